@@ -4,10 +4,16 @@ import (
 	"github.com/joho/godotenv"
 	"log"
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
-	DatabaseURL string
+	DatabaseURL      string
+	ReminderInterval time.Duration
+	TrashRetention   time.Duration
+	AttachmentsDir   string
+	AttachmentMax    int64
 }
 
 func LoadConfig() *Config {
@@ -19,7 +25,43 @@ func LoadConfig() *Config {
 		log.Fatal("DATABASE_URL is not set")
 	}
 
+	reminderInterval := time.Minute
+	if s := os.Getenv("REMINDER_INTERVAL"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			log.Fatalf("invalid REMINDER_INTERVAL %q", s)
+		}
+		reminderInterval = d
+	}
+
+	retentionDays := 30
+	if s := os.Getenv("TRASH_RETENTION_DAYS"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid TRASH_RETENTION_DAYS %q", s)
+		}
+		retentionDays = n
+	}
+
+	attachmentsDir := os.Getenv("ATTACHMENTS_DIR")
+	if attachmentsDir == "" {
+		attachmentsDir = "data/attachments"
+	}
+
+	attachmentMax := int64(25 << 20)
+	if s := os.Getenv("ATTACHMENT_MAX_BYTES"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid ATTACHMENT_MAX_BYTES %q", s)
+		}
+		attachmentMax = n
+	}
+
 	return &Config{
-		DatabaseURL: dsn,
+		DatabaseURL:      dsn,
+		ReminderInterval: reminderInterval,
+		TrashRetention:   time.Duration(retentionDays) * 24 * time.Hour,
+		AttachmentsDir:   attachmentsDir,
+		AttachmentMax:    attachmentMax,
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/db"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/events"
 	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/grpcs"
 	handlers2 "github.com/HDBOOMONE12/TaskManager/internal/taskmanager/handlers"
 	userspb "github.com/HDBOOMONE12/TaskManager/internal/taskmanager/proto"
@@ -30,9 +31,43 @@ func main() {
 
 	userRepo := storage2.NewUserRepo(database)
 	taskRepo := storage2.NewTaskRepo(database)
+	labelRepo := storage2.NewLabelRepo(database)
+	commentRepo := storage2.NewCommentRepo(database)
+	projectRepo := storage2.NewProjectRepo(database)
+	reminderRepo := storage2.NewReminderRepo(database)
+	workflowRepo := storage2.NewWorkflowRepo(database)
+	calendarRepo := storage2.NewCalendarRepo(database)
+	attachmentRepo := storage2.NewAttachmentRepo(database)
+	timeEntryRepo := storage2.NewTimeEntryRepo(database)
+	watcherRepo := storage2.NewWatcherRepo(database)
+	templateRepo := storage2.NewTemplateRepo(database)
+	viewRepo := storage2.NewViewRepo(database)
+	webhookRepo := storage2.NewWebhookRepo(database)
+
+	blobStore, err := storage2.NewFSBlobStore(config.AttachmentsDir)
+	if err != nil {
+		log.Fatalf("failed to open attachments dir: %v", err)
+	}
+
+	publisher := events.Multi{events.LogPublisher{}, service2.NewWebhookPublisher(webhookRepo)}
 
 	userSvc := service2.NewUserService(userRepo)
 	taskSvc := service2.NewTaskService(taskRepo)
+	taskSvc.SetPublisher(publisher, watcherRepo)
+	labelSvc := service2.NewLabelService(labelRepo)
+	commentSvc := service2.NewCommentService(commentRepo, taskRepo, projectRepo)
+	projectSvc := service2.NewProjectService(projectRepo, taskSvc)
+	reminderSvc := service2.NewReminderService(reminderRepo, publisher, service2.DefaultReminderThresholds)
+	attachmentSvc := service2.NewAttachmentService(attachmentRepo, blobStore, taskRepo, config.AttachmentMax)
+	purgeSvc := service2.NewPurgeService(taskRepo, userRepo, attachmentSvc, config.TrashRetention)
+	workflowSvc := service2.NewWorkflowService(workflowRepo)
+	calendarSvc := service2.NewCalendarService(calendarRepo, taskSvc)
+	timeSvc := service2.NewTimeService(timeEntryRepo, taskRepo)
+	watcherSvc := service2.NewWatcherService(watcherRepo, taskRepo, projectRepo)
+	templateSvc := service2.NewTemplateService(templateRepo, taskSvc)
+	viewSvc := service2.NewViewService(viewRepo, taskSvc)
+	webhookSvc := service2.NewWebhookService(webhookRepo)
+	webhookDispatcher := service2.NewWebhookDispatcher(webhookRepo, nil)
 
 	gServer := &grpcs.GrpcServer{
 		UserService: userSvc,
@@ -55,6 +90,17 @@ func main() {
 
 	handlers2.SetUserService(userSvc)
 	handlers2.SetTaskService(taskSvc)
+	handlers2.SetLabelService(labelSvc)
+	handlers2.SetCommentService(commentSvc)
+	handlers2.SetProjectService(projectSvc)
+	handlers2.SetWorkflowService(workflowSvc)
+	handlers2.SetCalendarService(calendarSvc)
+	handlers2.SetAttachmentService(attachmentSvc)
+	handlers2.SetTimeService(timeSvc)
+	handlers2.SetWatcherService(watcherSvc)
+	handlers2.SetTemplateService(templateSvc)
+	handlers2.SetViewService(viewSvc)
+	handlers2.SetWebhookService(webhookSvc)
 
 	mux := buildMux()
 	srv := &http.Server{
@@ -73,6 +119,23 @@ func main() {
 		}
 	}()
 
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	remindersDone := make(chan struct{})
+	go func() {
+		defer close(remindersDone)
+		reminderSvc.Run(workerCtx, config.ReminderInterval)
+	}()
+	purgeDone := make(chan struct{})
+	go func() {
+		defer close(purgeDone)
+		purgeSvc.Run(workerCtx, time.Hour)
+	}()
+	webhooksDone := make(chan struct{})
+	go func() {
+		defer close(webhooksDone)
+		webhookDispatcher.Run(workerCtx, 5*time.Second)
+	}()
+
 	log.Println("Server started")
 
 	ch := make(chan os.Signal, 1)
@@ -99,11 +162,34 @@ func main() {
 	default:
 		log.Printf("shutdown error: %v", err)
 	}
+
+	stopWorkers()
+	select {
+	case <-remindersDone:
+		log.Printf("reminder worker stopped")
+	case <-ctx.Done():
+		log.Printf("reminder worker did not stop in time")
+	}
+	select {
+	case <-purgeDone:
+		log.Printf("trash purge worker stopped")
+	case <-ctx.Done():
+		log.Printf("trash purge worker did not stop in time")
+	}
+	select {
+	case <-webhooksDone:
+		log.Printf("webhook worker stopped")
+	case <-ctx.Done():
+		log.Printf("webhook worker did not stop in time")
+	}
 }
 
 func buildMux() *http.ServeMux {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/users", handlers2.UsersHandler)
 	mux.HandleFunc("/users/", handlers2.UsersSubtreeHandler)
+	mux.HandleFunc("/tasks/search", handlers2.TasksSearchHandler)
+	mux.HandleFunc("/projects", handlers2.ProjectsHandler)
+	mux.HandleFunc("/projects/", handlers2.ProjectsSubtreeHandler)
 	return mux
 }
@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS tasks_user_id_created_at_idx ON tasks (user_id, created_at, id);
+
+CREATE INDEX IF NOT EXISTS tasks_user_id_due_idx
+    ON tasks (user_id, COALESCE(due_date, 'infinity'::timestamptz), id);
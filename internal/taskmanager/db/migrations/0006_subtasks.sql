@@ -0,0 +1,13 @@
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS parent_id bigint REFERENCES tasks (id) ON DELETE CASCADE;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'tasks_parent_not_self') THEN
+        ALTER TABLE tasks
+            ADD CONSTRAINT tasks_parent_not_self CHECK (parent_id IS NULL OR parent_id <> id);
+    END IF;
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS tasks_parent_id_idx ON tasks (parent_id) WHERE parent_id IS NOT NULL;
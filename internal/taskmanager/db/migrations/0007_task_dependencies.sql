@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS task_dependencies
+(
+    task_id       bigint      not null references tasks (id) on delete cascade,
+    blocked_by_id bigint      not null references tasks (id) on delete cascade,
+    created_at    timestamptz not null default now(),
+
+    primary key (task_id, blocked_by_id),
+    check (task_id <> blocked_by_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_dependencies_blocked_by_idx ON task_dependencies (blocked_by_id);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS labels
+(
+    id         bigint generated always as identity primary key,
+    user_id    bigint      not null references users (id) on delete cascade,
+    name       varchar(50) not null,
+    color      varchar(7)  not null default '#808080',
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+
+    unique (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS task_labels
+(
+    task_id  bigint not null references tasks (id) on delete cascade,
+    label_id bigint not null references labels (id) on delete cascade,
+
+    primary key (task_id, label_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_labels_label_id_idx ON task_labels (label_id);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS comments
+(
+    id         bigint generated always as identity primary key,
+    task_id    bigint      not null references tasks (id) on delete cascade,
+    user_id    bigint      not null references users (id) on delete cascade,
+    parent_id  bigint references comments (id) on delete cascade,
+    body       text        not null,
+    edited     boolean     not null default false,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+
+    check (length(body) between 1 and 4000)
+);
+
+CREATE INDEX IF NOT EXISTS comments_task_id_created_idx ON comments (task_id, created_at, id) WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS comments_parent_id_idx ON comments (parent_id) WHERE parent_id IS NOT NULL;
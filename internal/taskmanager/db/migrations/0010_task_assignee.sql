@@ -0,0 +1,5 @@
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS assignee_id bigint REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS tasks_assignee_id_created_at_idx
+    ON tasks (assignee_id, created_at, id) WHERE assignee_id IS NOT NULL;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS projects
+(
+    id          bigint generated always as identity primary key,
+    owner_id    bigint       not null references users (id) on delete cascade,
+    name        varchar(100) not null,
+    description text         not null default '',
+    created_at  timestamptz  not null default now(),
+    updated_at  timestamptz  not null default now()
+);
+
+CREATE TABLE IF NOT EXISTS project_members
+(
+    project_id bigint      not null references projects (id) on delete cascade,
+    user_id    bigint      not null references users (id) on delete cascade,
+    role       varchar(10) not null default 'member' check (role in ('owner', 'member')),
+    added_at   timestamptz not null default now(),
+
+    primary key (project_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS project_members_user_id_idx ON project_members (user_id);
+
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS project_id bigint REFERENCES projects (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS tasks_project_id_created_at_idx
+    ON tasks (project_id, created_at, id) WHERE project_id IS NOT NULL;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS task_series
+(
+    id         bigint generated always as identity primary key,
+    user_id    bigint      not null references users (id) on delete cascade,
+    rrule      text        not null,
+    dtstart    timestamptz not null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS series_id     bigint REFERENCES task_series (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS occurrence_at timestamptz;
+
+CREATE UNIQUE INDEX IF NOT EXISTS tasks_series_occurrence_uidx
+    ON tasks (series_id, occurrence_at) WHERE series_id IS NOT NULL;
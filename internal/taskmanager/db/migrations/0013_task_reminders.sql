@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS task_reminders
+(
+    task_id   bigint      not null references tasks (id) on delete cascade,
+    threshold varchar(16) not null,
+    due_date  timestamptz not null,
+    sent_at   timestamptz not null default now(),
+
+    primary key (task_id, threshold, due_date)
+);
+
+CREATE INDEX IF NOT EXISTS tasks_open_due_date_idx
+    ON tasks (due_date) WHERE due_date IS NOT NULL AND status <> 'done';
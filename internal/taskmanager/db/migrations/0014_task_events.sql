@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS task_events
+(
+    id         bigint generated always as identity primary key,
+    task_id    bigint      not null references tasks (id) on delete cascade,
+    actor_id   bigint      references users (id) on delete set null,
+    field      varchar(32) not null,
+    old_value  text,
+    new_value  text,
+    created_at timestamptz not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS task_events_task_id_idx ON task_events (task_id, id DESC);
@@ -0,0 +1,22 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS tasks_user_id_deleted_at_idx
+    ON tasks (user_id, deleted_at DESC, id) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx
+    ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- A user in the trash must not keep their username and email taken.
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_username_key,
+    DROP CONSTRAINT IF EXISTS users_email_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_live_key
+    ON users (username) WHERE deleted_at IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_live_key
+    ON users (email) WHERE deleted_at IS NULL;
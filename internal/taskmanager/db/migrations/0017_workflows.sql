@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS workflows
+(
+    id            bigint generated always as identity primary key,
+    user_id       bigint      not null references users (id) on delete cascade,
+    name          varchar(50) not null,
+    initial_state varchar(32) not null,
+    created_at    timestamptz not null default now(),
+    updated_at    timestamptz not null default now(),
+
+    unique (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS workflow_states
+(
+    workflow_id bigint      not null references workflows (id) on delete cascade,
+    name        varchar(32) not null,
+    position    int         not null,
+
+    primary key (workflow_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS workflow_transitions
+(
+    workflow_id          bigint      not null,
+    from_state           varchar(32) not null,
+    to_state             varchar(32) not null,
+    requires_description boolean     not null default false,
+    requires_assignee    boolean     not null default false,
+
+    primary key (workflow_id, from_state, to_state),
+    foreign key (workflow_id, from_state) references workflow_states (workflow_id, name) on delete cascade,
+    foreign key (workflow_id, to_state) references workflow_states (workflow_id, name) on delete cascade
+);
+
+-- Statuses are validated against the task's workflow by the service now;
+-- tasks without a workflow keep the built-in todo/doing/done one.
+ALTER TABLE tasks
+    DROP CONSTRAINT IF EXISTS tasks_status_check;
+
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS workflow_id bigint REFERENCES workflows (id);
+
+CREATE INDEX IF NOT EXISTS tasks_workflow_id_status_idx
+    ON tasks (workflow_id, status) WHERE workflow_id IS NOT NULL;
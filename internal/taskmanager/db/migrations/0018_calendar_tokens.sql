@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS calendar_tokens
+(
+    user_id    bigint primary key references users (id) on delete cascade,
+    token_hash char(64)    not null unique,
+    created_at timestamptz not null default now()
+);
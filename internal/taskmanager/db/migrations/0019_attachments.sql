@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS attachments
+(
+    id           bigint generated always as identity primary key,
+    task_id      bigint       not null references tasks (id) on delete cascade,
+    user_id      bigint       not null references users (id) on delete cascade,
+    name         varchar(255) not null,
+    size         bigint       not null,
+    content_type varchar(255) not null,
+    checksum     char(64)     not null,
+    blob_key     text         not null unique,
+    created_at   timestamptz  not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS attachments_task_id_idx ON attachments (task_id);
+
+-- Blobs live outside the database, so a cascade from a purged task cannot
+-- remove them. Every deleted attachment leaves its key here until the purge
+-- worker has deleted the blob.
+CREATE TABLE IF NOT EXISTS blob_garbage
+(
+    blob_key   text primary key,
+    created_at timestamptz not null default now()
+);
+
+CREATE OR REPLACE FUNCTION attachments_collect_blob() RETURNS trigger AS
+$$
+BEGIN
+    INSERT INTO blob_garbage (blob_key) VALUES (OLD.blob_key) ON CONFLICT DO NOTHING;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS attachments_collect_blob ON attachments;
+CREATE TRIGGER attachments_collect_blob
+    AFTER DELETE
+    ON attachments
+    FOR EACH ROW
+EXECUTE FUNCTION attachments_collect_blob();
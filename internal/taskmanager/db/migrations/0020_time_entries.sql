@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS time_entries
+(
+    id         bigint generated always as identity primary key,
+    task_id    bigint        not null references tasks (id) on delete cascade,
+    user_id    bigint        not null references users (id) on delete cascade,
+    started_at timestamptz   not null,
+    ended_at   timestamptz,
+    note       varchar(1000) not null default '',
+    created_at timestamptz   not null default now(),
+    updated_at timestamptz   not null default now(),
+    CHECK (ended_at IS NULL OR ended_at >= started_at)
+);
+
+CREATE INDEX IF NOT EXISTS time_entries_task_id_idx ON time_entries (task_id);
+CREATE INDEX IF NOT EXISTS time_entries_user_started_idx ON time_entries (user_id, started_at);
+
+-- An entry without ended_at is a running timer; a user has at most one.
+CREATE UNIQUE INDEX IF NOT EXISTS time_entries_running_uidx ON time_entries (user_id) WHERE ended_at IS NULL;
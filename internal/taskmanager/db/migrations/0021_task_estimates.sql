@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS estimate_minutes integer CHECK (estimate_minutes > 0),
+    ADD COLUMN IF NOT EXISTS story_points     integer CHECK (story_points >= 0);
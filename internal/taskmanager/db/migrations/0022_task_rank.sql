@@ -0,0 +1,19 @@
+-- rank orders the tasks of a user within a status column; see package rank.
+-- Keys compare bytewise, hence the "C" collation.
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS rank text COLLATE "C";
+
+-- Existing tasks keep their creation order. Zero-padded decimal numbers
+-- followed by 'i' are valid base-36 keys.
+UPDATE tasks t
+SET rank = r.rank
+FROM (SELECT id,
+             lpad(row_number() OVER (PARTITION BY user_id, status ORDER BY created_at, id)::text, 10, '0') || 'i' AS rank
+      FROM tasks) r
+WHERE r.id = t.id
+  AND t.rank IS NULL;
+
+ALTER TABLE tasks
+    ALTER COLUMN rank SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS tasks_user_status_rank_idx ON tasks (user_id, status, rank) WHERE deleted_at IS NULL;
@@ -0,0 +1,13 @@
+-- Explicit watchers of a task. The owner and the assignee watch their tasks
+-- without a row here; a row with muted = true silences any watcher,
+-- implicit ones included.
+CREATE TABLE IF NOT EXISTS watchers
+(
+    task_id    bigint      not null references tasks (id) on delete cascade,
+    user_id    bigint      not null references users (id) on delete cascade,
+    muted      boolean     not null default false,
+    created_at timestamptz not null default now(),
+    primary key (task_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS watchers_user_id_idx ON watchers (user_id);
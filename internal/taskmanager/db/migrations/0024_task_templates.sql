@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS task_templates
+(
+    id          bigint generated always as identity primary key,
+    user_id     bigint       not null references users (id) on delete cascade,
+    name        varchar(100) not null,
+    title       text         not null,
+    description text         not null default '',
+    priority    integer      not null default 0,
+    due_offset  varchar(32)  not null default '',
+    created_at  timestamptz  not null default now(),
+    updated_at  timestamptz  not null default now(),
+    UNIQUE (user_id, name)
+);
+
+-- Subtasks created together with a task from the template, in position
+-- order.
+CREATE TABLE IF NOT EXISTS task_template_subtasks
+(
+    template_id bigint      not null references task_templates (id) on delete cascade,
+    position    integer     not null,
+    title       text        not null,
+    description text        not null default '',
+    priority    integer     not null default 0,
+    due_offset  varchar(32) not null default '',
+    primary key (template_id, position)
+);
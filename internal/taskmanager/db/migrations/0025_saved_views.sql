@@ -0,0 +1,32 @@
+-- Named task filters. filter holds the JSON filter definition in the
+-- format of filter_version, so old rows keep working after the format
+-- changes.
+CREATE TABLE IF NOT EXISTS saved_views
+(
+    id             bigint generated always as identity primary key,
+    user_id        bigint       not null references users (id) on delete cascade,
+    name           varchar(100) not null,
+    filter         jsonb        not null default '{}',
+    filter_version integer      not null default 1,
+    created_at     timestamptz  not null default now(),
+    updated_at     timestamptz  not null default now(),
+    UNIQUE (user_id, name)
+);
+
+-- Users a view is shared with. They can use the view but not change it.
+CREATE TABLE IF NOT EXISTS view_shares
+(
+    view_id    bigint      not null references saved_views (id) on delete cascade,
+    user_id    bigint      not null references users (id) on delete cascade,
+    created_at timestamptz not null default now(),
+    primary key (view_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS view_shares_user_id_idx ON view_shares (user_id);
+
+-- The view applied when a user lists tasks without any filter.
+CREATE TABLE IF NOT EXISTS default_views
+(
+    user_id bigint primary key references users (id) on delete cascade,
+    view_id bigint not null references saved_views (id) on delete cascade
+);
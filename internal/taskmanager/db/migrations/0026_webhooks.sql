@@ -0,0 +1,53 @@
+-- Outgoing webhooks: HTTPS callbacks for the task events of their owner.
+CREATE TABLE IF NOT EXISTS webhooks
+(
+    id         bigint generated always as identity primary key,
+    user_id    bigint      not null references users (id) on delete cascade,
+    url        text        not null,
+    secret     text        not null,
+    events     text[]      not null,
+    active     boolean     not null default true,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_user_id_idx ON webhooks (user_id);
+
+-- One event to send to one webhook. A pending delivery is sent again at
+-- next_attempt_at until it succeeds or runs out of attempts; the sender
+-- pushes next_attempt_at ahead while it works on a delivery.
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id              bigint generated always as identity primary key,
+    webhook_id      bigint      not null references webhooks (id) on delete cascade,
+    event_id        text        not null,
+    event_type      varchar(64) not null,
+    payload         text        not null,
+    status          varchar(16) not null default 'pending'
+        check (status in ('pending', 'succeeded', 'failed')),
+    attempts        integer     not null default 0,
+    next_attempt_at timestamptz default now(),
+    response_code   integer,
+    last_error      text        not null default '',
+    redelivery_of   bigint references webhook_deliveries (id) on delete set null,
+    created_at      timestamptz not null default now(),
+    updated_at      timestamptz not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+    ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx
+    ON webhook_deliveries (webhook_id, id);
+
+-- Every request made for a delivery, with the receiver's response code or
+-- the error that prevented one.
+CREATE TABLE IF NOT EXISTS webhook_attempts
+(
+    delivery_id   bigint      not null references webhook_deliveries (id) on delete cascade,
+    attempt       integer     not null,
+    response_code integer,
+    error         text        not null default '',
+    duration_ms   integer     not null default 0,
+    attempted_at  timestamptz not null default now(),
+    primary key (delivery_id, attempt)
+);
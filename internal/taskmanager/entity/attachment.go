@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// Attachment is the metadata of a file attached to a task; the content is
+// kept in a blob store under BlobKey.
+type Attachment struct {
+	ID          int64
+	TaskID      int64
+	UserID      int64
+	Name        string
+	Size        int64
+	ContentType string
+	Checksum    string
+	BlobKey     string
+	CreatedAt   time.Time
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+type Comment struct {
+	ID        int64
+	TaskID    int64
+	UserID    int64
+	ParentID  *int64
+	Body      string
+	Edited    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Replies   []Comment
+}
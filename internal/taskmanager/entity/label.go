@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+type Label struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	Color     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+type Project struct {
+	ID          int64
+	OwnerID     int64
+	Name        string
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type ProjectMember struct {
+	ProjectID int64
+	UserID    int64
+	Role      string
+	AddedAt   time.Time
+}
@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// TaskReminder is one reminder about a task's due date that has been
+// claimed for sending.
+type TaskReminder struct {
+	TaskID     int64
+	UserID     int64
+	AssigneeID *int64
+	Title      string
+	DueAt      time.Time
+	Threshold  string
+	SentAt     time.Time
+	// Watchers are the users to notify, see Watcher.
+	Watchers []int64
+}
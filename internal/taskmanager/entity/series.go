@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// TaskSeries holds the recurrence rule shared by all instances of a
+// recurring task. DTStart anchors the rule.
+type TaskSeries struct {
+	ID        int64
+	UserID    int64
+	RRule     string
+	DTStart   time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
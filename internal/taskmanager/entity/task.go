@@ -5,11 +5,90 @@ import "time"
 type Task struct {
 	ID          int64
 	UserID      int64
+	AssigneeID  *int64
+	ProjectID   *int64
+	WorkflowID  *int64
+	ParentID    *int64
 	Title       string
 	Description string
 	Status      string
 	Priority    int64
 	DueAt       *time.Time
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	// EstimateMinutes and StoryPoints are nil when the task is not
+	// estimated.
+	EstimateMinutes *int64
+	StoryPoints     *int64
+	SubtasksTotal   int64
+	SubtasksDone    int64
+	BlockedBy       []int64
+	Blocking        []int64
+	OpenBlockers    int64
+	Labels          []Label
+	SeriesID        *int64
+	OccurrenceAt    *time.Time
+	RRule           string
+	TimeSpent       int64 // seconds, running timers included
+	// Rank orders the task within its owner's status column.
+	Rank      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+	Version   int64
+}
+
+// TaskPatch lists the fields of a partial update. Nullable columns carry a
+// separate *Set flag so that "set to NULL" differs from "leave unchanged".
+type TaskPatch struct {
+	Title       *string
+	Description *string
+	Status      *string
+	Priority    *int
+	DueAtSet    bool
+	DueAt       *time.Time
+	ParentSet   bool
+	ParentID    *int64
+	ProjectSet  bool
+	ProjectID   *int64
+	WorkflowSet bool
+	WorkflowID  *int64
+	EstimateSet bool
+	Estimate    *int64
+	PointsSet   bool
+	Points      *int64
+
+	AddLabels    []int64
+	RemoveLabels []int64
+
+	// IfVersion, when set, makes the update apply only to that version.
+	IfVersion *int64
+}
+
+// OnlyStatus reports whether the patch changes the status and nothing else.
+func (p TaskPatch) OnlyStatus() bool {
+	return p.Status != nil && p.Title == nil && p.Description == nil && p.Priority == nil &&
+		!p.DueAtSet && !p.ParentSet && !p.ProjectSet && !p.WorkflowSet && !p.EstimateSet && !p.PointsSet &&
+		len(p.AddLabels) == 0 && len(p.RemoveLabels) == 0
+}
+
+func (p TaskPatch) IsEmpty() bool {
+	return p.Title == nil && p.Description == nil && p.Status == nil && p.Priority == nil &&
+		!p.DueAtSet && !p.ParentSet && !p.ProjectSet && !p.WorkflowSet && !p.EstimateSet && !p.PointsSet &&
+		len(p.AddLabels) == 0 && len(p.RemoveLabels) == 0
+}
+
+// TaskEffort is the estimate of a done task next to the time tracked on
+// it.
+type TaskEffort struct {
+	TaskID          int64
+	EstimateMinutes *int64
+	StoryPoints     *int64
+	SpentSeconds    int64
+	CompletedAt     time.Time
+}
+
+type TaskSearchHit struct {
+	Task               Task
+	Rank               float64
+	TitleSnippet       string
+	DescriptionSnippet string
 }
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// TaskEvent is one field change in a task's history. Old and new values
+// are stored as text; nil stands for SQL NULL.
+type TaskEvent struct {
+	ID        int64
+	TaskID    int64
+	ActorID   *int64
+	Field     string
+	OldValue  *string
+	NewValue  *string
+	CreatedAt time.Time
+}
@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// TaskTemplate describes a task, and optionally its subtasks, to be
+// created again and again. Title and Description may hold {{variables}};
+// DueOffset, like "+3d", places the due date relative to the creation
+// time. A zero Priority falls back to the task default.
+type TaskTemplate struct {
+	ID          int64
+	UserID      int64
+	Name        string
+	Title       string
+	Description string
+	Priority    int64
+	DueOffset   string
+	Subtasks    []TemplateSubtask
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TemplateSubtask is a subtask of a TaskTemplate. A zero Priority takes
+// the template's.
+type TemplateSubtask struct {
+	Title       string
+	Description string
+	Priority    int64
+	DueOffset   string
+}
@@ -0,0 +1,36 @@
+package entity
+
+import "time"
+
+// TimeEntry is time a user spent on a task. An entry without EndedAt is a
+// running timer.
+type TimeEntry struct {
+	ID        int64
+	TaskID    int64
+	UserID    int64
+	StartedAt time.Time
+	EndedAt   *time.Time
+	Note      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Duration is the length of the entry; a running timer counts up to now.
+func (e TimeEntry) Duration(now time.Time) time.Duration {
+	end := now
+	if e.EndedAt != nil {
+		end = *e.EndedAt
+	}
+	if end.Before(e.StartedAt) {
+		return 0
+	}
+	return end.Sub(e.StartedAt)
+}
+
+// TimeReportRow is the time a user spent on one task during one day.
+type TimeReportRow struct {
+	Day     time.Time
+	TaskID  int64
+	Title   string
+	Seconds int64
+}
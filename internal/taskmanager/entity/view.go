@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// SavedView is a named task filter. FilterJSON holds its JSON definition in
+// the format of FilterVersion. SharedWith lists the users the owner shared
+// the view with.
+type SavedView struct {
+	ID            int64
+	UserID        int64
+	Name          string
+	FilterJSON    []byte
+	FilterVersion int
+	SharedWith    []int64
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
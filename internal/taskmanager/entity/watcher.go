@@ -0,0 +1,10 @@
+package entity
+
+// Watcher is a user who gets notified about changes to a task. The owner
+// and the assignee are Implicit watchers; anyone may mute a task.
+type Watcher struct {
+	TaskID   int64
+	UserID   int64
+	Implicit bool
+	Muted    bool
+}
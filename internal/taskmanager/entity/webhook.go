@@ -0,0 +1,52 @@
+package entity
+
+import "time"
+
+// Webhook is an HTTPS callback a user registered for some of the events
+// of their tasks. Secret signs every request sent to URL.
+type Webhook struct {
+	ID        int64
+	UserID    int64
+	URL       string
+	Secret    string
+	Events    []string
+	Active    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent, or to be sent, to one webhook.
+// NextAttemptAt is set while the delivery is pending. RedeliveryOf points
+// to the delivery it replays.
+type WebhookDelivery struct {
+	ID            int64
+	WebhookID     int64
+	EventID       string
+	EventType     string
+	Payload       []byte
+	Status        string
+	Attempts      int
+	NextAttemptAt *time.Time
+	ResponseCode  *int
+	LastError     string
+	RedeliveryOf  *int64
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// WebhookAttempt logs one request made for a delivery. ResponseCode is
+// nil when no response came back, and Error says why.
+type WebhookAttempt struct {
+	DeliveryID   int64
+	Attempt      int
+	ResponseCode *int
+	Error        string
+	Duration     time.Duration
+	AttemptedAt  time.Time
+}
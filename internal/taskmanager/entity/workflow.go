@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// Workflow lists the states a task may be in and the moves between them.
+// A transition may carry guards that the task must satisfy to take it.
+type Workflow struct {
+	ID           int64
+	UserID       int64
+	Name         string
+	InitialState string
+	States       []string
+	Transitions  []WorkflowTransition
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type WorkflowTransition struct {
+	From                string
+	To                  string
+	RequiresDescription bool
+	RequiresAssignee    bool
+}
@@ -0,0 +1,62 @@
+// Package events describes what the task service tells the outside world
+// about tasks and how it hands those messages over.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	TypeTaskCreated       = "task.created"
+	TypeTaskUpdated       = "task.updated"
+	TypeTaskStatusChanged = "task.status_changed"
+	TypeTaskDeleted       = "task.deleted"
+	TypeTaskReminder      = "task.reminder"
+)
+
+// Event is about the task TaskID owned by UserID. Watchers lists every
+// user the event should be fanned out to, owner included, except those who
+// muted the task and the user who made the change.
+type Event struct {
+	Type       string         `json:"type"`
+	TaskID     int64          `json:"task_id"`
+	UserID     int64          `json:"user_id"`
+	Watchers   []int64        `json:"watchers"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Data       map[string]any `json:"data,omitempty"`
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// LogPublisher writes every event to the standard logger. It is the
+// default until a real delivery channel is configured.
+type LogPublisher struct{}
+
+func (LogPublisher) Publish(_ context.Context, e Event) error {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	log.Printf("event: %s", raw)
+	return nil
+}
+
+// Multi hands every event to each of its publishers and reports all their
+// failures together.
+type Multi []Publisher
+
+func (m Multi) Publish(ctx context.Context, e Event) error {
+	var errs []error
+	for _, p := range m {
+		if err := p.Publish(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
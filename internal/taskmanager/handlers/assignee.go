@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// UserTaskAssigneeHandler serves /users/{id}/tasks/{tid}/assignee. PUT
+// assigns or reassigns the task, DELETE unassigns it. Both are owner-only.
+func UserTaskAssigneeHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPut:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, tid, perr := parseUserTaskSubPath(r, "assignee")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		var req struct {
+			AssigneeID *int64 `json:"assignee_id"`
+		}
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+		if req.AssigneeID == nil {
+			errorJSON(w, http.StatusBadRequest, "assignee_id is required")
+			return
+		}
+
+		task, err := taskSvc.AssignTask(r.Context(), int64(uid), int64(tid), req.AssigneeID)
+		if err != nil {
+			respondTaskError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toTaskResponse(task))
+
+	case http.MethodDelete:
+		uid, tid, perr := parseUserTaskSubPath(r, "assignee")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		if _, err := taskSvc.AssignTask(r.Context(), int64(uid), int64(tid), nil); err != nil {
+			respondTaskError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "PUT, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func UserAssignedTasksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, perr := parseUserSubPath(r, "assigned-tasks")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+		errorJSON(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	q, err := parseTaskListQuery(r)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := taskSvc.ListAssignedTasks(r.Context(), int64(uid), q)
+	if err != nil {
+		respondTaskError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toTaskListResponse(page))
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+type AttachmentResponse struct {
+	ID          int64     `json:"id"`
+	TaskID      int64     `json:"task_id"`
+	UserID      int64     `json:"user_id"`
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	Checksum    string    `json:"checksum"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+var attachmentSvc *service.AttachmentService
+
+func SetAttachmentService(s *service.AttachmentService) { attachmentSvc = s }
+
+func toAttachmentResponse(a entity.Attachment) AttachmentResponse {
+	return AttachmentResponse{
+		ID:          a.ID,
+		TaskID:      a.TaskID,
+		UserID:      a.UserID,
+		Name:        a.Name,
+		Size:        a.Size,
+		ContentType: a.ContentType,
+		Checksum:    a.Checksum,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+func respondAttachmentError(w http.ResponseWriter, err error) {
+	var mbe *http.MaxBytesError
+	switch {
+	case errors.Is(err, service.ErrAttachmentTooLarge), errors.As(err, &mbe):
+		errorJSON(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("attachment exceeds %d bytes", attachmentSvc.MaxSize()))
+	case errors.Is(err, service.ErrBadAttachmentName):
+		errorJSON(w, http.StatusBadRequest, "invalid file name")
+	case errors.Is(err, service.ErrBadContentType):
+		errorJSON(w, http.StatusBadRequest, "invalid content type")
+	case errors.Is(err, service.ErrAttachmentNotFound):
+		errorJSON(w, http.StatusNotFound, "attachment not found")
+	default:
+		respondTaskError(w, err)
+	}
+}
+
+// multipartOverhead is what the request may carry on top of the file itself:
+// part headers and boundaries.
+const multipartOverhead = 64 << 10
+
+// uploadAttachment streams the "file" part of a multipart form to the blob
+// store without buffering it in memory or on disk.
+func uploadAttachment(w http.ResponseWriter, r *http.Request, uid, tid int) {
+	r.Body = http.MaxBytesReader(w, r.Body, attachmentSvc.MaxSize()+multipartOverhead)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be multipart/form-data")
+		return
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			errorJSON(w, http.StatusBadRequest, `missing "file" part`)
+			return
+		}
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				respondAttachmentError(w, err)
+				return
+			}
+			errorJSON(w, http.StatusBadRequest, "malformed multipart body")
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		a, err := attachmentSvc.Upload(r.Context(), int64(uid), int64(tid), part.FileName(), part.Header.Get("Content-Type"), part)
+		part.Close()
+		if err != nil {
+			respondAttachmentError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toAttachmentResponse(a))
+		return
+	}
+}
+
+func UserTaskAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	uid, tid, perr := parseUserTaskSubPath(r, "attachments")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := attachmentSvc.ListAttachments(r.Context(), int64(uid), int64(tid))
+		if err != nil {
+			respondAttachmentError(w, err)
+			return
+		}
+		resp := make([]AttachmentResponse, 0, len(list))
+		for _, a := range list {
+			resp = append(resp, toAttachmentResponse(a))
+		}
+		writeJSON(w, http.StatusOK, resp)
+
+	case http.MethodPost:
+		uploadAttachment(w, r, uid, tid)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// UserTaskAttachmentDetailHandler serves the content of an attachment.
+// http.ServeContent takes care of Range, If-Range and If-None-Match; the
+// checksum doubles as a strong ETag since blobs never change.
+func UserTaskAttachmentDetailHandler(w http.ResponseWriter, r *http.Request) {
+	uid, tid, aid, perr := parseUserTaskSubItemPath(r, "attachments")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		a, content, err := attachmentSvc.Open(r.Context(), int64(uid), int64(tid), int64(aid))
+		if err != nil {
+			respondAttachmentError(w, err)
+			return
+		}
+		defer content.Close()
+
+		// Anything a browser could run as a page in our origin (HTML, SVG,
+		// ...) is only ever served as an opaque download.
+		disposition, contentType := "attachment", "application/octet-stream"
+		if service.InlineSafe(a.ContentType) {
+			contentType = a.ContentType
+			if r.URL.Query().Get("inline") == "true" {
+				disposition = "inline"
+			}
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Name}))
+		w.Header().Set("ETag", `"`+a.Checksum+`"`)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeContent(w, r, "", a.CreatedAt, content)
+
+	case http.MethodDelete:
+		if err := attachmentSvc.DeleteAttachment(r.Context(), int64(uid), int64(tid), int64(aid)); err != nil {
+			respondAttachmentError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, HEAD, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
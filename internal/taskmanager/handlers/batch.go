@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type BatchRequest struct {
+	Mode       string           `json:"mode"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation carries the body of the matching single-task request in
+// Data: CreateTaskRequest for create, UpdateTaskRequest for update and
+// PatchTaskRequest for patch.
+type BatchOperation struct {
+	Op        string          `json:"op"`
+	ID        int64           `json:"id"`
+	IfVersion *int64          `json:"if_version"`
+	Children  string          `json:"children"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type BatchResultResponse struct {
+	Index  int           `json:"index"`
+	Op     string        `json:"op"`
+	Status int           `json:"status"`
+	Task   *TaskResponse `json:"task,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Committed bool                  `json:"committed"`
+	Results   []BatchResultResponse `json:"results"`
+}
+
+func parseUserTasksBatchPath(r *http.Request) (int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !(len(parts) == 4 || (len(parts) == 5 && parts[4] == "")) {
+		return 0, errBadPath
+	}
+	if parts[1] != "users" || parts[3] != "tasks:batch" {
+		return 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, errBadID
+	}
+	return uid, nil
+}
+
+// toBatchOp decodes one operation and checks that it is well formed;
+// business rules are left to the service.
+func toBatchOp(in BatchOperation) (service.BatchOp, error) {
+	op := service.BatchOp{
+		Op:        in.Op,
+		TaskID:    in.ID,
+		IfVersion: in.IfVersion,
+		Children:  service.ChildPolicy(in.Children),
+	}
+
+	decode := func(v any) error {
+		if len(in.Data) == 0 {
+			return errors.New("missing data")
+		}
+		dec := json.NewDecoder(bytes.NewReader(in.Data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(v); err != nil {
+			return fmt.Errorf("invalid data: %v", err)
+		}
+		return nil
+	}
+
+	switch in.Op {
+	case service.BatchCreate:
+		var req CreateTaskRequest
+		if err := decode(&req); err != nil {
+			return op, err
+		}
+		due, err := parseDueAt(req.DueAt)
+		if err != nil {
+			return op, err
+		}
+		op.Title, op.Description, op.Status = req.Title, req.Description, req.Status
+		op.Priority, op.DueAt, op.Opts = int64(req.Priority), due, req.options()
+
+	case service.BatchUpdate:
+		var req UpdateTaskRequest
+		if err := decode(&req); err != nil {
+			return op, err
+		}
+		due, err := parseDueAt(req.DueAt)
+		if err != nil {
+			return op, err
+		}
+		op.Title, op.Description, op.Status = req.Title, req.Description, req.Status
+		op.Priority, op.DueAt = int64(req.Priority), due
+
+	case service.BatchPatch:
+		var req PatchTaskRequest
+		if err := decode(&req); err != nil {
+			return op, err
+		}
+		p, err := req.toPatch()
+		if err != nil {
+			return op, err
+		}
+		op.Patch = p
+
+	case service.BatchDelete:
+
+	default:
+		return op, errors.New("invalid op, use create, update, patch or delete")
+	}
+
+	if in.Op != service.BatchCreate && in.ID <= 0 {
+		return op, errors.New("invalid task id")
+	}
+	return op, nil
+}
+
+func UserTasksBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ct := r.Header.Get("Content-Type")
+	if !strings.HasPrefix(ct, "application/json") {
+		errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+
+	uid, perr := parseUserTasksBatchPath(r)
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+		errorJSON(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	var req BatchRequest
+	if err := decodeJSON(w, r, &req, 10<<20); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+
+	mode := service.BatchMode(req.Mode)
+	if mode != "" && mode != service.BatchAtomic && mode != service.BatchBestEffort {
+		errorJSON(w, http.StatusBadRequest, "invalid mode, use atomic or best_effort")
+		return
+	}
+	if len(req.Operations) == 0 {
+		errorJSON(w, http.StatusBadRequest, "no operations")
+		return
+	}
+	if len(req.Operations) > service.MaxBatchOps {
+		errorJSON(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d operations per batch", service.MaxBatchOps))
+		return
+	}
+
+	// Malformed operations fail the whole request before anything runs, so
+	// that an atomic batch never commits half of a broken payload.
+	ops := make([]service.BatchOp, 0, len(req.Operations))
+	for i, in := range req.Operations {
+		op, err := toBatchOp(in)
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, fmt.Sprintf("operation %d: %v", i, err))
+			return
+		}
+		ops = append(ops, op)
+	}
+
+	results, committed, err := taskSvc.RunBatch(r.Context(), int64(uid), ops, mode)
+	if err != nil {
+		respondTaskError(w, err)
+		return
+	}
+
+	resp := BatchResponse{Committed: committed, Results: make([]BatchResultResponse, 0, len(results))}
+	for i, res := range results {
+		out := BatchResultResponse{Index: i, Op: ops[i].Op}
+		switch {
+		case errors.Is(res.Err, service.ErrRolledBack), errors.Is(res.Err, service.ErrNotRun):
+			out.Status, out.Error = http.StatusFailedDependency, res.Err.Error()
+		case res.Err != nil:
+			out.Status, out.Error = taskErrorStatus(res.Err)
+		case ops[i].Op == service.BatchDelete:
+			out.Status = http.StatusNoContent
+		default:
+			out.Status = http.StatusOK
+			if ops[i].Op == service.BatchCreate {
+				out.Status = http.StatusCreated
+			}
+			task := toTaskResponse(res.Task)
+			out.Task = &task
+		}
+		resp.Results = append(resp.Results, out)
+	}
+
+	code := http.StatusOK
+	if !committed {
+		code = http.StatusConflict
+	}
+	writeJSON(w, code, resp)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/ical"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type CalendarTokenResponse struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+var calendarSvc *service.CalendarService
+
+func SetCalendarService(s *service.CalendarService) { calendarSvc = s }
+
+func parseUserCalendarPath(r *http.Request, name string) (int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !(len(parts) == 4 || (len(parts) == 5 && parts[4] == "")) {
+		return 0, errBadPath
+	}
+	if parts[1] != "users" || parts[3] != name {
+		return 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, errBadID
+	}
+	return uid, nil
+}
+
+// UserCalendarTokenHandler issues (POST) or revokes (DELETE) the secret
+// token that grants access to the calendar feed.
+func UserCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	uid, perr := parseUserCalendarPath(r, "calendar-token")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+			errorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+		token, err := calendarSvc.RotateToken(r.Context(), int64(uid))
+		if err != nil {
+			errorJSON(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		writeJSON(w, http.StatusCreated, CalendarTokenResponse{
+			Token: token,
+			URL:   fmt.Sprintf("/users/%d/calendar.ics?token=%s", uid, url.QueryEscape(token)),
+		})
+
+	case http.MethodDelete:
+		err := calendarSvc.RevokeToken(r.Context(), int64(uid))
+		switch {
+		case errors.Is(err, service.ErrNoCalendarToken):
+			errorJSON(w, http.StatusNotFound, "calendar feed is not enabled")
+			return
+		case err != nil:
+			errorJSON(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// UserCalendarHandler serves the feed to calendar apps, which can only
+// authenticate with the token in the URL. A wrong token looks the same as
+// a missing user.
+func UserCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, perr := parseUserCalendarPath(r, "calendar.ics")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	component := ical.Event
+	switch r.URL.Query().Get("component") {
+	case "", "vevent":
+	case "vtodo":
+		component = ical.Todo
+	default:
+		errorJSON(w, http.StatusBadRequest, "invalid component, use vevent or vtodo")
+		return
+	}
+
+	err := calendarSvc.CheckToken(r.Context(), int64(uid), r.URL.Query().Get("token"))
+	if errors.Is(err, service.ErrBadCalendarToken) {
+		errorJSON(w, http.StatusNotFound, "calendar not found")
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	user, err := userSvc.GetUserByID(r.Context(), int64(uid))
+	if err != nil {
+		errorJSON(w, http.StatusNotFound, "calendar not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	enc := ical.NewEncoder(w, "-//TaskManager//Tasks//EN", user.Username+" — tasks")
+	err = calendarSvc.Feed(r.Context(), int64(uid), func(it ical.Item) error {
+		return enc.Encode(component, it)
+	})
+	if err == nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		log.Printf("calendar feed of user %d: %v", uid, err)
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CommentRequest struct {
+	Body     string `json:"body"`
+	ParentID *int64 `json:"parent_id"`
+}
+
+type CommentResponse struct {
+	ID        int64             `json:"id"`
+	TaskID    int64             `json:"task_id"`
+	UserID    int64             `json:"user_id"`
+	ParentID  *int64            `json:"parent_id"`
+	Body      string            `json:"body"`
+	Edited    bool              `json:"edited"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Replies   []CommentResponse `json:"replies,omitempty"`
+}
+
+type CommentListResponse struct {
+	Items      []CommentResponse `json:"items"`
+	NextCursor *string           `json:"next_cursor"`
+}
+
+var commentSvc *service.CommentService
+
+func SetCommentService(s *service.CommentService) { commentSvc = s }
+
+func toCommentResponse(c entity.Comment) CommentResponse {
+	resp := CommentResponse{
+		ID:        c.ID,
+		TaskID:    c.TaskID,
+		UserID:    c.UserID,
+		ParentID:  c.ParentID,
+		Body:      c.Body,
+		Edited:    c.Edited,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+	for _, r := range c.Replies {
+		resp.Replies = append(resp.Replies, toCommentResponse(r))
+	}
+	return resp
+}
+
+func respondCommentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmptyComment):
+		errorJSON(w, http.StatusBadRequest, "empty comment")
+	case errors.Is(err, service.ErrCommentTooLong):
+		errorJSON(w, http.StatusBadRequest, "comment too long")
+	case errors.Is(err, service.ErrBadReply):
+		errorJSON(w, http.StatusBadRequest, "replies are allowed only to top-level comments of the same task")
+	case errors.Is(err, service.ErrCommentNotFound):
+		errorJSON(w, http.StatusNotFound, "comment not found")
+	case errors.Is(err, service.ErrNotAuthor):
+		errorJSON(w, http.StatusForbidden, "only the author can change a comment")
+	default:
+		respondTaskError(w, err)
+	}
+}
+
+func UserTaskCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, tid, perr := parseUserTaskSubPath(r, "comments")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		limit := 0
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				errorJSON(w, http.StatusBadRequest, "invalid limit")
+				return
+			}
+			limit = n
+		}
+
+		page, err := commentSvc.ListComments(r.Context(), int64(uid), int64(tid), r.URL.Query().Get("cursor"), limit)
+		if err != nil {
+			respondCommentError(w, err)
+			return
+		}
+
+		resp := CommentListResponse{Items: make([]CommentResponse, 0, len(page.Comments))}
+		for _, c := range page.Comments {
+			resp.Items = append(resp.Items, toCommentResponse(c))
+		}
+		if page.NextCursor != "" {
+			resp.NextCursor = &page.NextCursor
+		}
+		writeJSON(w, http.StatusOK, resp)
+
+	case http.MethodPost:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, tid, perr := parseUserTaskSubPath(r, "comments")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		var req CommentRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		c, err := commentSvc.AddComment(r.Context(), int64(uid), int64(tid), req.ParentID, req.Body)
+		if err != nil {
+			respondCommentError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toCommentResponse(c))
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func UserTaskCommentDetailHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPatch:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, tid, cid, perr := parseUserTaskSubItemPath(r, "comments")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		var req struct {
+			Body string `json:"body"`
+		}
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		c, err := commentSvc.EditComment(r.Context(), int64(uid), int64(tid), int64(cid), req.Body)
+		if err != nil {
+			respondCommentError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toCommentResponse(c))
+
+	case http.MethodDelete:
+		uid, tid, cid, perr := parseUserTaskSubItemPath(r, "comments")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		if err := commentSvc.DeleteComment(r.Context(), int64(uid), int64(tid), int64(cid)); err != nil {
+			respondCommentError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "PATCH, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+type AddDependencyRequest struct {
+	BlockedByID int64 `json:"blocked_by_id"`
+}
+
+type DependenciesResponse struct {
+	BlockedBy []TaskResponse `json:"blocked_by"`
+	Blocking  []TaskResponse `json:"blocking"`
+}
+
+func UserTaskDependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, tid, perr := parseUserTaskSubPath(r, "dependencies")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+		if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+			errorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		blockedBy, blocking, err := taskSvc.ListDependencies(r.Context(), int64(uid), int64(tid))
+		if err != nil {
+			respondTaskError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, DependenciesResponse{
+			BlockedBy: toTaskResponses(blockedBy),
+			Blocking:  toTaskResponses(blocking),
+		})
+
+	case http.MethodPost:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, tid, perr := parseUserTaskSubPath(r, "dependencies")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+		if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+			errorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		var req AddDependencyRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+		if req.BlockedByID <= 0 {
+			errorJSON(w, http.StatusBadRequest, "blocked_by_id is required")
+			return
+		}
+
+		if err := taskSvc.AddDependency(r.Context(), int64(uid), int64(tid), req.BlockedByID); err != nil {
+			respondTaskError(w, err)
+			return
+		}
+
+		task, err := taskSvc.GetTaskByID(r.Context(), int64(tid))
+		if err != nil {
+			respondTaskError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toTaskResponse(task))
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func UserTaskDependencyDetailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, tid, blockerID, perr := parseUserTaskSubItemPath(r, "dependencies")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+		errorJSON(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if err := taskSvc.RemoveDependency(r.Context(), int64(uid), int64(tid), int64(blockerID)); err != nil {
+		respondTaskError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,24 +2,86 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
 	"net/http"
 )
 
 func respondTaskError(w http.ResponseWriter, err error) bool {
+	code, msg := taskErrorStatus(err)
+	errorJSON(w, code, msg)
+	return true
+}
+
+// taskErrorStatus maps a task service error to an HTTP status and message.
+func taskErrorStatus(err error) (int, string) {
 	switch {
 	case errors.Is(err, service.ErrUserNotFound):
-		errorJSON(w, http.StatusNotFound, "user not found")
+		return http.StatusNotFound, "user not found"
 	case errors.Is(err, service.ErrEmptyTitle):
-		errorJSON(w, http.StatusBadRequest, "invalid title")
+		return http.StatusBadRequest, "invalid title"
 	case errors.Is(err, service.ErrBadStatus):
-		errorJSON(w, http.StatusBadRequest, "invalid task status")
+		return http.StatusBadRequest, "invalid task status"
 	case errors.Is(err, service.ErrBadPriority):
-		errorJSON(w, http.StatusBadRequest, "invalid task priority")
+		return http.StatusBadRequest, "invalid task priority"
+	case errors.Is(err, service.ErrBadEstimate):
+		return http.StatusBadRequest, fmt.Sprintf("invalid estimate_minutes, use %d..%d", service.MinEstimateMinutes, service.MaxEstimateMinutes)
+	case errors.Is(err, service.ErrBadPoints):
+		return http.StatusBadRequest, fmt.Sprintf("invalid story_points, use %d..%d", service.MinStoryPoints, service.MaxStoryPoints)
+	case errors.Is(err, service.ErrBadDueAt):
+		return http.StatusBadRequest, errBadDueAt.Error()
 	case errors.Is(err, service.ErrTaskNotFound):
-		errorJSON(w, http.StatusNotFound, "task not found")
-	default:
-		errorJSON(w, http.StatusInternalServerError, "internal server error")
+		return http.StatusNotFound, "task not found"
+	case errors.Is(err, service.ErrBadSort):
+		return http.StatusBadRequest, "invalid sort field"
+	case errors.Is(err, service.ErrBadCursor):
+		return http.StatusBadRequest, "invalid cursor"
+	case errors.Is(err, service.ErrBadFilter):
+		return http.StatusBadRequest, "invalid filter"
+	case errors.Is(err, service.ErrEmptyQuery):
+		return http.StatusBadRequest, "empty search query"
+	case errors.Is(err, service.ErrBadParent):
+		return http.StatusBadRequest, "invalid parent task"
+	case errors.Is(err, service.ErrOpenSubtasks):
+		return http.StatusConflict, "task has open subtasks"
+	case errors.Is(err, service.ErrHasSubtasks):
+		return http.StatusConflict, "task has subtasks, use ?children=cascade or ?children=orphan"
+	case errors.Is(err, service.ErrBadPolicy):
+		return http.StatusBadRequest, "invalid children policy, use block, cascade or orphan"
+	case errors.Is(err, service.ErrBadDependency):
+		return http.StatusBadRequest, "invalid dependency"
+	case errors.Is(err, service.ErrDependencyCycle):
+		return http.StatusConflict, "dependency would create a cycle"
+	case errors.Is(err, service.ErrDependencyExists):
+		return http.StatusConflict, "dependency already exists"
+	case errors.Is(err, service.ErrDependencyNotFound):
+		return http.StatusNotFound, "dependency not found"
+	case errors.Is(err, service.ErrBadTransition), errors.Is(err, service.ErrTransitionGuard):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, service.ErrBadWorkflow):
+		return http.StatusBadRequest, "unknown workflow"
+	case errors.Is(err, service.ErrBlocked):
+		return http.StatusConflict, "task is blocked by unfinished tasks"
+	case errors.Is(err, service.ErrBadLabel):
+		return http.StatusBadRequest, "unknown label"
+	case errors.Is(err, service.ErrVersionMismatch):
+		return http.StatusPreconditionFailed, "task was modified, reload it and retry"
+	case errors.Is(err, service.ErrNotOwner):
+		return http.StatusForbidden, "only the task owner can do this"
+	case errors.Is(err, service.ErrBadAssignee):
+		return http.StatusBadRequest, "unknown assignee"
+	case errors.Is(err, service.ErrBadRRule):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, service.ErrRecurrenceNeedsDue):
+		return http.StatusBadRequest, "recurring task needs due_at"
+	case errors.Is(err, service.ErrBadMove):
+		return http.StatusBadRequest, "after_id and before_id must be other tasks of the same status, in that order"
+	case errors.Is(err, service.ErrNotRecurring):
+		return http.StatusNotFound, "task is not recurring"
+	case errors.Is(err, service.ErrBadSeriesPatch):
+		return http.StatusBadRequest, "only title, description, priority, estimates and labels can be changed with scope=series"
+	case errors.Is(err, service.ErrBadProject):
+		return http.StatusBadRequest, "task owner is not a member of the project"
 	}
-	return true
+	return http.StatusInternalServerError, "internal server error"
 }
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"math"
+	"net/http"
+	"time"
+)
+
+type EffortEstimatesResponse struct {
+	Tasks           int     `json:"tasks"`
+	EstimateMinutes int64   `json:"estimate_minutes"`
+	ActualMinutes   int64   `json:"actual_minutes"`
+	Ratio           float64 `json:"ratio"`
+	Over            int     `json:"over"`
+	Under           int     `json:"under"`
+}
+
+type EffortPointsBucketResponse struct {
+	Points        int64   `json:"points"`
+	Tasks         int     `json:"tasks"`
+	ActualMinutes int64   `json:"actual_minutes"`
+	AvgMinutes    float64 `json:"avg_minutes"`
+}
+
+type EffortPointsResponse struct {
+	Tasks           int                          `json:"tasks"`
+	Points          int64                        `json:"points"`
+	ActualMinutes   int64                        `json:"actual_minutes"`
+	MinutesPerPoint float64                      `json:"minutes_per_point"`
+	ByPoints        []EffortPointsBucketResponse `json:"by_points"`
+}
+
+type EffortReportResponse struct {
+	From        *string                 `json:"from"`
+	To          *string                 `json:"to"`
+	TimeZone    string                  `json:"tz"`
+	Tasks       int                     `json:"tasks"`
+	Untracked   int                     `json:"untracked"`
+	Estimates   EffortEstimatesResponse `json:"estimates"`
+	StoryPoints EffortPointsResponse    `json:"story_points"`
+}
+
+func minutes(seconds int64) int64 {
+	return int64(math.Round(float64(seconds) / 60))
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+func toEffortReportResponse(rep service.EffortReport) EffortReportResponse {
+	resp := EffortReportResponse{
+		TimeZone:  rep.Location.String(),
+		Tasks:     rep.Tasks,
+		Untracked: rep.Untracked,
+		Estimates: EffortEstimatesResponse{
+			Tasks:           rep.Estimated,
+			EstimateMinutes: rep.EstimateMinutes,
+			ActualMinutes:   minutes(rep.SpentSeconds),
+			Ratio:           round2(rep.Ratio()),
+			Over:            rep.Over,
+			Under:           rep.Under,
+		},
+		StoryPoints: EffortPointsResponse{
+			Tasks:           rep.Pointed,
+			Points:          rep.Points,
+			ActualMinutes:   minutes(rep.PointedSeconds),
+			MinutesPerPoint: round2(rep.SecondsPerPoint() / 60),
+			ByPoints:        make([]EffortPointsBucketResponse, 0, len(rep.ByPoints)),
+		},
+	}
+	if rep.From != nil {
+		from := rep.From.Format(time.DateOnly)
+		resp.From = &from
+	}
+	if rep.To != nil {
+		to := rep.To.Format(time.DateOnly)
+		resp.To = &to
+	}
+	for _, b := range rep.ByPoints {
+		resp.StoryPoints.ByPoints = append(resp.StoryPoints.ByPoints, EffortPointsBucketResponse{
+			Points:        b.Points,
+			Tasks:         b.Tasks,
+			ActualMinutes: minutes(b.SpentSeconds),
+			AvgMinutes:    round2(float64(b.SpentSeconds) / 60 / float64(b.Tasks)),
+		})
+	}
+	return resp
+}
+
+// UserEffortReportHandler compares estimated and tracked effort of done
+// tasks: GET /users/{id}/effort-report?from=YYYY-MM-DD&to=YYYY-MM-DD&tz=.
+// Either bound may be left out; without both it covers every done task.
+func UserEffortReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, perr := parseUserSubPath(r, "effort-report")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+		errorJSON(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	q := r.URL.Query()
+	rep, err := taskSvc.EffortReport(r.Context(), int64(uid), q.Get("from"), q.Get("to"), q.Get("tz"))
+	if err != nil {
+		respondTimeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toEffortReportResponse(rep))
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag renders a row version as a strong entity tag.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// notModified reports whether If-None-Match already names the current
+// version. Weak tags compare equal to strong ones here, as RFC 9110 asks.
+func notModified(r *http.Request, version int64) bool {
+	h := strings.TrimSpace(r.Header.Get("If-None-Match"))
+	if h == "" {
+		return false
+	}
+	if h == "*" {
+		return true
+	}
+	cur := etag(version)
+	for _, tag := range strings.Split(h, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == cur {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch reads If-Match for a conditional write. No header or "*" means
+// no version requirement. Anything but a single strong tag can never match
+// one of ours, so it is answered with 412 right away and ok is false.
+func ifMatch(w http.ResponseWriter, r *http.Request) (version *int64, ok bool) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" || h == "*" {
+		return nil, true
+	}
+	if len(h) < 2 || h[0] != '"' || h[len(h)-1] != '"' {
+		errorJSON(w, http.StatusPreconditionFailed, "If-Match does not match the current version")
+		return nil, false
+	}
+	v, err := strconv.ParseInt(h[1:len(h)-1], 10, 64)
+	if err != nil || v <= 0 {
+		errorJSON(w, http.StatusPreconditionFailed, "If-Match does not match the current version")
+		return nil, false
+	}
+	return &v, true
+}
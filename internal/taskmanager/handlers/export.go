@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// flushEvery is how many exported tasks are written between flushes.
+const flushEvery = 100
+
+var exportColumns = []string{
+	"id", "title", "description", "status", "priority", "estimate_minutes", "story_points", "due_at",
+	"parent_id", "project_id", "workflow_id", "assignee_id", "labels",
+	"created_at", "updated_at",
+}
+
+func formatOptInt(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(*v, 10)
+}
+
+func exportRecord(t entity.Task) []string {
+	due := ""
+	if t.DueAt != nil {
+		due = t.DueAt.UTC().Format(time.RFC3339)
+	}
+	labels := make([]string, 0, len(t.Labels))
+	for _, l := range t.Labels {
+		labels = append(labels, l.Name)
+	}
+	return []string{
+		strconv.FormatInt(t.ID, 10),
+		t.Title,
+		t.Description,
+		t.Status,
+		strconv.FormatInt(t.Priority, 10),
+		formatOptInt(t.EstimateMinutes),
+		formatOptInt(t.StoryPoints),
+		due,
+		formatOptInt(t.ParentID),
+		formatOptInt(t.ProjectID),
+		formatOptInt(t.WorkflowID),
+		formatOptInt(t.AssigneeID),
+		strings.Join(labels, ";"),
+		t.CreatedAt.UTC().Format(time.RFC3339),
+		t.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// UserTasksExportHandler streams tasks straight from the database cursor to
+// the client. Once the first task is out the status can no longer change,
+// so a failure midway only cuts the body short and is logged.
+func UserTasksExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, perr := parseUserTasksActionPath(r, "export")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+		errorJSON(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	flusher, _ := w.(http.Flusher)
+	written := 0
+	flush := func() {
+		written++
+		if flusher != nil && written%flushEvery == 0 {
+			flusher.Flush()
+		}
+	}
+
+	var err error
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="tasks.csv"`)
+		cw := csv.NewWriter(w)
+		cw.Write(exportColumns)
+		err = taskSvc.ExportTasks(r.Context(), int64(uid), func(t entity.Task) error {
+			cw.Write(exportRecord(t))
+			cw.Flush()
+			flush()
+			return cw.Error()
+		})
+		if err == nil || written > 0 {
+			cw.Flush()
+		}
+
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="tasks.json"`)
+		enc := json.NewEncoder(w)
+		err = taskSvc.ExportTasks(r.Context(), int64(uid), func(t entity.Task) error {
+			sep := ","
+			if written == 0 {
+				sep = "["
+			}
+			if _, err := io.WriteString(w, sep); err != nil {
+				return err
+			}
+			flush()
+			return enc.Encode(toTaskResponse(t))
+		})
+		if err == nil || written > 0 {
+			if written == 0 {
+				io.WriteString(w, "[")
+			}
+			io.WriteString(w, "]\n")
+		}
+
+	case "ndjson":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="tasks.ndjson"`)
+		enc := json.NewEncoder(w)
+		err = taskSvc.ExportTasks(r.Context(), int64(uid), func(t entity.Task) error {
+			flush()
+			return enc.Encode(toTaskResponse(t))
+		})
+
+	default:
+		errorJSON(w, http.StatusBadRequest, "invalid format, use csv, json or ndjson")
+		return
+	}
+
+	if err != nil {
+		if written == 0 {
+			w.Header().Del("Content-Disposition")
+			respondTaskError(w, err)
+			return
+		}
+		log.Printf("export tasks of user %d: %v", uid, err)
+	}
+}
@@ -3,17 +3,23 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
 	"io"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
 	errBadPath   = errors.New("bad user path")
 	errBadID     = errors.New("bad user id")
 	errBadTaskID = errors.New("bad task id")
+	errBadItemID = errors.New("bad item id")
+
+	errBadProjectID = errors.New("bad project id")
+	errNoActor      = errors.New("missing or invalid X-User-ID header")
 )
 
 func parseUserID(r *http.Request) (int, error) {
@@ -142,6 +148,24 @@ func parseUserTasksListPath(r *http.Request) (int, error) {
 	return uid, nil
 }
 
+// parseUserSubPath matches /users/{id}/{sub} with an optional trailing slash.
+func parseUserSubPath(r *http.Request, sub string) (int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !(len(parts) == 4 || (len(parts) == 5 && parts[4] == "")) {
+		return 0, errBadPath
+	}
+	if parts[1] != "users" || parts[3] != sub {
+		return 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, errBadID
+	}
+	return uid, nil
+}
+
 func parseUserTaskDetailPath(r *http.Request) (int, int, error) {
 	parts := strings.Split(r.URL.Path, "/")
 
@@ -160,3 +184,261 @@ func parseUserTaskDetailPath(r *http.Request) (int, int, error) {
 	}
 	return uid, tid, nil
 }
+
+func parseUserTasksActionPath(r *http.Request, action string) (int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !((len(parts) == 5 && parts[4] == action) ||
+		(len(parts) == 6 && parts[4] == action && parts[5] == "")) {
+		return 0, errBadPath
+	}
+	if parts[1] != "users" || parts[3] != "tasks" {
+		return 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, errBadID
+	}
+	return uid, nil
+}
+
+func parseUserTaskSubPath(r *http.Request, sub string) (int, int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !((len(parts) == 6 && parts[5] == sub) ||
+		(len(parts) == 7 && parts[5] == sub && parts[6] == "")) {
+		return 0, 0, errBadPath
+	}
+	if parts[1] != "users" || parts[3] != "tasks" {
+		return 0, 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, 0, errBadID
+	}
+	tid, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return 0, 0, errBadTaskID
+	}
+	return uid, tid, nil
+}
+
+func parseUserTaskSubItemPath(r *http.Request, sub string) (int, int, int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !((len(parts) == 7 && parts[5] == sub) ||
+		(len(parts) == 8 && parts[5] == sub && parts[7] == "")) {
+		return 0, 0, 0, errBadPath
+	}
+	if parts[1] != "users" || parts[3] != "tasks" {
+		return 0, 0, 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, 0, 0, errBadID
+	}
+	tid, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return 0, 0, 0, errBadTaskID
+	}
+	itemID, err := strconv.Atoi(parts[6])
+	if err != nil {
+		return 0, 0, 0, errBadItemID
+	}
+	return uid, tid, itemID, nil
+}
+
+// nullableInt64 tells an explicit JSON null apart from a missing field.
+type nullableInt64 struct {
+	Set   bool
+	Value *int64
+}
+
+func (n *nullableInt64) UnmarshalJSON(b []byte) error {
+	n.Set = true
+	if string(b) == "null" {
+		n.Value = nil
+		return nil
+	}
+	var v int64
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	n.Value = &v
+	return nil
+}
+
+func respondPathError(w http.ResponseWriter, r *http.Request, perr error) {
+	switch {
+	case errors.Is(perr, errBadPath):
+		http.NotFound(w, r)
+	case errors.Is(perr, errBadID):
+		errorJSON(w, http.StatusBadRequest, "invalid user id")
+	case errors.Is(perr, errBadTaskID):
+		errorJSON(w, http.StatusBadRequest, "invalid task id")
+	case errors.Is(perr, errBadItemID):
+		errorJSON(w, http.StatusBadRequest, "invalid id")
+	case errors.Is(perr, errBadProjectID):
+		errorJSON(w, http.StatusBadRequest, "invalid project id")
+	case errors.Is(perr, errNoActor):
+		errorJSON(w, http.StatusBadRequest, errNoActor.Error())
+	default:
+		errorJSON(w, http.StatusBadRequest, "bad request")
+	}
+}
+
+func parsePaging(r *http.Request) (limit, offset int, err error) {
+	v := r.URL.Query()
+	if s := v.Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			return 0, 0, errors.New("invalid limit")
+		}
+	}
+	if s := v.Get("offset"); s != "" {
+		offset, err = strconv.Atoi(s)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("invalid offset")
+		}
+	}
+	return limit, offset, nil
+}
+
+// taskFilterParams are the task list parameters that filter or sort it.
+// A request with none of them gets the user's default view.
+var taskFilterParams = []string{
+	"status", "priority_min", "priority_max", "estimate_min", "estimate_max",
+	"points_min", "points_max", "due_after", "due_before", "overdue",
+	"label", "label_match", "sort", "order",
+}
+
+func hasTaskFilters(r *http.Request) bool {
+	v := r.URL.Query()
+	for _, name := range taskFilterParams {
+		if _, ok := v[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTaskListQuery(r *http.Request) (service.TaskListQuery, error) {
+	v := r.URL.Query()
+	var q service.TaskListQuery
+
+	for _, raw := range v["status"] {
+		for _, st := range strings.Split(raw, ",") {
+			if st = strings.TrimSpace(st); st != "" {
+				q.Statuses = append(q.Statuses, st)
+			}
+		}
+	}
+
+	if s := v.Get("priority_min"); s != "" {
+		p, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return q, errors.New("invalid priority_min")
+		}
+		q.MinPriority = &p
+	}
+	if s := v.Get("priority_max"); s != "" {
+		p, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return q, errors.New("invalid priority_max")
+		}
+		q.MaxPriority = &p
+	}
+	if s := v.Get("estimate_min"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return q, errors.New("invalid estimate_min")
+		}
+		q.MinEstimate = &n
+	}
+	if s := v.Get("estimate_max"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return q, errors.New("invalid estimate_max")
+		}
+		q.MaxEstimate = &n
+	}
+	if s := v.Get("points_min"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return q, errors.New("invalid points_min")
+		}
+		q.MinPoints = &n
+	}
+	if s := v.Get("points_max"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return q, errors.New("invalid points_max")
+		}
+		q.MaxPoints = &n
+	}
+
+	if s := v.Get("due_after"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return q, errors.New("invalid due_after, use RFC3339 e.g 2025-08-20T10:00:00Z")
+		}
+		q.DueAfter = &t
+	}
+	if s := v.Get("due_before"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return q, errors.New("invalid due_before, use RFC3339 e.g 2025-08-20T10:00:00Z")
+		}
+		q.DueBefore = &t
+	}
+
+	if s := v.Get("overdue"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return q, errors.New("invalid overdue, use true or false")
+		}
+		q.OverdueOnly = b
+	}
+
+	for _, raw := range v["label"] {
+		for _, name := range strings.Split(raw, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				q.Labels = append(q.Labels, name)
+			}
+		}
+	}
+	switch strings.ToLower(v.Get("label_match")) {
+	case "", "all":
+		q.AnyLabel = false
+	case "any":
+		q.AnyLabel = true
+	default:
+		return q, errors.New("invalid label_match, use all or any")
+	}
+
+	q.SortBy = v.Get("sort")
+	switch strings.ToLower(v.Get("order")) {
+	case "":
+		q.Desc = q.SortBy == ""
+	case "asc":
+		q.Desc = false
+	case "desc":
+		q.Desc = true
+	default:
+		return q, errors.New("invalid order, use asc or desc")
+	}
+
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return q, errors.New("invalid limit")
+		}
+		q.Limit = n
+	}
+	q.Cursor = v.Get("cursor")
+
+	return q, nil
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type TaskEventResponse struct {
+	ID        int64     `json:"id"`
+	ActorID   *int64    `json:"actor_id"`
+	Field     string    `json:"field"`
+	OldValue  *string   `json:"old_value"`
+	NewValue  *string   `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TaskHistoryResponse struct {
+	Items      []TaskEventResponse `json:"items"`
+	NextCursor *string             `json:"next_cursor"`
+}
+
+func toTaskEventResponse(e entity.TaskEvent) TaskEventResponse {
+	return TaskEventResponse{
+		ID:        e.ID,
+		ActorID:   e.ActorID,
+		Field:     e.Field,
+		OldValue:  e.OldValue,
+		NewValue:  e.NewValue,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+func UserTaskHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, tid, perr := parseUserTaskSubPath(r, "history")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	limit := 0
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			errorJSON(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	page, err := taskSvc.TaskHistory(r.Context(), int64(uid), int64(tid), r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		respondTaskError(w, err)
+		return
+	}
+
+	resp := TaskHistoryResponse{Items: make([]TaskEventResponse, 0, len(page.Events))}
+	for _, e := range page.Events {
+		resp.Items = append(resp.Items, toTaskEventResponse(e))
+	}
+	if page.NextCursor != "" {
+		resp.NextCursor = &page.NextCursor
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
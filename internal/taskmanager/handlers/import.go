@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errBadRecord marks rows that could not be decoded; its message is safe to
+// show to the client.
+var errBadRecord = errors.New("invalid record")
+
+type ImportErrorResponse struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+type ImportResponse struct {
+	Rows    int                   `json:"rows"`
+	Valid   int                   `json:"valid"`
+	Created int                   `json:"created"`
+	DryRun  bool                  `json:"dry_run"`
+	Errors  []ImportErrorResponse `json:"errors"`
+}
+
+// importRecord is one JSON or NDJSON task. Unknown fields are ignored so
+// that an export can be fed back as is.
+type importRecord struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Status      string  `json:"status"`
+	Priority    int64   `json:"priority"`
+	DueAt       *string `json:"due_at"`
+	WorkflowID  *int64  `json:"workflow_id"`
+}
+
+func (rec importRecord) toRow(n int) service.ImportRow {
+	row := service.ImportRow{
+		Row:         n,
+		Title:       rec.Title,
+		Description: rec.Description,
+		Status:      rec.Status,
+		Priority:    rec.Priority,
+		WorkflowID:  rec.WorkflowID,
+	}
+	if rec.DueAt != nil {
+		row.DueAt = *rec.DueAt
+	}
+	return row
+}
+
+func decodeImportRecord(n int, raw []byte) service.ImportRow {
+	var rec importRecord
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return service.ImportRow{Row: n, Err: fmt.Errorf("%w: %v", errBadRecord, err)}
+	}
+	return rec.toRow(n)
+}
+
+// readImportCSV maps columns by the header row; title is required, the
+// other columns of an export are ignored.
+func readImportCSV(r io.Reader) ([]service.ImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, errors.New("missing csv header")
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := col["title"]; !ok {
+		return nil, errors.New("csv header has no title column")
+	}
+	field := func(rec []string, name string) string {
+		i, ok := col[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	var rows []service.ImportRow
+	for n := 1; ; n++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				rows = append(rows, service.ImportRow{Row: n, Err: fmt.Errorf("%w: %v", errBadRecord, pe.Err)})
+				continue
+			}
+			return nil, err
+		}
+
+		row := service.ImportRow{
+			Row:         n,
+			Title:       field(rec, "title"),
+			Description: field(rec, "description"),
+			Status:      field(rec, "status"),
+			DueAt:       field(rec, "due_at"),
+		}
+		if v := field(rec, "priority"); v != "" {
+			p, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				row.Err = service.ErrBadPriority
+			}
+			row.Priority = p
+		}
+		if v := field(rec, "workflow_id"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				row.Err = service.ErrBadWorkflow
+			}
+			row.WorkflowID = &id
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readImportJSON(r io.Reader) ([]service.ImportRow, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			return nil, err
+		}
+		return nil, errors.New("body must be a JSON array of tasks")
+	}
+	rows := make([]service.ImportRow, 0, len(raw))
+	for i, rec := range raw {
+		rows = append(rows, decodeImportRecord(i+1, rec))
+	}
+	return rows, nil
+}
+
+func readImportNDJSON(r io.Reader) ([]service.ImportRow, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+
+	var rows []service.ImportRow
+	n := 0
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		n++
+		rows = append(rows, decodeImportRecord(n, line))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// UserTasksImportHandler creates tasks from a CSV, JSON or NDJSON file. The
+// format comes from ?format= or, failing that, from the Content-Type.
+// Nothing is written unless every row is valid; ?dry_run=true only checks.
+func UserTasksImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, perr := parseUserTasksActionPath(r, "import")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+		errorJSON(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, "invalid dry_run")
+			return
+		}
+		dryRun = b
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		ct := r.Header.Get("Content-Type")
+		switch {
+		case strings.HasPrefix(ct, "text/csv"):
+			format = "csv"
+		case strings.HasPrefix(ct, "application/x-ndjson"):
+			format = "ndjson"
+		case strings.HasPrefix(ct, "application/json"):
+			format = "json"
+		default:
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be text/csv, application/json or application/x-ndjson")
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, 10<<20)
+	var rows []service.ImportRow
+	var err error
+	switch format {
+	case "csv":
+		rows, err = readImportCSV(body)
+	case "json":
+		rows, err = readImportJSON(body)
+	case "ndjson":
+		rows, err = readImportNDJSON(body)
+	default:
+		errorJSON(w, http.StatusBadRequest, "invalid format, use csv, json or ndjson")
+		return
+	}
+	if err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			errorJSON(w, http.StatusRequestEntityTooLarge, "import file too large")
+			return
+		}
+		errorJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := taskSvc.ImportTasks(r.Context(), int64(uid), rows, dryRun)
+	switch {
+	case errors.Is(err, service.ErrImportEmpty):
+		errorJSON(w, http.StatusBadRequest, "nothing to import")
+		return
+	case errors.Is(err, service.ErrImportTooBig):
+		errorJSON(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d rows per import", service.MaxImportRows))
+		return
+	case err != nil:
+		respondTaskError(w, err)
+		return
+	}
+
+	resp := ImportResponse{
+		Rows:    report.Rows,
+		Valid:   report.Rows - len(report.Errors),
+		Created: report.Created,
+		DryRun:  report.DryRun,
+		Errors:  make([]ImportErrorResponse, 0, len(report.Errors)),
+	}
+	for _, e := range report.Errors {
+		_, msg := taskErrorStatus(e.Err)
+		if errors.Is(e.Err, errBadRecord) {
+			msg = e.Err.Error()
+		}
+		resp.Errors = append(resp.Errors, ImportErrorResponse{Row: e.Row, Error: msg})
+	}
+
+	code := http.StatusOK
+	switch {
+	case len(resp.Errors) > 0:
+		code = http.StatusUnprocessableEntity
+	case resp.Created > 0:
+		code = http.StatusCreated
+	}
+	writeJSON(w, code, resp)
+}
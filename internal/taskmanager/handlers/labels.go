@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type LabelRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type LabelResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TaskLabelResponse struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+var labelSvc *service.LabelService
+
+func SetLabelService(s *service.LabelService) { labelSvc = s }
+
+func toLabelResponse(l entity.Label) LabelResponse {
+	return LabelResponse{
+		ID:        l.ID,
+		Name:      l.Name,
+		Color:     l.Color,
+		CreatedAt: l.CreatedAt,
+		UpdatedAt: l.UpdatedAt,
+	}
+}
+
+func respondLabelError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmptyLabelName):
+		errorJSON(w, http.StatusBadRequest, "empty label name")
+	case errors.Is(err, service.ErrBadLabelName):
+		errorJSON(w, http.StatusBadRequest, "invalid label name")
+	case errors.Is(err, service.ErrBadColor):
+		errorJSON(w, http.StatusBadRequest, "invalid color, use #RRGGBB")
+	case errors.Is(err, service.ErrLabelNotFound):
+		errorJSON(w, http.StatusNotFound, "label not found")
+	case errors.Is(err, service.ErrLabelExists):
+		errorJSON(w, http.StatusConflict, "label with this name already exists")
+	default:
+		errorJSON(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+func parseUserLabelsPath(r *http.Request) (int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !(len(parts) == 4 || (len(parts) == 5 && parts[4] == "")) {
+		return 0, errBadPath
+	}
+	if parts[1] != "users" || parts[3] != "labels" {
+		return 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, errBadID
+	}
+	return uid, nil
+}
+
+func parseUserLabelDetailPath(r *http.Request) (int, int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !((len(parts) == 5 && parts[1] == "users" && parts[3] == "labels") ||
+		(len(parts) == 6 && parts[1] == "users" && parts[3] == "labels" && parts[5] == "")) {
+		return 0, 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, 0, errBadID
+	}
+	lid, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return 0, 0, errBadItemID
+	}
+	return uid, lid, nil
+}
+
+func UserLabelsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, perr := parseUserLabelsPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+		if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+			errorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		list, err := labelSvc.ListLabels(r.Context(), int64(uid))
+		if err != nil {
+			respondLabelError(w, err)
+			return
+		}
+		resp := make([]LabelResponse, 0, len(list))
+		for _, l := range list {
+			resp = append(resp, toLabelResponse(l))
+		}
+		writeJSON(w, http.StatusOK, resp)
+
+	case http.MethodPost:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, perr := parseUserLabelsPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+		if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+			errorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		var req LabelRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		l, err := labelSvc.CreateLabel(r.Context(), int64(uid), req.Name, req.Color)
+		if err != nil {
+			respondLabelError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toLabelResponse(l))
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func UserLabelDetailHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, lid, perr := parseUserLabelDetailPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		l, err := labelSvc.GetLabel(r.Context(), int64(uid), int64(lid))
+		if err != nil {
+			respondLabelError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toLabelResponse(l))
+
+	case http.MethodPatch:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, lid, perr := parseUserLabelDetailPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		var req struct {
+			Name  *string `json:"name"`
+			Color *string `json:"color"`
+		}
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+		if req.Name == nil && req.Color == nil {
+			errorJSON(w, http.StatusBadRequest, "no fields to update")
+			return
+		}
+
+		l, err := labelSvc.PatchLabel(r.Context(), int64(uid), int64(lid), req.Name, req.Color)
+		if err != nil {
+			respondLabelError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toLabelResponse(l))
+
+	case http.MethodDelete:
+		uid, lid, perr := parseUserLabelDetailPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		if err := labelSvc.DeleteLabel(r.Context(), int64(uid), int64(lid)); err != nil {
+			respondLabelError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PATCH, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// UserTaskMoveHandler serves POST /users/{id}/tasks/{tid}/move, which
+// reorders a task within its status column. The body names the task it
+// should follow (after_id), the task it should precede (before_id), or
+// both. Owner-only.
+func UserTaskMoveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ct := r.Header.Get("Content-Type")
+	if !strings.HasPrefix(ct, "application/json") {
+		errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+
+	uid, tid, perr := parseUserTaskSubPath(r, "move")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	var req struct {
+		AfterID  *int64 `json:"after_id"`
+		BeforeID *int64 `json:"before_id"`
+	}
+	if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+	if req.AfterID == nil && req.BeforeID == nil {
+		errorJSON(w, http.StatusBadRequest, "after_id or before_id is required")
+		return
+	}
+
+	task, err := taskSvc.MoveTask(r.Context(), int64(uid), int64(tid), req.AfterID, req.BeforeID)
+	if err != nil {
+		respondTaskError(w, err)
+		return
+	}
+	setETag(w, task.Version)
+	writeJSON(w, http.StatusOK, toTaskResponse(task))
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ProjectRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type ProjectResponse struct {
+	ID          int64     `json:"id"`
+	OwnerID     int64     `json:"owner_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ProjectMemberResponse struct {
+	UserID  int64     `json:"user_id"`
+	Role    string    `json:"role"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+var projectSvc *service.ProjectService
+
+func SetProjectService(s *service.ProjectService) { projectSvc = s }
+
+func toProjectResponse(p entity.Project) ProjectResponse {
+	return ProjectResponse{
+		ID:          p.ID,
+		OwnerID:     p.OwnerID,
+		Name:        p.Name,
+		Description: p.Description,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
+
+func toProjectMemberResponse(m entity.ProjectMember) ProjectMemberResponse {
+	return ProjectMemberResponse{UserID: m.UserID, Role: m.Role, AddedAt: m.AddedAt}
+}
+
+func respondProjectError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmptyProjectName):
+		errorJSON(w, http.StatusBadRequest, "empty project name")
+	case errors.Is(err, service.ErrBadProjectName):
+		errorJSON(w, http.StatusBadRequest, "project name too long")
+	case errors.Is(err, service.ErrProjectNotFound):
+		errorJSON(w, http.StatusNotFound, "project not found")
+	case errors.Is(err, service.ErrNotProjectOwner):
+		errorJSON(w, http.StatusForbidden, "only the project owner can do this")
+	case errors.Is(err, service.ErrMemberExists):
+		errorJSON(w, http.StatusConflict, "user is already a member")
+	case errors.Is(err, service.ErrMemberNotFound):
+		errorJSON(w, http.StatusNotFound, "member not found")
+	case errors.Is(err, service.ErrBadMember):
+		errorJSON(w, http.StatusBadRequest, "invalid member")
+	default:
+		respondTaskError(w, err)
+	}
+}
+
+// actorID reads the acting user of a /projects request. Project routes are
+// not nested under /users/{id}, so the caller identifies itself with the
+// X-User-ID header.
+func actorID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.Header.Get("X-User-ID"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errNoActor
+	}
+	return id, nil
+}
+
+func parseProjectPath(r *http.Request, sub string) (int64, error) {
+	parts := strings.Split(r.URL.Path, "/")
+	n := 3
+	if sub != "" {
+		n = 4
+	}
+
+	if !(len(parts) == n || (len(parts) == n+1 && parts[n] == "")) {
+		return 0, errBadPath
+	}
+	if parts[1] != "projects" || (sub != "" && parts[3] != sub) {
+		return 0, errBadPath
+	}
+
+	pid, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, errBadProjectID
+	}
+	return pid, nil
+}
+
+func parseProjectMemberPath(r *http.Request) (int64, int64, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !(len(parts) == 5 || (len(parts) == 6 && parts[5] == "")) {
+		return 0, 0, errBadPath
+	}
+	if parts[1] != "projects" || parts[3] != "members" {
+		return 0, 0, errBadPath
+	}
+
+	pid, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, 0, errBadProjectID
+	}
+	uid, err := strconv.ParseInt(parts[4], 10, 64)
+	if err != nil {
+		return 0, 0, errBadID
+	}
+	return pid, uid, nil
+}
+
+func ProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	uid, err := actorID(r)
+	if err != nil {
+		respondPathError(w, r, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := projectSvc.ListProjects(r.Context(), uid)
+		if err != nil {
+			respondProjectError(w, err)
+			return
+		}
+		resp := make([]ProjectResponse, 0, len(list))
+		for _, p := range list {
+			resp = append(resp, toProjectResponse(p))
+		}
+		writeJSON(w, http.StatusOK, resp)
+
+	case http.MethodPost:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		var req ProjectRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		p, err := projectSvc.CreateProject(r.Context(), uid, req.Name, req.Description)
+		if err != nil {
+			respondProjectError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toProjectResponse(p))
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func ProjectDetailHandler(w http.ResponseWriter, r *http.Request) {
+	pid, perr := parseProjectPath(r, "")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	uid, err := actorID(r)
+	if err != nil {
+		respondPathError(w, r, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		p, err := projectSvc.GetProject(r.Context(), uid, pid)
+		if err != nil {
+			respondProjectError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toProjectResponse(p))
+
+	case http.MethodPatch:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		var req struct {
+			Name        *string `json:"name"`
+			Description *string `json:"description"`
+		}
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+		if req.Name == nil && req.Description == nil {
+			errorJSON(w, http.StatusBadRequest, "nothing to update")
+			return
+		}
+
+		p, err := projectSvc.PatchProject(r.Context(), uid, pid, req.Name, req.Description)
+		if err != nil {
+			respondProjectError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toProjectResponse(p))
+
+	case http.MethodDelete:
+		if err := projectSvc.DeleteProject(r.Context(), uid, pid); err != nil {
+			respondProjectError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PATCH, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func ProjectMembersHandler(w http.ResponseWriter, r *http.Request) {
+	pid, perr := parseProjectPath(r, "members")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	uid, err := actorID(r)
+	if err != nil {
+		respondPathError(w, r, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := projectSvc.ListMembers(r.Context(), uid, pid)
+		if err != nil {
+			respondProjectError(w, err)
+			return
+		}
+		resp := make([]ProjectMemberResponse, 0, len(list))
+		for _, m := range list {
+			resp = append(resp, toProjectMemberResponse(m))
+		}
+		writeJSON(w, http.StatusOK, resp)
+
+	case http.MethodPost:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		var req struct {
+			UserID int64 `json:"user_id"`
+		}
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		m, err := projectSvc.AddMember(r.Context(), uid, pid, req.UserID)
+		if err != nil {
+			respondProjectError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toProjectMemberResponse(m))
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func ProjectMemberDetailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	pid, memberID, perr := parseProjectMemberPath(r)
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	uid, err := actorID(r)
+	if err != nil {
+		respondPathError(w, r, err)
+		return
+	}
+
+	if err := projectSvc.RemoveMember(r.Context(), uid, pid, memberID); err != nil {
+		respondProjectError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func ProjectTasksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	pid, perr := parseProjectPath(r, "tasks")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	uid, err := actorID(r)
+	if err != nil {
+		respondPathError(w, r, err)
+		return
+	}
+
+	q, err := parseTaskListQuery(r)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := projectSvc.ListTasks(r.Context(), uid, pid, q)
+	if err != nil {
+		respondProjectError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toTaskListResponse(page))
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RecurrenceResponse struct {
+	SeriesID int64       `json:"series_id"`
+	RRule    string      `json:"rrule"`
+	DTStart  time.Time   `json:"dtstart"`
+	Upcoming []time.Time `json:"upcoming"`
+}
+
+func toRecurrenceResponse(rec service.Recurrence) RecurrenceResponse {
+	resp := RecurrenceResponse{
+		SeriesID: rec.Series.ID,
+		RRule:    rec.Series.RRule,
+		DTStart:  rec.Series.DTStart,
+		Upcoming: rec.Upcoming,
+	}
+	if resp.Upcoming == nil {
+		resp.Upcoming = []time.Time{}
+	}
+	return resp
+}
+
+// UserTaskRecurrenceHandler serves /users/{id}/tasks/{tid}/recurrence:
+// GET shows the rule and upcoming occurrences, PUT starts or changes the
+// series and DELETE stops it.
+func UserTaskRecurrenceHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, tid, perr := parseUserTaskSubPath(r, "recurrence")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		n := 0
+		if s := r.URL.Query().Get("count"); s != "" {
+			v, err := strconv.Atoi(s)
+			if err != nil || v <= 0 {
+				errorJSON(w, http.StatusBadRequest, "invalid count")
+				return
+			}
+			n = v
+		}
+
+		rec, err := taskSvc.GetRecurrence(r.Context(), int64(uid), int64(tid), n)
+		if err != nil {
+			respondTaskError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toRecurrenceResponse(rec))
+
+	case http.MethodPut:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, tid, perr := parseUserTaskSubPath(r, "recurrence")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		var req struct {
+			RRule string `json:"rrule"`
+		}
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		rec, err := taskSvc.SetRecurrence(r.Context(), int64(uid), int64(tid), req.RRule)
+		if err != nil {
+			respondTaskError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toRecurrenceResponse(rec))
+
+	case http.MethodDelete:
+		uid, tid, perr := parseUserTaskSubPath(r, "recurrence")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		if err := taskSvc.StopRecurrence(r.Context(), int64(uid), int64(tid)); err != nil {
+			respondTaskError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+func ProjectsSubtreeHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if len(parts) < 2 || parts[1] != "projects" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if len(parts) == 3 && parts[2] == "" {
+		ProjectsHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 3 && parts[2] != "") ||
+		(len(parts) == 4 && parts[2] != "" && parts[3] == "") {
+		ProjectDetailHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "members") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "members" && parts[4] == "") {
+		ProjectMembersHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 5 && parts[2] != "" && parts[3] == "members") ||
+		(len(parts) == 6 && parts[2] != "" && parts[3] == "members" && parts[5] == "") {
+		ProjectMemberDetailHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "tasks") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "tasks" && parts[4] == "") {
+		ProjectTasksHandler(w, r)
+		return
+	}
+
+	http.NotFound(w, r)
+}
@@ -31,11 +31,246 @@ func UsersSubtreeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "tasks:batch") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "tasks:batch" && parts[4] == "") {
+		UserTasksBatchHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "labels") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "labels" && parts[4] == "") {
+		UserLabelsHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "templates") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "templates" && parts[4] == "") {
+		UserTemplatesHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "views") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "views" && parts[4] == "") {
+		UserViewsHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "webhooks") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "webhooks" && parts[4] == "") {
+		UserWebhooksHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "workflows") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "workflows" && parts[4] == "") {
+		UserWorkflowsHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "assigned-tasks") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "assigned-tasks" && parts[4] == "") {
+		UserAssignedTasksHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "trash") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "trash" && parts[4] == "") {
+		UserTrashHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "calendar.ics") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "calendar.ics" && parts[4] == "") {
+		UserCalendarHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "calendar-token") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "calendar-token" && parts[4] == "") {
+		UserCalendarTokenHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "effort-report") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "effort-report" && parts[4] == "") {
+		UserEffortReportHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "time-report") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "time-report" && parts[4] == "") {
+		UserTimeReportHandler(w, r)
+		return
+	}
+
+	if len(parts) >= 4 && len(parts) <= 6 && parts[2] != "" && parts[3] == "timer" {
+		UserTimerHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 4 && parts[2] != "" && parts[3] == "restore") ||
+		(len(parts) == 5 && parts[2] != "" && parts[3] == "restore" && parts[4] == "") {
+		UserRestoreHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 5 && parts[2] != "" && parts[3] == "labels") ||
+		(len(parts) == 6 && parts[2] != "" && parts[3] == "labels" && parts[5] == "") {
+		UserLabelDetailHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 5 && parts[2] != "" && parts[3] == "templates") ||
+		(len(parts) == 6 && parts[2] != "" && parts[3] == "templates" && parts[5] == "") {
+		UserTemplateDetailHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 5 && parts[3] == "views" && parts[4] == "default") ||
+		(len(parts) == 6 && parts[3] == "views" && parts[4] == "default" && parts[5] == "") {
+		UserDefaultViewHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 5 && parts[2] != "" && parts[3] == "views") ||
+		(len(parts) == 6 && parts[2] != "" && parts[3] == "views" && parts[5] == "") {
+		UserViewDetailHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 6 && parts[3] == "views" && parts[5] == "tasks") ||
+		(len(parts) == 7 && parts[3] == "views" && parts[5] == "tasks" && parts[6] == "") {
+		UserViewTasksHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 7 && parts[3] == "views" && parts[5] == "shares") ||
+		(len(parts) == 8 && parts[3] == "views" && parts[5] == "shares" && parts[7] == "") {
+		UserViewShareHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 5 && parts[2] != "" && parts[3] == "webhooks") ||
+		(len(parts) == 6 && parts[2] != "" && parts[3] == "webhooks" && parts[5] == "") {
+		UserWebhookDetailHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 6 && parts[3] == "webhooks" && parts[5] == "deliveries") ||
+		(len(parts) == 7 && parts[3] == "webhooks" && parts[5] == "deliveries" && parts[6] == "") {
+		UserWebhookDeliveriesHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 7 && parts[3] == "webhooks" && parts[5] == "deliveries") ||
+		(len(parts) == 8 && parts[3] == "webhooks" && parts[5] == "deliveries" && parts[7] == "") {
+		UserWebhookDeliveryDetailHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 8 && parts[3] == "webhooks" && parts[5] == "deliveries" && parts[7] == "redeliver") ||
+		(len(parts) == 9 && parts[3] == "webhooks" && parts[5] == "deliveries" && parts[7] == "redeliver" && parts[8] == "") {
+		UserWebhookRedeliverHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 5 && parts[2] != "" && parts[3] == "workflows") ||
+		(len(parts) == 6 && parts[2] != "" && parts[3] == "workflows" && parts[5] == "") {
+		UserWorkflowDetailHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 5 && parts[3] == "tasks" && parts[4] == "export") ||
+		(len(parts) == 6 && parts[3] == "tasks" && parts[4] == "export" && parts[5] == "") {
+		UserTasksExportHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 5 && parts[3] == "tasks" && parts[4] == "import") ||
+		(len(parts) == 6 && parts[3] == "tasks" && parts[4] == "import" && parts[5] == "") {
+		UserTasksImportHandler(w, r)
+		return
+	}
+
+	if (len(parts) == 5 && parts[3] == "tasks" && parts[4] == "search") ||
+		(len(parts) == 6 && parts[3] == "tasks" && parts[4] == "search" && parts[5] == "") {
+		UserTasksSearchHandler(w, r)
+		return
+	}
+
 	if (len(parts) == 5 && parts[2] != "" && parts[3] == "tasks") ||
 		(len(parts) == 6 && parts[2] != "" && parts[3] == "tasks" && parts[5] == "") {
 		UserTaskDetailHandler(w, r)
 		return
 	}
 
+	if (len(parts) == 6 && parts[3] == "tasks" && parts[4] == "from-template") ||
+		(len(parts) == 7 && parts[3] == "tasks" && parts[4] == "from-template" && parts[6] == "") {
+		UserTaskFromTemplateHandler(w, r)
+		return
+	}
+
+	if len(parts) == 6 || (len(parts) == 7 && parts[6] == "") {
+		if parts[2] != "" && parts[3] == "tasks" && parts[4] != "" {
+			switch parts[5] {
+			case "children":
+				UserTaskChildrenHandler(w, r)
+				return
+			case "subtree":
+				UserTaskSubtreeHandler(w, r)
+				return
+			case "dependencies":
+				UserTaskDependenciesHandler(w, r)
+				return
+			case "comments":
+				UserTaskCommentsHandler(w, r)
+				return
+			case "assignee":
+				UserTaskAssigneeHandler(w, r)
+				return
+			case "recurrence":
+				UserTaskRecurrenceHandler(w, r)
+				return
+			case "history":
+				UserTaskHistoryHandler(w, r)
+				return
+			case "restore":
+				UserTaskRestoreHandler(w, r)
+				return
+			case "attachments":
+				UserTaskAttachmentsHandler(w, r)
+				return
+			case "time-entries":
+				UserTaskTimeEntriesHandler(w, r)
+				return
+			case "move":
+				UserTaskMoveHandler(w, r)
+				return
+			case "watchers":
+				UserTaskWatchersHandler(w, r)
+				return
+			}
+		}
+	}
+
+	if (len(parts) == 7 && parts[6] != "") || (len(parts) == 8 && parts[6] != "" && parts[7] == "") {
+		if parts[2] != "" && parts[3] == "tasks" && parts[4] != "" {
+			switch parts[5] {
+			case "dependencies":
+				UserTaskDependencyDetailHandler(w, r)
+				return
+			case "comments":
+				UserTaskCommentDetailHandler(w, r)
+				return
+			case "attachments":
+				UserTaskAttachmentDetailHandler(w, r)
+				return
+			case "time-entries":
+				UserTaskTimeEntryDetailHandler(w, r)
+				return
+			}
+		}
+	}
+
 	http.NotFound(w, r)
 }
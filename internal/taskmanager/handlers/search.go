@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"net/http"
+)
+
+type TaskSearchHitResponse struct {
+	Task               TaskResponse `json:"task"`
+	Rank               float64      `json:"rank"`
+	TitleSnippet       string       `json:"title_snippet"`
+	DescriptionSnippet string       `json:"description_snippet"`
+}
+
+func toSearchResponse(hits []entity.TaskSearchHit) []TaskSearchHitResponse {
+	resp := make([]TaskSearchHitResponse, 0, len(hits))
+	for _, h := range hits {
+		resp = append(resp, TaskSearchHitResponse{
+			Task:               toTaskResponse(h.Task),
+			Rank:               h.Rank,
+			TitleSnippet:       h.TitleSnippet,
+			DescriptionSnippet: h.DescriptionSnippet,
+		})
+	}
+	return resp
+}
+
+func UserTasksSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, perr := parseUserTasksActionPath(r, "search")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+		errorJSON(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	limit, offset, err := parsePaging(r)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	owner := int64(uid)
+	hits, err := taskSvc.SearchTasks(r.Context(), &owner, r.URL.Query().Get("q"), limit, offset)
+	if err != nil {
+		respondTaskError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toSearchResponse(hits))
+}
+
+func TasksSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	limit, offset, err := parsePaging(r)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hits, err := taskSvc.SearchTasks(r.Context(), nil, r.URL.Query().Get("q"), limit, offset)
+	if err != nil {
+		respondTaskError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toSearchResponse(hits))
+}
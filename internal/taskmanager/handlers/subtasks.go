@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"net/http"
+)
+
+func UserTaskChildrenHandler(w http.ResponseWriter, r *http.Request) {
+	serveTaskTree(w, r, "children", taskSvc.ListSubtasks)
+}
+
+func UserTaskSubtreeHandler(w http.ResponseWriter, r *http.Request) {
+	serveTaskTree(w, r, "subtree", taskSvc.ListSubtree)
+}
+
+func serveTaskTree(w http.ResponseWriter, r *http.Request, sub string, list func(ctx context.Context, uid, tid int64) ([]entity.Task, error)) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, tid, perr := parseUserTaskSubPath(r, sub)
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+		errorJSON(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	tasks, err := list(r.Context(), int64(uid), int64(tid))
+	if err != nil {
+		respondTaskError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toTaskResponses(tasks))
+}
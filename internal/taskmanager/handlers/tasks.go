@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
 	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
 	"net/http"
 	"strings"
@@ -9,18 +10,64 @@ import (
 )
 
 type TaskResponse struct {
-	ID          int64      `json:"id"`
-	UserID      int64      `json:"user_id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Status      string     `json:"status"`
-	Priority    int64      `json:"priority"`
-	DueAt       *time.Time `json:"due_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID          int64                 `json:"id"`
+	UserID      int64                 `json:"user_id"`
+	AssigneeID  *int64                `json:"assignee_id"`
+	ProjectID   *int64                `json:"project_id"`
+	WorkflowID  *int64                `json:"workflow_id"`
+	ParentID    *int64                `json:"parent_id"`
+	Title       string                `json:"title"`
+	Description string                `json:"description"`
+	Status      string                `json:"status"`
+	Priority    int64                 `json:"priority"`
+	DueAt       *time.Time            `json:"due_at"`
+	Estimate    *int64                `json:"estimate_minutes"`
+	StoryPoints *int64                `json:"story_points"`
+	Progress    *TaskProgressResponse `json:"progress,omitempty"`
+	BlockedBy   []int64               `json:"blocked_by"`
+	Blocking    []int64               `json:"blocking"`
+	Labels      []TaskLabelResponse   `json:"labels"`
+	Recurrence  *TaskRecurrenceRef    `json:"recurrence,omitempty"`
+	TimeSpent   int64                 `json:"time_spent"`
+	Rank        string                `json:"rank"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+	DeletedAt   *time.Time            `json:"deleted_at,omitempty"`
+}
+
+type TaskProgressResponse struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total"`
+}
+
+type TaskRecurrenceRef struct {
+	SeriesID     int64      `json:"series_id"`
+	RRule        string     `json:"rrule"`
+	OccurrenceAt *time.Time `json:"occurrence_at"`
+}
+
+type TaskListResponse struct {
+	Items      []TaskResponse `json:"items"`
+	NextCursor *string        `json:"next_cursor"`
 }
 
 type CreateTaskRequest struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Status      string  `json:"status"`
+	Priority    int     `json:"priority"`
+	DueAt       string  `json:"due_at"`
+	ParentID    *int64  `json:"parent_id"`
+	AssigneeID  *int64  `json:"assignee_id"`
+	ProjectID   *int64  `json:"project_id"`
+	WorkflowID  *int64  `json:"workflow_id"`
+	Estimate    *int64  `json:"estimate_minutes"`
+	StoryPoints *int64  `json:"story_points"`
+	LabelIDs    []int64 `json:"label_ids"`
+	RRule       string  `json:"rrule"`
+}
+
+type UpdateTaskRequest struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	Status      string `json:"status"`
@@ -28,10 +75,158 @@ type CreateTaskRequest struct {
 	DueAt       string `json:"due_at"`
 }
 
+type PatchTaskRequest struct {
+	Title        *string       `json:"title"`
+	Description  *string       `json:"description"`
+	Status       *string       `json:"status"`
+	Priority     *int          `json:"priority"`
+	DueAt        *string       `json:"due_at"`
+	ParentID     nullableInt64 `json:"parent_id"`
+	ProjectID    nullableInt64 `json:"project_id"`
+	WorkflowID   nullableInt64 `json:"workflow_id"`
+	Estimate     nullableInt64 `json:"estimate_minutes"`
+	StoryPoints  nullableInt64 `json:"story_points"`
+	AddLabels    []int64       `json:"add_labels"`
+	RemoveLabels []int64       `json:"remove_labels"`
+}
+
+var errBadDueAt = errors.New("invalid due_at, use RFC3339 e.g 2025-08-20T10:00:00Z")
+
 var taskSvc *service.TaskService
 
 func SetTaskService(s *service.TaskService) { taskSvc = s }
 
+func toTaskResponse(t entity.Task) TaskResponse {
+	resp := TaskResponse{
+		ID:          t.ID,
+		UserID:      t.UserID,
+		AssigneeID:  t.AssigneeID,
+		ProjectID:   t.ProjectID,
+		WorkflowID:  t.WorkflowID,
+		ParentID:    t.ParentID,
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      t.Status,
+		Priority:    t.Priority,
+		DueAt:       t.DueAt,
+		Estimate:    t.EstimateMinutes,
+		StoryPoints: t.StoryPoints,
+		BlockedBy:   t.BlockedBy,
+		Blocking:    t.Blocking,
+		TimeSpent:   t.TimeSpent,
+		Rank:        t.Rank,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+		DeletedAt:   t.DeletedAt,
+	}
+	if resp.BlockedBy == nil {
+		resp.BlockedBy = []int64{}
+	}
+	if resp.Blocking == nil {
+		resp.Blocking = []int64{}
+	}
+	resp.Labels = make([]TaskLabelResponse, 0, len(t.Labels))
+	for _, l := range t.Labels {
+		resp.Labels = append(resp.Labels, TaskLabelResponse{ID: l.ID, Name: l.Name, Color: l.Color})
+	}
+	if t.SeriesID != nil {
+		resp.Recurrence = &TaskRecurrenceRef{SeriesID: *t.SeriesID, RRule: t.RRule, OccurrenceAt: t.OccurrenceAt}
+	}
+	if t.SubtasksTotal > 0 {
+		resp.Progress = &TaskProgressResponse{Done: t.SubtasksDone, Total: t.SubtasksTotal}
+	}
+	return resp
+}
+
+// parseDueAt reads an optional RFC3339 due date; blank means none.
+func parseDueAt(s string) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, errBadDueAt
+	}
+	return &t, nil
+}
+
+func (req CreateTaskRequest) options() []service.TaskOption {
+	var opts []service.TaskOption
+	if req.ParentID != nil {
+		opts = append(opts, service.WithParent(*req.ParentID))
+	}
+	if req.AssigneeID != nil {
+		opts = append(opts, service.WithAssignee(*req.AssigneeID))
+	}
+	if req.ProjectID != nil {
+		opts = append(opts, service.WithProject(*req.ProjectID))
+	}
+	if req.WorkflowID != nil {
+		opts = append(opts, service.WithWorkflow(*req.WorkflowID))
+	}
+	if req.Estimate != nil {
+		opts = append(opts, service.WithEstimate(*req.Estimate))
+	}
+	if req.StoryPoints != nil {
+		opts = append(opts, service.WithStoryPoints(*req.StoryPoints))
+	}
+	if len(req.LabelIDs) > 0 {
+		opts = append(opts, service.WithLabels(req.LabelIDs...))
+	}
+	if req.RRule != "" {
+		opts = append(opts, service.WithRecurrence(req.RRule))
+	}
+	return opts
+}
+
+// toPatch converts the request; an empty due_at clears the due date.
+func (req PatchTaskRequest) toPatch() (entity.TaskPatch, error) {
+	p := entity.TaskPatch{
+		Title:        req.Title,
+		Description:  req.Description,
+		Status:       req.Status,
+		Priority:     req.Priority,
+		ParentSet:    req.ParentID.Set,
+		ParentID:     req.ParentID.Value,
+		ProjectSet:   req.ProjectID.Set,
+		ProjectID:    req.ProjectID.Value,
+		WorkflowSet:  req.WorkflowID.Set,
+		WorkflowID:   req.WorkflowID.Value,
+		EstimateSet:  req.Estimate.Set,
+		Estimate:     req.Estimate.Value,
+		PointsSet:    req.StoryPoints.Set,
+		Points:       req.StoryPoints.Value,
+		AddLabels:    req.AddLabels,
+		RemoveLabels: req.RemoveLabels,
+	}
+	if req.DueAt != nil {
+		due, err := parseDueAt(*req.DueAt)
+		if err != nil {
+			return entity.TaskPatch{}, err
+		}
+		p.DueAtSet = true
+		p.DueAt = due
+	}
+	return p, nil
+}
+
+func toTaskResponses(list []entity.Task) []TaskResponse {
+	resp := make([]TaskResponse, 0, len(list))
+	for _, t := range list {
+		resp = append(resp, toTaskResponse(t))
+	}
+	return resp
+}
+
+func toTaskListResponse(page service.TaskPage) TaskListResponse {
+	resp := TaskListResponse{Items: toTaskResponses(page.Tasks)}
+	if page.NextCursor != "" {
+		resp.NextCursor = &page.NextCursor
+	}
+	return resp
+}
+
 func UserTasksHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodHead:
@@ -75,26 +270,35 @@ func UserTasksHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		list, err := taskSvc.ListTasksByUser(r.Context(), int64(uid))
+		q, err := parseTaskListQuery(r)
 		if err != nil {
-			respondTaskError(w, err)
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		switch view := r.URL.Query().Get("view"); {
+		case view == "none":
+		case view != "":
+			errorJSON(w, http.StatusBadRequest, "invalid view, use none or GET /users/{id}/views/{view_id}/tasks")
 			return
+		case !hasTaskFilters(r):
+			dq, ok, err := viewSvc.DefaultQuery(r.Context(), int64(uid))
+			if err != nil {
+				respondViewError(w, err)
+				return
+			}
+			if ok {
+				dq.Limit, dq.Cursor = q.Limit, q.Cursor
+				q = dq
+			}
 		}
-		resp := make([]TaskResponse, 0, len(list))
-		for _, t := range list {
-			resp = append(resp, TaskResponse{
-				ID:          t.ID,
-				UserID:      t.UserID,
-				Title:       t.Title,
-				Description: t.Description,
-				Status:      t.Status,
-				Priority:    t.Priority,
-				DueAt:       t.DueAt,
-				CreatedAt:   t.CreatedAt,
-				UpdatedAt:   t.UpdatedAt,
-			})
+
+		page, err := taskSvc.ListTasks(r.Context(), int64(uid), q)
+		if err != nil {
+			respondTaskError(w, err)
+			return
 		}
-		writeJSON(w, http.StatusOK, resp)
+		writeJSON(w, http.StatusOK, toTaskListResponse(page))
 
 	case http.MethodPost:
 		ct := r.Header.Get("Content-Type")
@@ -123,14 +327,10 @@ func UserTasksHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		var duePtr *time.Time
-		if s := strings.TrimSpace(req.DueAt); s != "" {
-			t, err := time.Parse(time.RFC3339, s)
-			if err != nil {
-				errorJSON(w, http.StatusBadRequest, "invalid due_at, use RFC3339 e.g 2025-08-20T10:00:00Z")
-				return
-			}
-			duePtr = &t
+		duePtr, err := parseDueAt(req.DueAt)
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
 		}
 
 		task, err := taskSvc.CreateTask(
@@ -141,23 +341,14 @@ func UserTasksHandler(w http.ResponseWriter, r *http.Request) {
 			req.Status,
 			int64(req.Priority),
 			duePtr,
+			req.options()...,
 		)
 		if err != nil {
 			respondTaskError(w, err)
 			return
 		}
 
-		writeJSON(w, http.StatusCreated, TaskResponse{
-			ID:          task.ID,
-			UserID:      task.UserID,
-			Title:       task.Title,
-			Description: task.Description,
-			Status:      task.Status,
-			Priority:    task.Priority,
-			DueAt:       task.DueAt,
-			CreatedAt:   task.CreatedAt,
-			UpdatedAt:   task.UpdatedAt,
-		})
+		writeJSON(w, http.StatusCreated, toTaskResponse(task))
 
 	default:
 		w.Header().Set("Allow", "HEAD, GET, POST")
@@ -187,11 +378,16 @@ func UserTaskDetailHandler(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
-		t, err := taskSvc.GetTaskByID(r.Context(), int64(tid))
-		if err != nil || t.UserID != int64(uid) {
+		task, err := taskSvc.GetTaskForUser(r.Context(), int64(uid), int64(tid))
+		if err != nil {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
+		setETag(w, task.Version)
+		if notModified(r, task.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 
 	case http.MethodGet:
@@ -216,23 +412,18 @@ func UserTaskDetailHandler(w http.ResponseWriter, r *http.Request) {
 			errorJSON(w, http.StatusNotFound, "user not found")
 			return
 		}
-		task, err := taskSvc.GetTaskByID(r.Context(), int64(tid))
-		if err != nil || task.UserID != int64(uid) {
+		task, err := taskSvc.GetTaskForUser(r.Context(), int64(uid), int64(tid))
+		if err != nil {
 			errorJSON(w, http.StatusNotFound, "task not found")
 			return
 		}
 
-		writeJSON(w, http.StatusOK, TaskResponse{
-			ID:          task.ID,
-			UserID:      task.UserID,
-			Title:       task.Title,
-			Description: task.Description,
-			Status:      task.Status,
-			Priority:    task.Priority,
-			DueAt:       task.DueAt,
-			CreatedAt:   task.CreatedAt,
-			UpdatedAt:   task.UpdatedAt,
-		})
+		setETag(w, task.Version)
+		if notModified(r, task.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		writeJSON(w, http.StatusOK, toTaskResponse(task))
 
 	case http.MethodPut:
 		ct := r.Header.Get("Content-Type")
@@ -241,26 +432,16 @@ func UserTaskDetailHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		var req struct {
-			Title       string `json:"title"`
-			Description string `json:"description"`
-			Status      string `json:"status"`
-			Priority    int    `json:"priority"`
-			DueAt       string `json:"due_at"`
-		}
+		var req UpdateTaskRequest
 		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
 			respondDecodeError(w, err)
 			return
 		}
 
-		var duePtr *time.Time
-		if s := strings.TrimSpace(req.DueAt); s != "" {
-			t, err := time.Parse(time.RFC3339, s)
-			if err != nil {
-				errorJSON(w, http.StatusBadRequest, "invalid due_at, use RFC3339 e.g 2025-08-20T10:00:00Z")
-				return
-			}
-			duePtr = &t
+		duePtr, err := parseDueAt(req.DueAt)
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
 		}
 
 		uid, tid, perr := parseUserTaskDetailPath(r)
@@ -281,29 +462,26 @@ func UserTaskDetailHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		ifVersion, ok := ifMatch(w, r)
+		if !ok {
+			return
+		}
+
 		updated, err := taskSvc.UpdateTask(
 			r.Context(),
 			int64(uid), int64(tid),
 			req.Title, req.Description, req.Status,
 			int64(req.Priority),
 			duePtr,
+			ifVersion,
 		)
 		if err != nil {
 			respondTaskError(w, err)
 			return
 		}
 
-		writeJSON(w, http.StatusOK, TaskResponse{
-			ID:          updated.ID,
-			UserID:      updated.UserID,
-			Title:       updated.Title,
-			Description: updated.Description,
-			Status:      updated.Status,
-			Priority:    updated.Priority,
-			DueAt:       updated.DueAt,
-			CreatedAt:   updated.CreatedAt,
-			UpdatedAt:   updated.UpdatedAt,
-		})
+		setETag(w, updated.Version)
+		writeJSON(w, http.StatusOK, toTaskResponse(updated))
 
 	case http.MethodPatch:
 		ct := r.Header.Get("Content-Type")
@@ -312,34 +490,16 @@ func UserTaskDetailHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		var req struct {
-			Title       *string `json:"title"`
-			Description *string `json:"description"`
-			Status      *string `json:"status"`
-			Priority    *int    `json:"priority"`
-			DueAt       *string `json:"due_at"`
-		}
+		var req PatchTaskRequest
 		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
 			respondDecodeError(w, err)
 			return
 		}
 
-		var dueAtProvided bool
-		var duePtr *time.Time
-		if req.DueAt == nil {
-			dueAtProvided = false
-		} else if strings.TrimSpace(*req.DueAt) == "" {
-			dueAtProvided = true
-			req.DueAt = nil
-		} else {
-			s := strings.TrimSpace(*req.DueAt)
-			t, err := time.Parse(time.RFC3339, s)
-			if err != nil {
-				errorJSON(w, http.StatusBadRequest, "invalid due_at, use RFC3339 e.g 2025-08-20T10:00:00Z")
-				return
-			}
-			dueAtProvided = true
-			duePtr = &t
+		p, err := req.toPatch()
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
 		}
 
 		uid, tid, perr := parseUserTaskDetailPath(r)
@@ -360,29 +520,30 @@ func UserTaskDetailHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		task, err := taskSvc.PatchTask(
-			r.Context(),
-			int64(uid), int64(tid),
-			req.Title, req.Description, req.Status,
-			req.Priority,
-			dueAtProvided, duePtr,
-		)
+		ifVersion, ok := ifMatch(w, r)
+		if !ok {
+			return
+		}
+
+		patch := taskSvc.PatchTask
+		switch r.URL.Query().Get("scope") {
+		case "", "instance":
+		case "series":
+			patch = taskSvc.PatchSeries
+		default:
+			errorJSON(w, http.StatusBadRequest, "invalid scope, use instance or series")
+			return
+		}
+
+		p.IfVersion = ifVersion
+		task, err := patch(r.Context(), int64(uid), int64(tid), p)
 		if err != nil {
 			respondTaskError(w, err)
 			return
 		}
 
-		writeJSON(w, http.StatusOK, TaskResponse{
-			ID:          task.ID,
-			UserID:      task.UserID,
-			Title:       task.Title,
-			Description: task.Description,
-			Status:      task.Status,
-			Priority:    task.Priority,
-			DueAt:       task.DueAt,
-			CreatedAt:   task.CreatedAt,
-			UpdatedAt:   task.UpdatedAt,
-		})
+		setETag(w, task.Version)
+		writeJSON(w, http.StatusOK, toTaskResponse(task))
 
 	case http.MethodDelete:
 		uid, tid, perr := parseUserTaskDetailPath(r)
@@ -403,7 +564,13 @@ func UserTaskDetailHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := taskSvc.DeleteTaskByUser(r.Context(), int64(uid), int64(tid)); err != nil {
+		ifVersion, ok := ifMatch(w, r)
+		if !ok {
+			return
+		}
+
+		policy := service.ChildPolicy(r.URL.Query().Get("children"))
+		if err := taskSvc.DeleteTaskByUser(r.Context(), int64(uid), int64(tid), policy, ifVersion); err != nil {
 			respondTaskError(w, err)
 			return
 		}
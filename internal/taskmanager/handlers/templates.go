@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TemplateSubtaskJSON struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Priority    int64  `json:"priority"`
+	DueOffset   string `json:"due_offset"`
+}
+
+type TemplateRequest struct {
+	Name        string                `json:"name"`
+	Title       string                `json:"title"`
+	Description string                `json:"description"`
+	Priority    int64                 `json:"priority"`
+	DueOffset   string                `json:"due_offset"`
+	Subtasks    []TemplateSubtaskJSON `json:"subtasks"`
+}
+
+type TemplateResponse struct {
+	ID          int64                 `json:"id"`
+	Name        string                `json:"name"`
+	Title       string                `json:"title"`
+	Description string                `json:"description"`
+	Priority    int64                 `json:"priority"`
+	DueOffset   string                `json:"due_offset"`
+	Subtasks    []TemplateSubtaskJSON `json:"subtasks"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+type TemplateTaskResponse struct {
+	Task     TaskResponse   `json:"task"`
+	Subtasks []TaskResponse `json:"subtasks"`
+}
+
+var templateSvc *service.TemplateService
+
+func SetTemplateService(s *service.TemplateService) { templateSvc = s }
+
+func (req TemplateRequest) toEntity() entity.TaskTemplate {
+	t := entity.TaskTemplate{
+		Name:        req.Name,
+		Title:       req.Title,
+		Description: req.Description,
+		Priority:    req.Priority,
+		DueOffset:   req.DueOffset,
+	}
+	for _, st := range req.Subtasks {
+		t.Subtasks = append(t.Subtasks, entity.TemplateSubtask{
+			Title:       st.Title,
+			Description: st.Description,
+			Priority:    st.Priority,
+			DueOffset:   st.DueOffset,
+		})
+	}
+	return t
+}
+
+func toTemplateResponse(t entity.TaskTemplate) TemplateResponse {
+	resp := TemplateResponse{
+		ID:          t.ID,
+		Name:        t.Name,
+		Title:       t.Title,
+		Description: t.Description,
+		Priority:    t.Priority,
+		DueOffset:   t.DueOffset,
+		Subtasks:    make([]TemplateSubtaskJSON, 0, len(t.Subtasks)),
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+	for _, st := range t.Subtasks {
+		resp.Subtasks = append(resp.Subtasks, TemplateSubtaskJSON{
+			Title:       st.Title,
+			Description: st.Description,
+			Priority:    st.Priority,
+			DueOffset:   st.DueOffset,
+		})
+	}
+	return resp
+}
+
+func respondTemplateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmptyTemplateName):
+		errorJSON(w, http.StatusBadRequest, "empty template name")
+	case errors.Is(err, service.ErrBadTemplateName):
+		errorJSON(w, http.StatusBadRequest, "invalid template name")
+	case errors.Is(err, service.ErrBadTemplate), errors.Is(err, service.ErrBadDueOffset), errors.Is(err, service.ErrMissingVar):
+		errorJSON(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrTemplateNotFound):
+		errorJSON(w, http.StatusNotFound, "template not found")
+	case errors.Is(err, service.ErrTemplateExists):
+		errorJSON(w, http.StatusConflict, "template with this name already exists")
+	default:
+		respondTaskError(w, err)
+	}
+}
+
+func parseUserTemplateDetailPath(r *http.Request) (int, int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !((len(parts) == 5 && parts[1] == "users" && parts[3] == "templates") ||
+		(len(parts) == 6 && parts[1] == "users" && parts[3] == "templates" && parts[5] == "")) {
+		return 0, 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, 0, errBadID
+	}
+	id, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return 0, 0, errBadItemID
+	}
+	return uid, id, nil
+}
+
+// parseUserTemplateTaskPath matches /users/{id}/tasks/from-template/{tplID}.
+func parseUserTemplateTaskPath(r *http.Request) (int, int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !(len(parts) == 6 || (len(parts) == 7 && parts[6] == "")) {
+		return 0, 0, errBadPath
+	}
+	if parts[1] != "users" || parts[3] != "tasks" || parts[4] != "from-template" {
+		return 0, 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, 0, errBadID
+	}
+	id, err := strconv.Atoi(parts[5])
+	if err != nil {
+		return 0, 0, errBadItemID
+	}
+	return uid, id, nil
+}
+
+func UserTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, perr := parseUserSubPath(r, "templates")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+		if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+			errorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		list, err := templateSvc.ListTemplates(r.Context(), int64(uid))
+		if err != nil {
+			respondTemplateError(w, err)
+			return
+		}
+		resp := make([]TemplateResponse, 0, len(list))
+		for _, t := range list {
+			resp = append(resp, toTemplateResponse(t))
+		}
+		writeJSON(w, http.StatusOK, resp)
+
+	case http.MethodPost:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, perr := parseUserSubPath(r, "templates")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+		if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+			errorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		var req TemplateRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		t, err := templateSvc.CreateTemplate(r.Context(), int64(uid), req.toEntity())
+		if err != nil {
+			respondTemplateError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toTemplateResponse(t))
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func UserTemplateDetailHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, id, perr := parseUserTemplateDetailPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		t, err := templateSvc.GetTemplate(r.Context(), int64(uid), int64(id))
+		if err != nil {
+			respondTemplateError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toTemplateResponse(t))
+
+	case http.MethodPut:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, id, perr := parseUserTemplateDetailPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		var req TemplateRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		t, err := templateSvc.UpdateTemplate(r.Context(), int64(uid), int64(id), req.toEntity())
+		if err != nil {
+			respondTemplateError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toTemplateResponse(t))
+
+	case http.MethodDelete:
+		uid, id, perr := parseUserTemplateDetailPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		if err := templateSvc.DeleteTemplate(r.Context(), int64(uid), int64(id)); err != nil {
+			respondTemplateError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// UserTaskFromTemplateHandler serves POST
+// /users/{id}/tasks/from-template/{tplID}. The body carries the values of
+// the template variables and, optionally, the time due offsets count from:
+// {"vars": {"name": "Anna"}, "start": "2025-09-01T09:00:00Z"}.
+func UserTaskFromTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ct := r.Header.Get("Content-Type")
+	if !strings.HasPrefix(ct, "application/json") {
+		errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+
+	uid, id, perr := parseUserTemplateTaskPath(r)
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	var req struct {
+		Vars  map[string]string `json:"vars"`
+		Start *time.Time        `json:"start"`
+	}
+	if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+
+	task, subtasks, err := templateSvc.Instantiate(r.Context(), int64(uid), int64(id), req.Vars, req.Start)
+	if err != nil {
+		respondTemplateError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, TemplateTaskResponse{
+		Task:     toTaskResponse(task),
+		Subtasks: toTaskResponses(subtasks),
+	})
+}
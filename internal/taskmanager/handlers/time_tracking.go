@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TimeEntryRequest struct {
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	Note      string    `json:"note"`
+}
+
+type TimerStartRequest struct {
+	TaskID int64  `json:"task_id"`
+	Note   string `json:"note"`
+}
+
+type TimeEntryResponse struct {
+	ID        int64      `json:"id"`
+	TaskID    int64      `json:"task_id"`
+	UserID    int64      `json:"user_id"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Running   bool       `json:"running"`
+	Seconds   int64      `json:"seconds"`
+	Note      string     `json:"note"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type TimeReportTaskResponse struct {
+	TaskID  int64   `json:"task_id"`
+	Title   string  `json:"title"`
+	Seconds int64   `json:"seconds"`
+	Hours   float64 `json:"hours"`
+}
+
+type TimeReportDayResponse struct {
+	Date    string                   `json:"date"`
+	Seconds int64                    `json:"seconds"`
+	Hours   float64                  `json:"hours"`
+	Tasks   []TimeReportTaskResponse `json:"tasks"`
+}
+
+type TimeReportResponse struct {
+	From     string                   `json:"from"`
+	To       string                   `json:"to"`
+	TimeZone string                   `json:"tz"`
+	Seconds  int64                    `json:"seconds"`
+	Hours    float64                  `json:"hours"`
+	Days     []TimeReportDayResponse  `json:"days"`
+	Tasks    []TimeReportTaskResponse `json:"tasks"`
+}
+
+var timeSvc *service.TimeService
+
+func SetTimeService(s *service.TimeService) { timeSvc = s }
+
+func toTimeEntryResponse(e entity.TimeEntry) TimeEntryResponse {
+	return TimeEntryResponse{
+		ID:        e.ID,
+		TaskID:    e.TaskID,
+		UserID:    e.UserID,
+		StartedAt: e.StartedAt,
+		EndedAt:   e.EndedAt,
+		Running:   e.EndedAt == nil,
+		Seconds:   int64(e.Duration(time.Now()) / time.Second),
+		Note:      e.Note,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+func hours(seconds int64) float64 {
+	return math.Round(float64(seconds)/36) / 100
+}
+
+func toReportTasks(tasks []service.TimeReportTask) []TimeReportTaskResponse {
+	out := make([]TimeReportTaskResponse, 0, len(tasks))
+	for _, t := range tasks {
+		out = append(out, TimeReportTaskResponse{TaskID: t.TaskID, Title: t.Title, Seconds: t.Seconds, Hours: hours(t.Seconds)})
+	}
+	return out
+}
+
+func toTimeReportResponse(rep service.TimeReport) TimeReportResponse {
+	resp := TimeReportResponse{
+		From:     rep.From.Format(time.DateOnly),
+		To:       rep.To.Format(time.DateOnly),
+		TimeZone: rep.Location.String(),
+		Seconds:  rep.Seconds,
+		Hours:    hours(rep.Seconds),
+		Days:     make([]TimeReportDayResponse, 0, len(rep.Days)),
+		Tasks:    toReportTasks(rep.Tasks),
+	}
+	for _, d := range rep.Days {
+		resp.Days = append(resp.Days, TimeReportDayResponse{
+			Date:    d.Day.Format(time.DateOnly),
+			Seconds: d.Seconds,
+			Hours:   hours(d.Seconds),
+			Tasks:   toReportTasks(d.Tasks),
+		})
+	}
+	return resp
+}
+
+func respondTimeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrTimerRunning):
+		errorJSON(w, http.StatusConflict, "a timer is already running, stop it first")
+	case errors.Is(err, service.ErrNoTimer):
+		errorJSON(w, http.StatusNotFound, "no running timer")
+	case errors.Is(err, service.ErrTaskDone):
+		errorJSON(w, http.StatusConflict, "task is done")
+	case errors.Is(err, service.ErrTimeEntryNotFound):
+		errorJSON(w, http.StatusNotFound, "time entry not found")
+	case errors.Is(err, service.ErrBadTimeEntry):
+		errorJSON(w, http.StatusBadRequest, "ended_at must be after started_at and not in the future")
+	case errors.Is(err, service.ErrTimeNoteTooLong):
+		errorJSON(w, http.StatusBadRequest, "note too long")
+	case errors.Is(err, service.ErrBadReportRange):
+		errorJSON(w, http.StatusBadRequest, fmt.Sprintf("from and to must be dates (YYYY-MM-DD), from <= to, at most %d days", service.MaxReportDays))
+	case errors.Is(err, service.ErrBadTimeZone):
+		errorJSON(w, http.StatusBadRequest, "invalid tz, use an IANA name e.g. Europe/Moscow")
+	default:
+		respondTaskError(w, err)
+	}
+}
+
+// parseUserTimerPath matches /users/{id}/timer and /users/{id}/timer/{action}.
+func parseUserTimerPath(r *http.Request) (int, string, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !(len(parts) == 4 || len(parts) == 5 || (len(parts) == 6 && parts[4] != "" && parts[5] == "")) {
+		return 0, "", errBadPath
+	}
+	if parts[1] != "users" || parts[3] != "timer" {
+		return 0, "", errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, "", errBadID
+	}
+	action := ""
+	if len(parts) > 4 {
+		action = parts[4]
+	}
+	return uid, action, nil
+}
+
+// UserTimerHandler shows (GET /timer), starts (POST /timer/start) and
+// stops (POST /timer/stop) the running timer of a user.
+func UserTimerHandler(w http.ResponseWriter, r *http.Request) {
+	uid, action, perr := parseUserTimerPath(r)
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	switch action {
+	case "":
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		e, err := timeSvc.RunningTimer(r.Context(), int64(uid))
+		if err != nil {
+			respondTimeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toTimeEntryResponse(e))
+
+	case "start":
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+		var req TimerStartRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+		e, err := timeSvc.StartTimer(r.Context(), int64(uid), req.TaskID, req.Note)
+		if err != nil {
+			respondTimeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toTimeEntryResponse(e))
+
+	case "stop":
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		e, err := timeSvc.StopTimer(r.Context(), int64(uid))
+		if err != nil {
+			respondTimeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toTimeEntryResponse(e))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func UserTaskTimeEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	uid, tid, perr := parseUserTaskSubPath(r, "time-entries")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := timeSvc.ListEntries(r.Context(), int64(uid), int64(tid))
+		if err != nil {
+			respondTimeError(w, err)
+			return
+		}
+		resp := make([]TimeEntryResponse, 0, len(list))
+		for _, e := range list {
+			resp = append(resp, toTimeEntryResponse(e))
+		}
+		writeJSON(w, http.StatusOK, resp)
+
+	case http.MethodPost:
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+		var req TimeEntryRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+		e, err := timeSvc.AddEntry(r.Context(), int64(uid), int64(tid), req.StartedAt, req.EndedAt, req.Note)
+		if err != nil {
+			respondTimeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toTimeEntryResponse(e))
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func UserTaskTimeEntryDetailHandler(w http.ResponseWriter, r *http.Request) {
+	uid, tid, eid, perr := parseUserTaskSubItemPath(r, "time-entries")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		e, err := timeSvc.GetEntry(r.Context(), int64(uid), int64(tid), int64(eid))
+		if err != nil {
+			respondTimeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toTimeEntryResponse(e))
+
+	case http.MethodPut:
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+		var req TimeEntryRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+		e, err := timeSvc.UpdateEntry(r.Context(), int64(uid), int64(tid), int64(eid), req.StartedAt, req.EndedAt, req.Note)
+		if err != nil {
+			respondTimeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toTimeEntryResponse(e))
+
+	case http.MethodDelete:
+		if err := timeSvc.DeleteEntry(r.Context(), int64(uid), int64(tid), int64(eid)); err != nil {
+			respondTimeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// UserTimeReportHandler sums the time a user tracked by day and by task:
+// GET /users/{id}/time-report?from=YYYY-MM-DD&to=YYYY-MM-DD&tz=Europe/Moscow.
+func UserTimeReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, perr := parseUserSubPath(r, "time-report")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+		errorJSON(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	q := r.URL.Query()
+	rep, err := timeSvc.Report(r.Context(), int64(uid), q.Get("from"), q.Get("to"), q.Get("tz"))
+	if err != nil {
+		respondTimeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toTimeReportResponse(rep))
+}
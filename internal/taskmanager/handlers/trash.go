@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"net/http"
+)
+
+func UserTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, perr := parseUserSubPath(r, "trash")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+	if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+		errorJSON(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	tasks, err := taskSvc.ListTrash(r.Context(), int64(uid))
+	if err != nil {
+		respondTaskError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toTaskResponses(tasks))
+}
+
+func UserTaskRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, tid, perr := parseUserTaskSubPath(r, "restore")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	task, err := taskSvc.RestoreTask(r.Context(), int64(uid), int64(tid))
+	if err != nil {
+		respondTaskError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toTaskResponse(task))
+}
+
+func UserRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, perr := parseUserSubPath(r, "restore")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	user, err := userSvc.RestoreUserByID(r.Context(), int64(uid))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			errorJSON(w, http.StatusNotFound, "user not found")
+		case errors.Is(err, service.ErrUserTaken):
+			errorJSON(w, http.StatusConflict, "username or email is already taken")
+		default:
+			errorJSON(w, http.StatusInternalServerError, "internal error")
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, UserResponse{ID: user.ID, Name: user.Username, Email: user.Email})
+}
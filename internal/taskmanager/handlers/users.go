@@ -114,11 +114,16 @@ func UserDetailHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		ctx := r.Context()
-		_, err := userSvc.GetUserByID(ctx, int64(id))
+		u, err := userSvc.GetUserByID(ctx, int64(id))
 		if err != nil {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
+		setETag(w, u.Version)
+		if notModified(r, u.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 
 	case http.MethodGet:
@@ -142,6 +147,11 @@ func UserDetailHandler(w http.ResponseWriter, r *http.Request) {
 			errorJSON(w, http.StatusNotFound, "user not found")
 			return
 		}
+		setETag(w, u.Version)
+		if notModified(r, u.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 		writeJSON(w, http.StatusOK, UserResponse{ID: u.ID, Name: u.Username, Email: u.Email})
 		return
 
@@ -172,12 +182,19 @@ func UserDetailHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		ifVersion, ok := ifMatch(w, r)
+		if !ok {
+			return
+		}
+
 		ctx := r.Context()
-		u, err := userSvc.UpdateUserByID(ctx, int64(id), req.Name, req.Email)
+		u, err := userSvc.UpdateUserByID(ctx, int64(id), req.Name, req.Email, ifVersion)
 		if err != nil {
 			switch {
 			case errors.Is(err, service2.ErrUserNotFound), errors.Is(err, sql.ErrNoRows):
 				errorJSON(w, http.StatusNotFound, "user not found")
+			case errors.Is(err, service2.ErrVersionMismatch):
+				errorJSON(w, http.StatusPreconditionFailed, "user was modified, reload it and retry")
 			case errors.Is(err, service2.ErrEmptyName), errors.Is(err, service2.ErrEmptyEmail):
 				errorJSON(w, http.StatusBadRequest, err.Error())
 			default:
@@ -186,6 +203,7 @@ func UserDetailHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		setETag(w, u.Version)
 		writeJSON(w, http.StatusOK, UserResponse{ID: u.ID, Name: u.Username, Email: u.Email})
 		return
 
@@ -224,12 +242,19 @@ func UserDetailHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		ifVersion, ok := ifMatch(w, r)
+		if !ok {
+			return
+		}
+
 		ctx := r.Context()
-		u, err := userSvc.PatchUserByID(ctx, int64(id), req.Name, req.Email)
+		u, err := userSvc.PatchUserByID(ctx, int64(id), req.Name, req.Email, ifVersion)
 		if err != nil {
 			switch {
 			case errors.Is(err, service2.ErrUserNotFound), errors.Is(err, sql.ErrNoRows):
 				errorJSON(w, http.StatusNotFound, "user not found")
+			case errors.Is(err, service2.ErrVersionMismatch):
+				errorJSON(w, http.StatusPreconditionFailed, "user was modified, reload it and retry")
 			case errors.Is(err, service2.ErrEmptyName), errors.Is(err, service2.ErrEmptyEmail):
 				errorJSON(w, http.StatusBadRequest, err.Error())
 			default:
@@ -238,6 +263,7 @@ func UserDetailHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		setETag(w, u.Version)
 		writeJSON(w, http.StatusOK, UserResponse{ID: u.ID, Name: u.Username, Email: u.Email})
 		return
 
@@ -256,11 +282,18 @@ func UserDetailHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		ifVersion, ok := ifMatch(w, r)
+		if !ok {
+			return
+		}
+
 		ctx := r.Context()
-		if err := userSvc.DeleteUserByID(ctx, int64(id)); err != nil {
+		if err := userSvc.DeleteUserByID(ctx, int64(id), ifVersion); err != nil {
 			switch {
 			case errors.Is(err, service2.ErrUserNotFound), errors.Is(err, sql.ErrNoRows):
 				errorJSON(w, http.StatusNotFound, "user not found")
+			case errors.Is(err, service2.ErrVersionMismatch):
+				errorJSON(w, http.StatusPreconditionFailed, "user was modified, reload it and retry")
 			default:
 				errorJSON(w, http.StatusInternalServerError, "internal error")
 			}
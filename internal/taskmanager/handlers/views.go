@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ViewRequest struct {
+	Name   string             `json:"name"`
+	Filter service.ViewFilter `json:"filter"`
+}
+
+type ViewResponse struct {
+	ID            int64              `json:"id"`
+	OwnerID       int64              `json:"owner_id"`
+	Name          string             `json:"name"`
+	Filter        service.ViewFilter `json:"filter"`
+	FilterVersion int                `json:"filter_version"`
+	SharedWith    []int64            `json:"shared_with,omitempty"`
+	Default       bool               `json:"default"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+var viewSvc *service.ViewService
+
+func SetViewService(s *service.ViewService) { viewSvc = s }
+
+// toViewResponse shows the list of users a view is shared with to its
+// owner only.
+func toViewResponse(v service.View, uid int64) ViewResponse {
+	resp := ViewResponse{
+		ID:            v.ID,
+		OwnerID:       v.UserID,
+		Name:          v.Name,
+		Filter:        v.Filter,
+		FilterVersion: v.FilterVersion,
+		Default:       v.Default,
+		CreatedAt:     v.CreatedAt,
+		UpdatedAt:     v.UpdatedAt,
+	}
+	if v.UserID == uid {
+		resp.SharedWith = v.SharedWith
+	}
+	return resp
+}
+
+func respondViewError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmptyViewName):
+		errorJSON(w, http.StatusBadRequest, "empty view name")
+	case errors.Is(err, service.ErrBadViewName):
+		errorJSON(w, http.StatusBadRequest, "invalid view name")
+	case errors.Is(err, service.ErrBadViewFilter):
+		errorJSON(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrViewNotFound):
+		errorJSON(w, http.StatusNotFound, "view not found")
+	case errors.Is(err, service.ErrViewExists):
+		errorJSON(w, http.StatusConflict, "view with this name already exists")
+	case errors.Is(err, service.ErrNotViewOwner):
+		errorJSON(w, http.StatusForbidden, "only the view owner can do this")
+	case errors.Is(err, service.ErrBadShare):
+		errorJSON(w, http.StatusBadRequest, "cannot share a view with its owner")
+	case errors.Is(err, service.ErrNotShared):
+		errorJSON(w, http.StatusNotFound, "view is not shared with this user")
+	case errors.Is(err, service.ErrNoDefaultView):
+		errorJSON(w, http.StatusNotFound, "no default view")
+	default:
+		respondTaskError(w, err)
+	}
+}
+
+// parseUserViewPath matches /users/{id}/views/{vid} followed by the given
+// path segments.
+func parseUserViewPath(r *http.Request, sub ...string) (int, int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+	n := 5 + len(sub)
+
+	if !(len(parts) == n || (len(parts) == n+1 && parts[n] == "")) {
+		return 0, 0, errBadPath
+	}
+	if parts[1] != "users" || parts[3] != "views" {
+		return 0, 0, errBadPath
+	}
+	for i, s := range sub {
+		if parts[5+i] != s {
+			return 0, 0, errBadPath
+		}
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, 0, errBadID
+	}
+	id, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return 0, 0, errBadItemID
+	}
+	return uid, id, nil
+}
+
+// parseUserViewSharePath matches /users/{id}/views/{vid}/shares/{user_id}.
+func parseUserViewSharePath(r *http.Request) (int, int, int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !(len(parts) == 7 || (len(parts) == 8 && parts[7] == "")) {
+		return 0, 0, 0, errBadPath
+	}
+	if parts[1] != "users" || parts[3] != "views" || parts[5] != "shares" {
+		return 0, 0, 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, 0, 0, errBadID
+	}
+	id, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return 0, 0, 0, errBadItemID
+	}
+	with, err := strconv.Atoi(parts[6])
+	if err != nil {
+		return 0, 0, 0, errBadItemID
+	}
+	return uid, id, with, nil
+}
+
+func parseUserDefaultViewPath(r *http.Request) (int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !(len(parts) == 5 || (len(parts) == 6 && parts[5] == "")) {
+		return 0, errBadPath
+	}
+	if parts[1] != "users" || parts[3] != "views" || parts[4] != "default" {
+		return 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, errBadID
+	}
+	return uid, nil
+}
+
+func UserViewsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, perr := parseUserSubPath(r, "views")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+		if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+			errorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		list, err := viewSvc.ListViews(r.Context(), int64(uid))
+		if err != nil {
+			respondViewError(w, err)
+			return
+		}
+		resp := make([]ViewResponse, 0, len(list))
+		for _, v := range list {
+			resp = append(resp, toViewResponse(v, int64(uid)))
+		}
+		writeJSON(w, http.StatusOK, resp)
+
+	case http.MethodPost:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, perr := parseUserSubPath(r, "views")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+		if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+			errorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		var req ViewRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		v, err := viewSvc.CreateView(r.Context(), int64(uid), req.Name, req.Filter)
+		if err != nil {
+			respondViewError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toViewResponse(v, int64(uid)))
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func UserViewDetailHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, id, perr := parseUserViewPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		v, err := viewSvc.GetView(r.Context(), int64(uid), int64(id))
+		if err != nil {
+			respondViewError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toViewResponse(v, int64(uid)))
+
+	case http.MethodPut:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, id, perr := parseUserViewPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		var req ViewRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		v, err := viewSvc.UpdateView(r.Context(), int64(uid), int64(id), req.Name, req.Filter)
+		if err != nil {
+			respondViewError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toViewResponse(v, int64(uid)))
+
+	case http.MethodDelete:
+		uid, id, perr := parseUserViewPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		if err := viewSvc.DeleteView(r.Context(), int64(uid), int64(id)); err != nil {
+			respondViewError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// UserViewTasksHandler lists the user's tasks that match a view. Only
+// limit and cursor are taken from the query string.
+func UserViewTasksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, id, perr := parseUserViewPath(r, "tasks")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	q, err := parseTaskListQuery(r)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := viewSvc.ViewTasks(r.Context(), int64(uid), int64(id), q.Limit, q.Cursor)
+	if err != nil {
+		respondViewError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toTaskListResponse(page))
+}
+
+// UserViewShareHandler shares a view with another user (PUT) or stops
+// sharing it (DELETE).
+func UserViewShareHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPut:
+		uid, id, with, perr := parseUserViewSharePath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		if err := viewSvc.ShareView(r.Context(), int64(uid), int64(id), int64(with)); err != nil {
+			respondViewError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		uid, id, with, perr := parseUserViewSharePath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		if err := viewSvc.UnshareView(r.Context(), int64(uid), int64(id), int64(with)); err != nil {
+			respondViewError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "PUT, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func UserDefaultViewHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, perr := parseUserDefaultViewPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		v, err := viewSvc.DefaultView(r.Context(), int64(uid))
+		if err != nil {
+			respondViewError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toViewResponse(v, int64(uid)))
+
+	case http.MethodPut:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, perr := parseUserDefaultViewPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		var req struct {
+			ViewID int64 `json:"view_id"`
+		}
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		v, err := viewSvc.SetDefaultView(r.Context(), int64(uid), req.ViewID)
+		if err != nil {
+			respondViewError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toViewResponse(v, int64(uid)))
+
+	case http.MethodDelete:
+		uid, perr := parseUserDefaultViewPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		if err := viewSvc.ClearDefaultView(r.Context(), int64(uid)); err != nil {
+			respondViewError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
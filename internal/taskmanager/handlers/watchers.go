@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"net/http"
+	"strings"
+)
+
+type WatcherResponse struct {
+	TaskID   int64 `json:"task_id"`
+	UserID   int64 `json:"user_id"`
+	Implicit bool  `json:"implicit"`
+	Muted    bool  `json:"muted"`
+}
+
+var watcherSvc *service.WatcherService
+
+func SetWatcherService(s *service.WatcherService) { watcherSvc = s }
+
+func toWatcherResponse(w entity.Watcher) WatcherResponse {
+	return WatcherResponse{TaskID: w.TaskID, UserID: w.UserID, Implicit: w.Implicit, Muted: w.Muted}
+}
+
+func respondWatcherError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotWatching):
+		errorJSON(w, http.StatusNotFound, "not watching the task")
+	default:
+		respondTaskError(w, err)
+	}
+}
+
+// UserTaskWatchersHandler serves /users/{id}/tasks/{tid}/watchers, where
+// {id} is the acting user. GET lists the watchers, PUT starts watching or
+// sets {"muted": true|false}, DELETE stops watching. Only users who can see
+// the task may do any of it: its owner, its assignee and the members of
+// its project; anyone else gets 404.
+func UserTaskWatchersHandler(w http.ResponseWriter, r *http.Request) {
+	uid, tid, perr := parseUserTaskSubPath(r, "watchers")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := watcherSvc.ListWatchers(r.Context(), int64(uid), int64(tid))
+		if err != nil {
+			respondWatcherError(w, err)
+			return
+		}
+		out := make([]WatcherResponse, 0, len(list))
+		for _, wt := range list {
+			out = append(out, toWatcherResponse(wt))
+		}
+		writeJSON(w, http.StatusOK, out)
+
+	case http.MethodPut:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+		var req struct {
+			Muted bool `json:"muted"`
+		}
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		wt, err := watcherSvc.Watch(r.Context(), int64(uid), int64(tid), req.Muted)
+		if err != nil {
+			respondWatcherError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toWatcherResponse(wt))
+
+	case http.MethodDelete:
+		if err := watcherSvc.Unwatch(r.Context(), int64(uid), int64(tid)); err != nil {
+			respondWatcherError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
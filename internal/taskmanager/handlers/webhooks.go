@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+type WebhookResponse struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type DeliveryResponse struct {
+	ID            int64           `json:"id"`
+	WebhookID     int64           `json:"webhook_id"`
+	EventID       string          `json:"event_id"`
+	Event         string          `json:"event"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	ResponseCode  *int            `json:"response_code"`
+	LastError     string          `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at"`
+	RedeliveryOf  *int64          `json:"redelivery_of"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	Log           []AttemptJSON   `json:"log,omitempty"`
+}
+
+type AttemptJSON struct {
+	Attempt      int       `json:"attempt"`
+	ResponseCode *int      `json:"response_code"`
+	Error        string    `json:"error,omitempty"`
+	DurationMS   int64     `json:"duration_ms"`
+	AttemptedAt  time.Time `json:"attempted_at"`
+}
+
+var webhookSvc *service.WebhookService
+
+func SetWebhookService(s *service.WebhookService) { webhookSvc = s }
+
+// toWebhookResponse leaves the secret out unless withSecret is set, which
+// only happens right after it was created.
+func toWebhookResponse(w entity.Webhook, withSecret bool) WebhookResponse {
+	resp := WebhookResponse{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.Events,
+		Active:    w.Active,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+	if withSecret {
+		resp.Secret = w.Secret
+	}
+	return resp
+}
+
+func toDeliveryResponse(d entity.WebhookDelivery) DeliveryResponse {
+	return DeliveryResponse{
+		ID:            d.ID,
+		WebhookID:     d.WebhookID,
+		EventID:       d.EventID,
+		Event:         d.EventType,
+		Status:        d.Status,
+		Attempts:      d.Attempts,
+		ResponseCode:  d.ResponseCode,
+		LastError:     d.LastError,
+		NextAttemptAt: d.NextAttemptAt,
+		RedeliveryOf:  d.RedeliveryOf,
+		CreatedAt:     d.CreatedAt,
+		UpdatedAt:     d.UpdatedAt,
+	}
+}
+
+func respondWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrBadWebhookURL), errors.Is(err, service.ErrBadWebhookEvents), errors.Is(err, service.ErrBadWebhookSecret):
+		errorJSON(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrWebhookNotFound):
+		errorJSON(w, http.StatusNotFound, "webhook not found")
+	case errors.Is(err, service.ErrDeliveryNotFound):
+		errorJSON(w, http.StatusNotFound, "delivery not found")
+	default:
+		respondTaskError(w, err)
+	}
+}
+
+// parseUserWebhookPath matches /users/{id}/webhooks/{wid} followed by sub,
+// where an empty element stands for a delivery id.
+func parseUserWebhookPath(r *http.Request, sub ...string) (uid, id, did int, err error) {
+	parts := strings.Split(r.URL.Path, "/")
+	n := 5 + len(sub)
+
+	if !(len(parts) == n || (len(parts) == n+1 && parts[n] == "")) {
+		return 0, 0, 0, errBadPath
+	}
+	if parts[1] != "users" || parts[3] != "webhooks" {
+		return 0, 0, 0, errBadPath
+	}
+
+	if uid, err = strconv.Atoi(parts[2]); err != nil {
+		return 0, 0, 0, errBadID
+	}
+	if id, err = strconv.Atoi(parts[4]); err != nil {
+		return 0, 0, 0, errBadItemID
+	}
+	for i, s := range sub {
+		switch {
+		case s == "":
+			if did, err = strconv.Atoi(parts[5+i]); err != nil {
+				return 0, 0, 0, errBadItemID
+			}
+		case parts[5+i] != s:
+			return 0, 0, 0, errBadPath
+		}
+	}
+	return uid, id, did, nil
+}
+
+func UserWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, perr := parseUserSubPath(r, "webhooks")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+		if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+			errorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		list, err := webhookSvc.ListWebhooks(r.Context(), int64(uid))
+		if err != nil {
+			respondWebhookError(w, err)
+			return
+		}
+		resp := make([]WebhookResponse, 0, len(list))
+		for _, wh := range list {
+			resp = append(resp, toWebhookResponse(wh, false))
+		}
+		writeJSON(w, http.StatusOK, resp)
+
+	case http.MethodPost:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, perr := parseUserSubPath(r, "webhooks")
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+		if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+			errorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		var req WebhookRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		wh, err := webhookSvc.CreateWebhook(r.Context(), int64(uid), req.URL, req.Events, req.Secret)
+		if err != nil {
+			respondWebhookError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toWebhookResponse(wh, true))
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func UserWebhookDetailHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, id, _, perr := parseUserWebhookPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		wh, err := webhookSvc.GetWebhook(r.Context(), int64(uid), int64(id))
+		if err != nil {
+			respondWebhookError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toWebhookResponse(wh, false))
+
+	case http.MethodPut:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, id, _, perr := parseUserWebhookPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		var req WebhookRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+		active := true
+		if req.Active != nil {
+			active = *req.Active
+		}
+
+		wh, err := webhookSvc.UpdateWebhook(r.Context(), int64(uid), int64(id), req.URL, req.Events, req.Secret, active)
+		if err != nil {
+			respondWebhookError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toWebhookResponse(wh, false))
+
+	case http.MethodDelete:
+		uid, id, _, perr := parseUserWebhookPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		if err := webhookSvc.DeleteWebhook(r.Context(), int64(uid), int64(id)); err != nil {
+			respondWebhookError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func UserWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, id, _, perr := parseUserWebhookPath(r, "deliveries")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	list, err := webhookSvc.ListDeliveries(r.Context(), int64(uid), int64(id))
+	if err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+	resp := make([]DeliveryResponse, 0, len(list))
+	for _, d := range list {
+		resp = append(resp, toDeliveryResponse(d))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// UserWebhookDeliveryDetailHandler returns a delivery with its payload and
+// the log of its attempts.
+func UserWebhookDeliveryDetailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, id, did, perr := parseUserWebhookPath(r, "deliveries", "")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	d, attempts, err := webhookSvc.GetDelivery(r.Context(), int64(uid), int64(id), int64(did))
+	if err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+	resp := toDeliveryResponse(d)
+	resp.Payload = d.Payload
+	resp.Log = make([]AttemptJSON, 0, len(attempts))
+	for _, a := range attempts {
+		resp.Log = append(resp.Log, AttemptJSON{
+			Attempt:      a.Attempt,
+			ResponseCode: a.ResponseCode,
+			Error:        a.Error,
+			DurationMS:   a.Duration.Milliseconds(),
+			AttemptedAt:  a.AttemptedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// UserWebhookRedeliverHandler serves POST
+// /users/{id}/webhooks/{wid}/deliveries/{did}/redeliver. The replay is
+// queued as a new delivery, so the response is 202.
+func UserWebhookRedeliverHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uid, id, did, perr := parseUserWebhookPath(r, "deliveries", "", "redeliver")
+	if perr != nil {
+		respondPathError(w, r, perr)
+		return
+	}
+
+	d, err := webhookSvc.Redeliver(r.Context(), int64(uid), int64(id), int64(did))
+	if err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, toDeliveryResponse(d))
+}
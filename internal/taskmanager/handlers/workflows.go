@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type WorkflowTransitionJSON struct {
+	From                string `json:"from"`
+	To                  string `json:"to"`
+	RequiresDescription bool   `json:"requires_description"`
+	RequiresAssignee    bool   `json:"requires_assignee"`
+}
+
+type WorkflowRequest struct {
+	Name         string                   `json:"name"`
+	InitialState string                   `json:"initial_state"`
+	States       []string                 `json:"states"`
+	Transitions  []WorkflowTransitionJSON `json:"transitions"`
+}
+
+type WorkflowResponse struct {
+	ID           int64                    `json:"id"`
+	Name         string                   `json:"name"`
+	InitialState string                   `json:"initial_state"`
+	States       []string                 `json:"states"`
+	Transitions  []WorkflowTransitionJSON `json:"transitions"`
+	CreatedAt    time.Time                `json:"created_at"`
+	UpdatedAt    time.Time                `json:"updated_at"`
+}
+
+var workflowSvc *service.WorkflowService
+
+func SetWorkflowService(s *service.WorkflowService) { workflowSvc = s }
+
+func (req WorkflowRequest) toEntity() entity.Workflow {
+	wf := entity.Workflow{
+		Name:         req.Name,
+		InitialState: req.InitialState,
+		States:       req.States,
+	}
+	for _, tr := range req.Transitions {
+		wf.Transitions = append(wf.Transitions, entity.WorkflowTransition{
+			From:                tr.From,
+			To:                  tr.To,
+			RequiresDescription: tr.RequiresDescription,
+			RequiresAssignee:    tr.RequiresAssignee,
+		})
+	}
+	return wf
+}
+
+func toWorkflowResponse(wf entity.Workflow) WorkflowResponse {
+	resp := WorkflowResponse{
+		ID:           wf.ID,
+		Name:         wf.Name,
+		InitialState: wf.InitialState,
+		States:       wf.States,
+		Transitions:  make([]WorkflowTransitionJSON, 0, len(wf.Transitions)),
+		CreatedAt:    wf.CreatedAt,
+		UpdatedAt:    wf.UpdatedAt,
+	}
+	if resp.States == nil {
+		resp.States = []string{}
+	}
+	for _, tr := range wf.Transitions {
+		resp.Transitions = append(resp.Transitions, WorkflowTransitionJSON{
+			From:                tr.From,
+			To:                  tr.To,
+			RequiresDescription: tr.RequiresDescription,
+			RequiresAssignee:    tr.RequiresAssignee,
+		})
+	}
+	return resp
+}
+
+func respondWorkflowError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmptyWorkflowName):
+		errorJSON(w, http.StatusBadRequest, "empty workflow name")
+	case errors.Is(err, service.ErrBadWorkflowName):
+		errorJSON(w, http.StatusBadRequest, "invalid workflow name")
+	case errors.Is(err, service.ErrBadWorkflowDef):
+		errorJSON(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrWorkflowNotFound):
+		errorJSON(w, http.StatusNotFound, "workflow not found")
+	case errors.Is(err, service.ErrWorkflowExists):
+		errorJSON(w, http.StatusConflict, "workflow with this name already exists")
+	case errors.Is(err, service.ErrWorkflowInUse):
+		errorJSON(w, http.StatusConflict, "workflow is used by tasks")
+	case errors.Is(err, service.ErrStateInUse):
+		errorJSON(w, http.StatusConflict, "cannot remove a state that tasks are in")
+	default:
+		errorJSON(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+func parseUserWorkflowsPath(r *http.Request) (int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !(len(parts) == 4 || (len(parts) == 5 && parts[4] == "")) {
+		return 0, errBadPath
+	}
+	if parts[1] != "users" || parts[3] != "workflows" {
+		return 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, errBadID
+	}
+	return uid, nil
+}
+
+func parseUserWorkflowDetailPath(r *http.Request) (int, int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if !((len(parts) == 5 && parts[1] == "users" && parts[3] == "workflows") ||
+		(len(parts) == 6 && parts[1] == "users" && parts[3] == "workflows" && parts[5] == "")) {
+		return 0, 0, errBadPath
+	}
+
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, 0, errBadID
+	}
+	wid, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return 0, 0, errBadItemID
+	}
+	return uid, wid, nil
+}
+
+func UserWorkflowsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, perr := parseUserWorkflowsPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+		if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+			errorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		list, err := workflowSvc.ListWorkflows(r.Context(), int64(uid))
+		if err != nil {
+			respondWorkflowError(w, err)
+			return
+		}
+		resp := make([]WorkflowResponse, 0, len(list))
+		for _, wf := range list {
+			resp = append(resp, toWorkflowResponse(wf))
+		}
+		writeJSON(w, http.StatusOK, resp)
+
+	case http.MethodPost:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, perr := parseUserWorkflowsPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+		if _, err := userSvc.GetUserByID(r.Context(), int64(uid)); err != nil {
+			errorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		var req WorkflowRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		wf, err := workflowSvc.CreateWorkflow(r.Context(), int64(uid), req.toEntity())
+		if err != nil {
+			respondWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toWorkflowResponse(wf))
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func UserWorkflowDetailHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		uid, wid, perr := parseUserWorkflowDetailPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		wf, err := workflowSvc.GetWorkflow(r.Context(), int64(uid), int64(wid))
+		if err != nil {
+			respondWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toWorkflowResponse(wf))
+
+	case http.MethodPut:
+		ct := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(ct, "application/json") {
+			errorJSON(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+
+		uid, wid, perr := parseUserWorkflowDetailPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		var req WorkflowRequest
+		if err := decodeJSON(w, r, &req, 1<<20); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+
+		wf, err := workflowSvc.UpdateWorkflow(r.Context(), int64(uid), int64(wid), req.toEntity())
+		if err != nil {
+			respondWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toWorkflowResponse(wf))
+
+	case http.MethodDelete:
+		uid, wid, perr := parseUserWorkflowDetailPath(r)
+		if perr != nil {
+			respondPathError(w, r, perr)
+			return
+		}
+
+		if err := workflowSvc.DeleteWorkflow(r.Context(), int64(uid), int64(wid)); err != nil {
+			respondWorkflowError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		errorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,170 @@
+// Package ical writes the subset of iCalendar (RFC 5545) needed to publish
+// tasks as a subscribable feed: a VCALENDAR holding VTODO or VEVENT
+// components, with text escaping and 75-octet line folding.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Component selects how an Item is published.
+type Component string
+
+const (
+	Todo  Component = "VTODO"
+	Event Component = "VEVENT"
+)
+
+// Status values of VTODO.
+const (
+	NeedsAction = "NEEDS-ACTION"
+	InProcess   = "IN-PROCESS"
+	Completed   = "COMPLETED"
+)
+
+const (
+	maxLineOctets = 75
+	timeLayout    = "20060102T150405Z"
+)
+
+// Item is one entry of the feed. UID must stay the same across polls so
+// that clients update the entry instead of adding a copy; Sequence should
+// grow on every change. Status is only written for VTODO, since VEVENT has
+// no status that fits a task.
+type Item struct {
+	UID         string
+	Summary     string
+	Description string
+	Status      string
+	Priority    int
+	Due         time.Time
+	Stamp       time.Time
+	Sequence    int64
+	Categories  []string
+}
+
+// Encoder writes a calendar to an io.Writer. Errors are sticky: after the
+// first failed write every call is a no-op and Close reports the error.
+type Encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewEncoder starts a calendar; name is shown by clients as its title.
+func NewEncoder(w io.Writer, prodID, name string) *Encoder {
+	e := &Encoder{w: bufio.NewWriter(w)}
+	e.line("BEGIN:VCALENDAR")
+	e.line("VERSION:2.0")
+	e.line("PRODID:" + prodID)
+	e.line("CALSCALE:GREGORIAN")
+	e.line("METHOD:PUBLISH")
+	if name != "" {
+		e.line("X-WR-CALNAME:" + Escape(name))
+	}
+	return e
+}
+
+// Encode writes it as a c component.
+func (e *Encoder) Encode(c Component, it Item) error {
+	e.line("BEGIN:" + string(c))
+	e.line("UID:" + Escape(it.UID))
+	e.line("DTSTAMP:" + FormatTime(it.Stamp))
+	e.line("LAST-MODIFIED:" + FormatTime(it.Stamp))
+	e.line("SEQUENCE:" + strconv.FormatInt(it.Sequence, 10))
+	e.line("SUMMARY:" + Escape(it.Summary))
+	if it.Description != "" {
+		e.line("DESCRIPTION:" + Escape(it.Description))
+	}
+	if c == Todo {
+		e.line("DUE:" + FormatTime(it.Due))
+		if it.Status != "" {
+			e.line("STATUS:" + it.Status)
+		}
+		if it.Status == Completed {
+			e.line("COMPLETED:" + FormatTime(it.Stamp))
+		}
+	} else {
+		e.line("DTSTART:" + FormatTime(it.Due))
+		e.line("TRANSP:TRANSPARENT")
+	}
+	if it.Priority > 0 {
+		e.line("PRIORITY:" + strconv.Itoa(it.Priority))
+	}
+	if len(it.Categories) > 0 {
+		cats := make([]string, 0, len(it.Categories))
+		for _, cat := range it.Categories {
+			cats = append(cats, Escape(cat))
+		}
+		e.line("CATEGORIES:" + strings.Join(cats, ","))
+	}
+	e.line("END:" + string(c))
+	return e.err
+}
+
+// Flush pushes buffered output to the underlying writer.
+func (e *Encoder) Flush() error {
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+// Close ends the calendar and flushes it.
+func (e *Encoder) Close() error {
+	e.line("END:VCALENDAR")
+	return e.Flush()
+}
+
+func (e *Encoder) line(s string) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.WriteString(Fold(s) + "\r\n")
+}
+
+// FormatTime renders t as a UTC DATE-TIME.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// Escape escapes a TEXT value.
+func Escape(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// Fold splits a content line into lines of at most 75 octets, never inside
+// a UTF-8 sequence; continuation lines start with a space.
+func Fold(s string) string {
+	if len(s) <= maxLineOctets {
+		return s
+	}
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// The leading space counts towards the next line.
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscape(t *testing.T) {
+	got := Escape("a,b;c\\d\ne")
+	want := `a\,b\;c\\d\ne`
+	if got != want {
+		t.Errorf("Escape: got %q, want %q", got, want)
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"short", "SUMMARY:short"},
+		{"ascii", "DESCRIPTION:" + strings.Repeat("x", 200)},
+		{"multibyte", "SUMMARY:" + strings.Repeat("задача ", 40)},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := Fold(tt.in)
+			for i, line := range strings.Split(got, "\r\n") {
+				if len(line) > maxLineOctets {
+					t.Errorf("line %d has %d octets", i, len(line))
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("continuation line %d does not start with a space", i)
+				}
+			}
+			if unfolded := strings.ReplaceAll(got, "\r\n ", ""); unfolded != tt.in {
+				t.Errorf("unfolding does not restore the line: %q", unfolded)
+			}
+		})
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	due := time.Date(2025, 9, 5, 18, 0, 0, 0, time.FixedZone("MSK", 3*3600))
+	stamp := time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	enc := NewEncoder(&buf, "-//test//EN", "Tasks")
+	item := Item{UID: "task-7@test", Summary: "Report, final", Status: Completed, Priority: 1, Due: due, Stamp: stamp, Sequence: 3}
+	if err := enc.Encode(Todo, item); err != nil {
+		t.Fatalf("encode todo: %v", err)
+	}
+	if err := enc.Encode(Event, item); err != nil {
+		t.Fatalf("encode event: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"BEGIN:VTODO\r\nUID:task-7@test\r\n",
+		"DUE:20250905T150000Z\r\n",
+		"STATUS:COMPLETED\r\n",
+		"BEGIN:VEVENT\r\n",
+		"DTSTART:20250905T150000Z\r\n",
+		"SUMMARY:Report\\, final\r\n",
+		"SEQUENCE:3\r\n",
+		"PRIORITY:1\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "STATUS:") != 1 {
+		t.Errorf("STATUS must only be written for VTODO:\n%s", out)
+	}
+}
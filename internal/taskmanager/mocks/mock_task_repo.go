@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
 	reflect "reflect"
 	time "time"
 
@@ -36,6 +37,35 @@ func (m *MockTaskRepository) EXPECT() *MockTaskRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddDependency mocks base method.
+func (m *MockTaskRepository) AddDependency(ctx context.Context, taskID, blockedByID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDependency", ctx, taskID, blockedByID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddDependency indicates an expected call of AddDependency.
+func (mr *MockTaskRepositoryMockRecorder) AddDependency(ctx, taskID, blockedByID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDependency", reflect.TypeOf((*MockTaskRepository)(nil).AddDependency), ctx, taskID, blockedByID)
+}
+
+// Assign mocks base method.
+func (m *MockTaskRepository) Assign(ctx context.Context, id int64, assigneeID *int64) (entity.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Assign", ctx, id, assigneeID)
+	ret0, _ := ret[0].(entity.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Assign indicates an expected call of Assign.
+func (mr *MockTaskRepositoryMockRecorder) Assign(ctx, id, assigneeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Assign", reflect.TypeOf((*MockTaskRepository)(nil).Assign), ctx, id, assigneeID)
+}
+
 // Create mocks base method.
 func (m *MockTaskRepository) Create(ctx context.Context, task *entity.Task) error {
 	m.ctrl.T.Helper()
@@ -51,17 +81,75 @@ func (mr *MockTaskRepositoryMockRecorder) Create(ctx, task interface{}) *gomock.
 }
 
 // Delete mocks base method.
-func (m *MockTaskRepository) Delete(ctx context.Context, id int64) error {
+func (m *MockTaskRepository) Delete(ctx context.Context, id int64, ifVersion *int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id, ifVersion)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockTaskRepositoryMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+func (mr *MockTaskRepositoryMockRecorder) Delete(ctx, id, ifVersion interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTaskRepository)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTaskRepository)(nil).Delete), ctx, id, ifVersion)
+}
+
+// DeleteSeries mocks base method.
+func (m *MockTaskRepository) DeleteSeries(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSeries", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSeries indicates an expected call of DeleteSeries.
+func (mr *MockTaskRepositoryMockRecorder) DeleteSeries(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSeries", reflect.TypeOf((*MockTaskRepository)(nil).DeleteSeries), ctx, id)
+}
+
+// EachByUserID mocks base method.
+func (m *MockTaskRepository) EachByUserID(ctx context.Context, userID int64, fn func(entity.Task) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EachByUserID", ctx, userID, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EachByUserID indicates an expected call of EachByUserID.
+func (mr *MockTaskRepositoryMockRecorder) EachByUserID(ctx, userID, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EachByUserID", reflect.TypeOf((*MockTaskRepository)(nil).EachByUserID), ctx, userID, fn)
+}
+
+// GetBlocked mocks base method.
+func (m *MockTaskRepository) GetBlocked(ctx context.Context, taskID int64) ([]entity.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlocked", ctx, taskID)
+	ret0, _ := ret[0].([]entity.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlocked indicates an expected call of GetBlocked.
+func (mr *MockTaskRepositoryMockRecorder) GetBlocked(ctx, taskID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlocked", reflect.TypeOf((*MockTaskRepository)(nil).GetBlocked), ctx, taskID)
+}
+
+// GetBlockers mocks base method.
+func (m *MockTaskRepository) GetBlockers(ctx context.Context, taskID int64) ([]entity.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlockers", ctx, taskID)
+	ret0, _ := ret[0].([]entity.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlockers indicates an expected call of GetBlockers.
+func (mr *MockTaskRepositoryMockRecorder) GetBlockers(ctx, taskID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlockers", reflect.TypeOf((*MockTaskRepository)(nil).GetBlockers), ctx, taskID)
 }
 
 // GetByID mocks base method.
@@ -80,33 +168,284 @@ func (mr *MockTaskRepositoryMockRecorder) GetByID(ctx, id interface{}) *gomock.C
 }
 
 // GetByUserID mocks base method.
-func (m *MockTaskRepository) GetByUserID(ctx context.Context, userID int64) ([]entity.Task, error) {
+func (m *MockTaskRepository) GetByUserID(ctx context.Context, userID int64, order storage.TaskOrder) ([]entity.Task, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByUserID", ctx, userID)
+	ret := m.ctrl.Call(m, "GetByUserID", ctx, userID, order)
 	ret0, _ := ret[0].([]entity.Task)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByUserID indicates an expected call of GetByUserID.
-func (mr *MockTaskRepositoryMockRecorder) GetByUserID(ctx, userID interface{}) *gomock.Call {
+func (mr *MockTaskRepositoryMockRecorder) GetByUserID(ctx, userID, order interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserID", reflect.TypeOf((*MockTaskRepository)(nil).GetByUserID), ctx, userID, order)
+}
+
+// GetChildren mocks base method.
+func (m *MockTaskRepository) GetChildren(ctx context.Context, parentID int64) ([]entity.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChildren", ctx, parentID)
+	ret0, _ := ret[0].([]entity.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChildren indicates an expected call of GetChildren.
+func (mr *MockTaskRepositoryMockRecorder) GetChildren(ctx, parentID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChildren", reflect.TypeOf((*MockTaskRepository)(nil).GetChildren), ctx, parentID)
+}
+
+// GetHistory mocks base method.
+func (m *MockTaskRepository) GetHistory(ctx context.Context, taskID, beforeID int64, limit int) ([]entity.TaskEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistory", ctx, taskID, beforeID, limit)
+	ret0, _ := ret[0].([]entity.TaskEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistory indicates an expected call of GetHistory.
+func (mr *MockTaskRepositoryMockRecorder) GetHistory(ctx, taskID, beforeID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistory", reflect.TypeOf((*MockTaskRepository)(nil).GetHistory), ctx, taskID, beforeID, limit)
+}
+
+// GetSeries mocks base method.
+func (m *MockTaskRepository) GetSeries(ctx context.Context, id int64) (entity.TaskSeries, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSeries", ctx, id)
+	ret0, _ := ret[0].(entity.TaskSeries)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSeries indicates an expected call of GetSeries.
+func (mr *MockTaskRepositoryMockRecorder) GetSeries(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSeries", reflect.TypeOf((*MockTaskRepository)(nil).GetSeries), ctx, id)
+}
+
+// GetSeriesTasks mocks base method.
+func (m *MockTaskRepository) GetSeriesTasks(ctx context.Context, seriesID int64) ([]entity.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSeriesTasks", ctx, seriesID)
+	ret0, _ := ret[0].([]entity.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSeriesTasks indicates an expected call of GetSeriesTasks.
+func (mr *MockTaskRepositoryMockRecorder) GetSeriesTasks(ctx, seriesID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSeriesTasks", reflect.TypeOf((*MockTaskRepository)(nil).GetSeriesTasks), ctx, seriesID)
+}
+
+// GetSubtree mocks base method.
+func (m *MockTaskRepository) GetSubtree(ctx context.Context, rootID int64) ([]entity.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubtree", ctx, rootID)
+	ret0, _ := ret[0].([]entity.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubtree indicates an expected call of GetSubtree.
+func (mr *MockTaskRepositoryMockRecorder) GetSubtree(ctx, rootID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubtree", reflect.TypeOf((*MockTaskRepository)(nil).GetSubtree), ctx, rootID)
+}
+
+// GetWorkflow mocks base method.
+func (m *MockTaskRepository) GetWorkflow(ctx context.Context, id int64) (entity.Workflow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWorkflow", ctx, id)
+	ret0, _ := ret[0].(entity.Workflow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWorkflow indicates an expected call of GetWorkflow.
+func (mr *MockTaskRepositoryMockRecorder) GetWorkflow(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWorkflow", reflect.TypeOf((*MockTaskRepository)(nil).GetWorkflow), ctx, id)
+}
+
+// InTx mocks base method.
+func (m *MockTaskRepository) InTx(ctx context.Context, fn func(storage.TaskRepository) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InTx indicates an expected call of InTx.
+func (mr *MockTaskRepositoryMockRecorder) InTx(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InTx", reflect.TypeOf((*MockTaskRepository)(nil).InTx), ctx, fn)
+}
+
+// List mocks base method.
+func (m *MockTaskRepository) List(ctx context.Context, f storage.TaskListFilter) ([]entity.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, f)
+	ret0, _ := ret[0].([]entity.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockTaskRepositoryMockRecorder) List(ctx, f interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserID", reflect.TypeOf((*MockTaskRepository)(nil).GetByUserID), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTaskRepository)(nil).List), ctx, f)
+}
+
+// ListEffort mocks base method.
+func (m *MockTaskRepository) ListEffort(ctx context.Context, userID int64, from, to *time.Time) ([]entity.TaskEffort, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEffort", ctx, userID, from, to)
+	ret0, _ := ret[0].([]entity.TaskEffort)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEffort indicates an expected call of ListEffort.
+func (mr *MockTaskRepositoryMockRecorder) ListEffort(ctx, userID, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEffort", reflect.TypeOf((*MockTaskRepository)(nil).ListEffort), ctx, userID, from, to)
+}
+
+// ListTrash mocks base method.
+func (m *MockTaskRepository) ListTrash(ctx context.Context, userID int64) ([]entity.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTrash", ctx, userID)
+	ret0, _ := ret[0].([]entity.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTrash indicates an expected call of ListTrash.
+func (mr *MockTaskRepositoryMockRecorder) ListTrash(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTrash", reflect.TypeOf((*MockTaskRepository)(nil).ListTrash), ctx, userID)
+}
+
+// Move mocks base method.
+func (m *MockTaskRepository) Move(ctx context.Context, uid, tid int64, afterID, beforeID *int64) (entity.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Move", ctx, uid, tid, afterID, beforeID)
+	ret0, _ := ret[0].(entity.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Move indicates an expected call of Move.
+func (mr *MockTaskRepositoryMockRecorder) Move(ctx, uid, tid, afterID, beforeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Move", reflect.TypeOf((*MockTaskRepository)(nil).Move), ctx, uid, tid, afterID, beforeID)
+}
+
+// OrphanChildren mocks base method.
+func (m *MockTaskRepository) OrphanChildren(ctx context.Context, parentID int64, ifVersion *int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OrphanChildren", ctx, parentID, ifVersion)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// OrphanChildren indicates an expected call of OrphanChildren.
+func (mr *MockTaskRepositoryMockRecorder) OrphanChildren(ctx, parentID, ifVersion interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OrphanChildren", reflect.TypeOf((*MockTaskRepository)(nil).OrphanChildren), ctx, parentID, ifVersion)
 }
 
 // Patch mocks base method.
-func (m *MockTaskRepository) Patch(ctx context.Context, uid, tid int64, title, desc, status *string, priority *int, dueAtProvided bool, dueAt *time.Time) (entity.Task, error) {
+func (m *MockTaskRepository) Patch(ctx context.Context, uid, tid int64, p entity.TaskPatch) (entity.Task, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Patch", ctx, uid, tid, title, desc, status, priority, dueAtProvided, dueAt)
+	ret := m.ctrl.Call(m, "Patch", ctx, uid, tid, p)
 	ret0, _ := ret[0].(entity.Task)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Patch indicates an expected call of Patch.
-func (mr *MockTaskRepositoryMockRecorder) Patch(ctx, uid, tid, title, desc, status, priority, dueAtProvided, dueAt interface{}) *gomock.Call {
+func (mr *MockTaskRepositoryMockRecorder) Patch(ctx, uid, tid, p interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*MockTaskRepository)(nil).Patch), ctx, uid, tid, title, desc, status, priority, dueAtProvided, dueAt)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*MockTaskRepository)(nil).Patch), ctx, uid, tid, p)
+}
+
+// PurgeDeleted mocks base method.
+func (m *MockTaskRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockTaskRepositoryMockRecorder) PurgeDeleted(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockTaskRepository)(nil).PurgeDeleted), ctx, before)
+}
+
+// RemoveDependency mocks base method.
+func (m *MockTaskRepository) RemoveDependency(ctx context.Context, taskID, blockedByID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveDependency", ctx, taskID, blockedByID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveDependency indicates an expected call of RemoveDependency.
+func (mr *MockTaskRepositoryMockRecorder) RemoveDependency(ctx, taskID, blockedByID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveDependency", reflect.TypeOf((*MockTaskRepository)(nil).RemoveDependency), ctx, taskID, blockedByID)
+}
+
+// Restore mocks base method.
+func (m *MockTaskRepository) Restore(ctx context.Context, userID, id int64) (entity.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, userID, id)
+	ret0, _ := ret[0].(entity.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockTaskRepositoryMockRecorder) Restore(ctx, userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockTaskRepository)(nil).Restore), ctx, userID, id)
+}
+
+// Search mocks base method.
+func (m *MockTaskRepository) Search(ctx context.Context, userID *int64, q string, limit, offset int) ([]entity.TaskSearchHit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, userID, q, limit, offset)
+	ret0, _ := ret[0].([]entity.TaskSearchHit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockTaskRepositoryMockRecorder) Search(ctx, userID, q, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockTaskRepository)(nil).Search), ctx, userID, q, limit, offset)
+}
+
+// StartSeries mocks base method.
+func (m *MockTaskRepository) StartSeries(ctx context.Context, taskID int64, s *entity.TaskSeries) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartSeries", ctx, taskID, s)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StartSeries indicates an expected call of StartSeries.
+func (mr *MockTaskRepositoryMockRecorder) StartSeries(ctx, taskID, s interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartSeries", reflect.TypeOf((*MockTaskRepository)(nil).StartSeries), ctx, taskID, s)
 }
 
 // Update mocks base method.
@@ -123,3 +462,31 @@ func (mr *MockTaskRepositoryMockRecorder) Update(ctx, task interface{}) *gomock.
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockTaskRepository)(nil).Update), ctx, task)
 }
+
+// UpdateSeries mocks base method.
+func (m *MockTaskRepository) UpdateSeries(ctx context.Context, s *entity.TaskSeries) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSeries", ctx, s)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSeries indicates an expected call of UpdateSeries.
+func (mr *MockTaskRepositoryMockRecorder) UpdateSeries(ctx, s interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSeries", reflect.TypeOf((*MockTaskRepository)(nil).UpdateSeries), ctx, s)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockTaskRepository) UpdateStatus(ctx context.Context, actor, id int64, status string, ifVersion *int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", ctx, actor, id, status, ifVersion)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockTaskRepositoryMockRecorder) UpdateStatus(ctx, actor, id, status, ifVersion interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockTaskRepository)(nil).UpdateStatus), ctx, actor, id, status, ifVersion)
+}
@@ -0,0 +1,109 @@
+// Package rank generates sort keys for manually ordered lists.
+//
+// A key is a base-36 fraction written without the leading "0.": "i" sits
+// in the middle of the range, "0i" below it and "ii" above it. Keys compare
+// bytewise, so they sort correctly as strings in the "C" collation. A key
+// never ends in '0', which leaves room below every key and lets Between
+// place a new key between any two distinct ones without touching the rest
+// of the list.
+package rank
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// MaxLen is the key length past which a list should be rebalanced.
+// Repeated inserts at the same spot grow a key by one digit every five
+// inserts or so.
+const MaxLen = 32
+
+var ErrInvalid = errors.New("invalid rank")
+
+// Valid reports whether s is a well-formed key.
+func Valid(s string) bool {
+	if s == "" || s[len(s)-1] == '0' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(digits, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Between returns a key that sorts after a and before b. An empty a means
+// the start of the list and an empty b its end, so Between("", "") is the
+// key of the first item of an empty list.
+func Between(a, b string) (string, error) {
+	if a != "" && !Valid(a) || b != "" && !Valid(b) {
+		return "", ErrInvalid
+	}
+	if a != "" && b != "" && a >= b {
+		return "", ErrInvalid
+	}
+	return midpoint(a, b), nil
+}
+
+// midpoint works digit by digit: it copies the common prefix of a and b,
+// picks a digit halfway between the first ones that differ, and recurses
+// into the tail of a when they are adjacent.
+func midpoint(a, b string) string {
+	if b != "" {
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			tail := ""
+			if n < len(a) {
+				tail = a[n:]
+			}
+			return b[:n] + midpoint(tail, b[n:])
+		}
+	}
+
+	lo := 0
+	if a != "" {
+		lo = strings.IndexByte(digits, a[0])
+	}
+	hi := len(digits)
+	if b != "" {
+		hi = strings.IndexByte(digits, b[0])
+	}
+	if hi-lo > 1 {
+		return string(digits[(lo+hi+1)/2])
+	}
+	if len(b) > 1 {
+		return b[:1]
+	}
+	tail := ""
+	if a != "" {
+		tail = a[1:]
+	}
+	return string(digits[lo]) + midpoint(tail, "")
+}
+
+// digitAt returns the i-th digit of s, treating missing digits as zeros.
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return '0'
+}
+
+// Spread returns n ascending keys of equal length with plenty of room
+// between neighbours, for rebalancing a list that grew long keys.
+func Spread(n int) []string {
+	width := len(strconv.FormatInt(int64(n), 36))
+	keys := make([]string, n)
+	for i := range keys {
+		k := strconv.FormatInt(int64(i+1), 36)
+		keys[i] = strings.Repeat("0", width-len(k)) + k + "i"
+	}
+	return keys
+}
@@ -0,0 +1,117 @@
+package rank
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "empty list", want: "i"},
+		{name: "append", a: "i", want: "r"},
+		{name: "prepend", b: "i", want: "9"},
+		{name: "wide gap", a: "1", b: "9", want: "5"},
+		{name: "adjacent digits", a: "1", b: "2", want: "1i"},
+		{name: "common prefix", a: "abc", b: "abd", want: "abci"},
+		{name: "shorter upper bound", a: "1z", b: "2", want: "1zi"},
+		{name: "longer upper bound", a: "1", b: "2a", want: "2"},
+		{name: "zeros after lower bound", a: "1", b: "101", want: "100i"},
+		{name: "before smallest", b: "01", want: "00i"},
+		{name: "spread keys", a: "01i", b: "02i", want: "02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Between(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("Between(%q, %q): %v", tt.a, tt.b, err)
+			}
+			if got != tt.want {
+				t.Errorf("Between(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBetween_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{name: "equal", a: "i", b: "i"},
+		{name: "reversed", a: "r", b: "i"},
+		{name: "trailing zero", a: "10"},
+		{name: "bad digit", b: "I"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Between(tt.a, tt.b); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Between(%q, %q) error = %v, want ErrInvalid", tt.a, tt.b, err)
+			}
+		})
+	}
+}
+
+// TestBetween_RandomMoves keeps inserting keys at random spots and checks
+// that the list stays strictly ordered.
+func TestBetween_RandomMoves(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	keys := []string{}
+	for i := 0; i < 2000; i++ {
+		pos := rnd.Intn(len(keys) + 1)
+		var a, b string
+		if pos > 0 {
+			a = keys[pos-1]
+		}
+		if pos < len(keys) {
+			b = keys[pos]
+		}
+		k, err := Between(a, b)
+		if err != nil {
+			t.Fatalf("Between(%q, %q): %v", a, b, err)
+		}
+		if !Valid(k) || (a != "" && k <= a) || (b != "" && k >= b) {
+			t.Fatalf("Between(%q, %q) = %q is out of order", a, b, k)
+		}
+		keys = append(keys[:pos], append([]string{k}, keys[pos:]...)...)
+	}
+}
+
+func TestBetween_KeyGrowth(t *testing.T) {
+	a, b := "i", "r"
+	for i := 0; i < 100; i++ {
+		k, err := Between(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b = k
+	}
+	if len(b) <= MaxLen/2 || len(b) > 2*MaxLen {
+		t.Errorf("after 100 inserts at one spot the key is %d long", len(b))
+	}
+}
+
+func TestSpread(t *testing.T) {
+	for _, n := range []int{0, 1, 35, 36, 1300} {
+		keys := Spread(n)
+		if len(keys) != n {
+			t.Fatalf("Spread(%d) returned %d keys", n, len(keys))
+		}
+		if !sort.StringsAreSorted(keys) {
+			t.Errorf("Spread(%d) is not sorted", n)
+		}
+		for i, k := range keys {
+			if !Valid(k) || len(k) != len(keys[0]) {
+				t.Errorf("Spread(%d)[%d] = %q", n, i, k)
+			}
+			if i > 0 && k == keys[i-1] {
+				t.Errorf("Spread(%d) repeats %q", n, k)
+			}
+		}
+	}
+}
@@ -0,0 +1,467 @@
+// Package rrule implements the subset of iCalendar recurrence rules
+// (RFC 5545, section 3.3.10) needed for recurring tasks: FREQ, INTERVAL,
+// COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH, BYSETPOS and WKST.
+//
+// Occurrences keep the wall-clock time and location of the start time.
+// Unlike RFC 5545, the start time is only an occurrence when it matches
+// the rule itself.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency int
+
+const (
+	Daily Frequency = iota
+	Weekly
+	Monthly
+	Yearly
+)
+
+var ErrInvalid = errors.New("invalid rrule")
+
+// maxPeriods bounds the search for rules that never or only very rarely
+// produce an occurrence, e.g. BYMONTHDAY=31;BYMONTH=2.
+const maxPeriods = 100000
+
+var freqNames = map[string]Frequency{
+	"DAILY":   Daily,
+	"WEEKLY":  Weekly,
+	"MONTHLY": Monthly,
+	"YEARLY":  Yearly,
+}
+
+var dayNames = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// WeekdayNum is a BYDAY entry. N is the ordinal within the month or year
+// (1 = first, -1 = last); zero means every such weekday of the period.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	WeekStart  time.Weekday
+
+	start time.Time
+	raw   string
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalid, fmt.Sprintf(format, args...))
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;BYDAY=MO" anchored at start.
+// An optional "RRULE:" prefix is accepted.
+func Parse(s string, start time.Time) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.ToUpper(s), "RRULE:")
+	if s == "" {
+		return nil, invalid("empty rule")
+	}
+
+	r := &Rule{Interval: 1, WeekStart: time.Monday, start: start}
+	seen := map[string]bool{}
+	freqSet := false
+
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, invalid("malformed part %q", part)
+		}
+		if seen[key] {
+			return nil, invalid("duplicate %s", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			r.Freq, ok = freqNames[value]
+			if !ok {
+				return nil, invalid("unsupported FREQ %q", value)
+			}
+			freqSet = true
+		case "INTERVAL":
+			r.Interval, err = parseInt(value, 1, 1000)
+		case "COUNT":
+			r.Count, err = parseInt(value, 1, 10000)
+		case "UNTIL":
+			var until time.Time
+			until, err = parseUntil(value, start.Location())
+			r.Until = &until
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseIntList(value, -31, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseIntList(value, 1, 12)
+			for _, m := range months {
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			r.BySetPos, err = parseIntList(value, -366, 366)
+		case "WKST":
+			r.WeekStart, ok = dayNames[value]
+			if !ok {
+				return nil, invalid("bad WKST %q", value)
+			}
+		default:
+			return nil, invalid("unsupported part %s", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !freqSet {
+		return nil, invalid("FREQ is required")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return nil, invalid("COUNT and UNTIL are mutually exclusive")
+	}
+	if len(r.BySetPos) > 0 && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0 {
+		return nil, invalid("BYSETPOS needs another BYxxx part")
+	}
+	for _, wd := range r.ByDay {
+		if wd.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return nil, invalid("BYDAY ordinals need FREQ=MONTHLY or FREQ=YEARLY")
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return nil, invalid("BYMONTHDAY is not allowed with FREQ=WEEKLY")
+	}
+
+	r.raw = s
+	return r, nil
+}
+
+func parseInt(s string, min, max int) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, invalid("bad number %q", s)
+	}
+	return n, nil
+}
+
+func parseIntList(s string, min, max int) ([]int, error) {
+	var out []int
+	for _, item := range strings.Split(s, ",") {
+		n, err := parseInt(item, min, max)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, invalid("zero is not allowed in %q", s)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func parseUntil(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", s, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102", s, loc); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return time.Time{}, invalid("bad UNTIL %q", s)
+}
+
+func parseByDay(s string) ([]WeekdayNum, error) {
+	var out []WeekdayNum
+	for _, item := range strings.Split(s, ",") {
+		if len(item) < 2 {
+			return nil, invalid("bad BYDAY %q", item)
+		}
+		day, ok := dayNames[item[len(item)-2:]]
+		if !ok {
+			return nil, invalid("bad BYDAY %q", item)
+		}
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, invalid("bad BYDAY %q", item)
+			}
+		}
+		out = append(out, WeekdayNum{N: n, Day: day})
+	}
+	return out, nil
+}
+
+// String returns the rule in its normalized textual form.
+func (r *Rule) String() string {
+	return r.raw
+}
+
+// After returns the first occurrence strictly after t.
+func (r *Rule) After(t time.Time) (time.Time, bool) {
+	next := r.Upcoming(t, 1)
+	if len(next) == 0 {
+		return time.Time{}, false
+	}
+	return next[0], true
+}
+
+// Upcoming returns up to n occurrences strictly after t.
+func (r *Rule) Upcoming(t time.Time, n int) []time.Time {
+	var out []time.Time
+	if n <= 0 {
+		return out
+	}
+	r.each(func(occ time.Time) bool {
+		if occ.After(t) {
+			out = append(out, occ)
+		}
+		return len(out) < n
+	})
+	return out
+}
+
+// each calls fn for every occurrence in order until fn returns false or
+// the rule is exhausted.
+func (r *Rule) each(fn func(time.Time) bool) {
+	count := 0
+	empty := 0
+	for i := 0; empty < maxPeriods; i++ {
+		set := r.period(i)
+		if len(set) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		for _, occ := range set {
+			if occ.Before(r.start) {
+				continue
+			}
+			if r.Until != nil && occ.After(*r.Until) {
+				return
+			}
+			count++
+			if !fn(occ) {
+				return
+			}
+			if r.Count > 0 && count >= r.Count {
+				return
+			}
+		}
+	}
+}
+
+func (r *Rule) at(year int, month time.Month, day int) time.Time {
+	s := r.start
+	return time.Date(year, month, day, s.Hour(), s.Minute(), s.Second(), s.Nanosecond(), s.Location())
+}
+
+// period expands the i-th period (day, week, month or year) of the rule
+// into its sorted occurrences, BYSETPOS applied.
+func (r *Rule) period(i int) []time.Time {
+	s := r.start
+	step := i * r.Interval
+	var set []time.Time
+
+	switch r.Freq {
+	case Daily:
+		d := r.at(s.Year(), s.Month(), s.Day()+step)
+		if r.matchMonth(d.Month()) && r.matchMonthDay(d) && r.matchWeekday(d.Weekday()) {
+			set = append(set, d)
+		}
+
+	case Weekly:
+		offset := (int(s.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := s.Day() - offset + 7*step
+		for k := 0; k < 7; k++ {
+			d := r.at(s.Year(), s.Month(), weekStart+k)
+			if len(r.ByDay) == 0 && d.Weekday() != s.Weekday() {
+				continue
+			}
+			if r.matchWeekday(d.Weekday()) && r.matchMonth(d.Month()) {
+				set = append(set, d)
+			}
+		}
+
+	case Monthly:
+		first := r.at(s.Year(), s.Month()+time.Month(step), 1)
+		if r.matchMonth(first.Month()) {
+			set = r.monthDays(first.Year(), first.Month())
+		}
+
+	case Yearly:
+		year := s.Year() + step
+		switch {
+		case len(r.ByMonth) > 0:
+			for _, m := range sortedMonths(r.ByMonth) {
+				set = append(set, r.monthDays(year, m)...)
+			}
+		case len(r.ByMonthDay) > 0:
+			for m := time.January; m <= time.December; m++ {
+				set = append(set, r.monthDays(year, m)...)
+			}
+		case len(r.ByDay) > 0:
+			set = r.yearWeekdays(year)
+		default:
+			d := r.at(year, s.Month(), s.Day())
+			if d.Day() == s.Day() {
+				set = append(set, d)
+			}
+		}
+	}
+
+	return r.applySetPos(set)
+}
+
+// monthDays lists the days of one month selected by BYMONTHDAY and BYDAY,
+// or the start's day of month when neither is given.
+func (r *Rule) monthDays(year int, month time.Month) []time.Time {
+	dim := daysIn(year, month)
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if r.start.Day() > dim {
+			return nil
+		}
+		return []time.Time{r.at(year, month, r.start.Day())}
+	}
+
+	var out []time.Time
+	for day := 1; day <= dim; day++ {
+		d := r.at(year, month, day)
+		if len(r.ByMonthDay) > 0 && !r.matchMonthDay(d) {
+			continue
+		}
+		if len(r.ByDay) > 0 && !matchNthWeekday(r.ByDay, d.Weekday(), day, dim) {
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+func (r *Rule) yearWeekdays(year int) []time.Time {
+	total := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	var out []time.Time
+	for yd := 1; yd <= total; yd++ {
+		d := r.at(year, time.January, yd)
+		if matchNthWeekday(r.ByDay, d.Weekday(), yd, total) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func (r *Rule) applySetPos(set []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 || len(set) == 0 {
+		return set
+	}
+	picked := map[int]bool{}
+	for _, pos := range r.BySetPos {
+		idx := pos - 1
+		if pos < 0 {
+			idx = len(set) + pos
+		}
+		if idx >= 0 && idx < len(set) {
+			picked[idx] = true
+		}
+	}
+	out := make([]time.Time, 0, len(picked))
+	for idx := range set {
+		if picked[idx] {
+			out = append(out, set[idx])
+		}
+	}
+	return out
+}
+
+func (r *Rule) matchMonth(m time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, bm := range r.ByMonth {
+		if bm == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	dim := daysIn(d.Year(), d.Month())
+	for _, md := range r.ByMonthDay {
+		if md == d.Day() || (md < 0 && dim+1+md == d.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchWeekday(wd time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, bd := range r.ByDay {
+		if bd.Day == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// matchNthWeekday checks day number pos (1-based) of a period of length
+// total against BYDAY entries, honouring ordinals such as 2MO or -1FR.
+func matchNthWeekday(byDay []WeekdayNum, wd time.Weekday, pos, total int) bool {
+	for _, bd := range byDay {
+		if bd.Day != wd {
+			continue
+		}
+		switch {
+		case bd.N == 0:
+			return true
+		case bd.N > 0 && (pos-1)/7+1 == bd.N:
+			return true
+		case bd.N < 0 && (total-pos)/7+1 == -bd.N:
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func sortedMonths(months []time.Month) []time.Month {
+	out := append([]time.Month(nil), months...)
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
@@ -0,0 +1,117 @@
+package rrule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestRule_Upcoming(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		after string
+		want  []string
+	}{
+		{
+			name:  "weekly on monday",
+			rule:  "FREQ=WEEKLY;BYDAY=MO",
+			start: "2025-09-03 09:00",
+			after: "2025-09-03 09:00",
+			want:  []string{"2025-09-08 09:00", "2025-09-15 09:00", "2025-09-22 09:00"},
+		},
+		{
+			name:  "last business day of the month",
+			rule:  "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+			start: "2025-01-31 18:00",
+			after: "2025-01-31 18:00",
+			want:  []string{"2025-02-28 18:00", "2025-03-31 18:00", "2025-04-30 18:00", "2025-05-30 18:00"},
+		},
+		{
+			name:  "monthly on the 31st skips short months",
+			rule:  "FREQ=MONTHLY",
+			start: "2025-01-31 10:00",
+			after: "2025-01-31 10:00",
+			want:  []string{"2025-03-31 10:00", "2025-05-31 10:00"},
+		},
+		{
+			name:  "every other day with count",
+			rule:  "RRULE:FREQ=DAILY;INTERVAL=2;COUNT=3",
+			start: "2025-09-01 08:00",
+			after: "2025-08-01 00:00",
+			want:  []string{"2025-09-01 08:00", "2025-09-03 08:00", "2025-09-05 08:00"},
+		},
+		{
+			name:  "until is inclusive",
+			rule:  "FREQ=WEEKLY;UNTIL=20250915T090000Z",
+			start: "2025-09-01 09:00",
+			after: "2025-09-01 09:00",
+			want:  []string{"2025-09-08 09:00", "2025-09-15 09:00"},
+		},
+		{
+			name:  "second tuesday of march every year",
+			rule:  "FREQ=YEARLY;BYMONTH=3;BYDAY=2TU",
+			start: "2025-01-01 12:00",
+			after: "2025-01-01 12:00",
+			want:  []string{"2025-03-11 12:00", "2026-03-10 12:00"},
+		},
+		{
+			name:  "last day of month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: "2024-01-15 00:00",
+			after: "2024-01-15 00:00",
+			want:  []string{"2024-01-31 00:00", "2024-02-29 00:00", "2024-03-31 00:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.rule, date(tt.start))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			got := r.Upcoming(date(tt.after), len(tt.want)+1)
+			if (r.Count > 0 || r.Until != nil) && len(got) != len(tt.want) {
+				t.Fatalf("expected the rule to end after %d occurrences, got %v", len(tt.want), got)
+			}
+			if len(got) < len(tt.want) {
+				t.Fatalf("expected %d occurrences, got %v", len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				if !got[i].Equal(date(w)) {
+					t.Errorf("occurrence %d: got %v, want %s", i, got[i], w)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	rules := []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=HOURLY",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=DAILY;COUNT=2;UNTIL=20250101",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=MONTHLY;BYSETPOS=1",
+		"FREQ=MONTHLY;FREQ=DAILY",
+		"FREQ=MONTHLY;BYHOUR=9",
+	}
+	for _, s := range rules {
+		if _, err := Parse(s, time.Now()); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Parse(%q): expected ErrInvalid, got %v", s, err)
+		}
+	}
+}
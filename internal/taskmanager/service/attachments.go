@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"io"
+	"log"
+	"mime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	DefaultMaxAttachmentSize = 25 << 20
+	MaxAttachmentNameLen     = 255
+	MaxContentTypeLen        = 255
+
+	// sweepBatch is how many orphaned blobs one sweep deletes at most.
+	sweepBatch = 500
+)
+
+var (
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrAttachmentTooLarge = errors.New("attachment too large")
+	ErrBadAttachmentName  = errors.New("bad attachment name")
+	ErrBadContentType     = errors.New("bad content type")
+)
+
+// inlineTypes are the media types a browser may render in place without
+// running script in our origin. SVG and HTML are deliberately absent.
+var inlineTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+type AttachmentService struct {
+	repo    *storage.AttachmentRepo
+	store   storage.BlobStore
+	tasks   storage.TaskRepository
+	maxSize int64
+}
+
+func NewAttachmentService(repo *storage.AttachmentRepo, store storage.BlobStore, tasks storage.TaskRepository, maxSize int64) *AttachmentService {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxAttachmentSize
+	}
+	return &AttachmentService{repo: repo, store: store, tasks: tasks, maxSize: maxSize}
+}
+
+func (s *AttachmentService) MaxSize() int64 { return s.maxSize }
+
+// validateAttachmentName keeps the base name a client sent, as browsers
+// used to send full paths, and rejects names that cannot be echoed back in
+// a Content-Disposition header.
+func validateAttachmentName(name string) (string, error) {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || !utf8.ValidString(name) {
+		return "", ErrBadAttachmentName
+	}
+	if utf8.RuneCountInString(name) > MaxAttachmentNameLen {
+		return "", ErrBadAttachmentName
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return "", ErrBadAttachmentName
+		}
+	}
+	return name, nil
+}
+
+// normalizeContentType parses the type a client sent and formats it back,
+// so that only well-formed values that fit the column are stored.
+func normalizeContentType(ct string) (string, error) {
+	if strings.TrimSpace(ct) == "" {
+		return "application/octet-stream", nil
+	}
+	mt, params, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return "", ErrBadContentType
+	}
+	ct = mime.FormatMediaType(mt, params)
+	if ct == "" || len(ct) > MaxContentTypeLen {
+		return "", ErrBadContentType
+	}
+	return ct, nil
+}
+
+// InlineSafe reports whether content of the given type may be served with
+// Content-Disposition: inline.
+func InlineSafe(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && inlineTypes[mt]
+}
+
+func newBlobKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	k := hex.EncodeToString(b[:])
+	return k[:2] + "/" + k, nil
+}
+
+// storeBlob copies r into the store under key while hashing it. Content
+// beyond max is not accepted: the blob is removed and ErrAttachmentTooLarge
+// returned.
+func storeBlob(ctx context.Context, store storage.BlobStore, key string, r io.Reader, max int64) (int64, string, error) {
+	h := sha256.New()
+	n, err := store.Put(ctx, key, io.TeeReader(io.LimitReader(r, max+1), h))
+	if err == nil && n > max {
+		err = ErrAttachmentTooLarge
+	}
+	if err != nil {
+		if derr := store.Delete(context.WithoutCancel(ctx), key); derr != nil {
+			log.Printf("attachments: delete blob %s: %v", key, derr)
+		}
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (s *AttachmentService) checkTask(ctx context.Context, uid, tid int64) error {
+	t, err := s.tasks.GetByID(ctx, tid)
+	if err != nil || t.UserID != uid {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+func (s *AttachmentService) getAttachment(ctx context.Context, tid, aid int64) (entity.Attachment, error) {
+	a, err := s.repo.GetByID(ctx, aid)
+	if err != nil || a.TaskID != tid {
+		return entity.Attachment{}, ErrAttachmentNotFound
+	}
+	return a, nil
+}
+
+// Upload stores the content first and the metadata second, so a row never
+// points at a missing blob.
+func (s *AttachmentService) Upload(ctx context.Context, uid, tid int64, name, contentType string, r io.Reader) (entity.Attachment, error) {
+	if err := s.checkTask(ctx, uid, tid); err != nil {
+		return entity.Attachment{}, err
+	}
+	name, err := validateAttachmentName(name)
+	if err != nil {
+		return entity.Attachment{}, err
+	}
+	contentType, err = normalizeContentType(contentType)
+	if err != nil {
+		return entity.Attachment{}, err
+	}
+
+	key, err := newBlobKey()
+	if err != nil {
+		return entity.Attachment{}, err
+	}
+	size, sum, err := storeBlob(ctx, s.store, key, r, s.maxSize)
+	if err != nil {
+		return entity.Attachment{}, err
+	}
+
+	a := entity.Attachment{
+		TaskID:      tid,
+		UserID:      uid,
+		Name:        name,
+		Size:        size,
+		ContentType: contentType,
+		Checksum:    sum,
+		BlobKey:     key,
+	}
+	if err := s.repo.Create(ctx, &a); err != nil {
+		if derr := s.store.Delete(context.WithoutCancel(ctx), key); derr != nil {
+			log.Printf("attachments: delete blob %s: %v", key, derr)
+		}
+		return entity.Attachment{}, err
+	}
+	return a, nil
+}
+
+func (s *AttachmentService) ListAttachments(ctx context.Context, uid, tid int64) ([]entity.Attachment, error) {
+	if err := s.checkTask(ctx, uid, tid); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByTask(ctx, tid)
+}
+
+// Open returns the metadata and the content of an attachment; the caller
+// closes the content.
+func (s *AttachmentService) Open(ctx context.Context, uid, tid, aid int64) (entity.Attachment, io.ReadSeekCloser, error) {
+	if err := s.checkTask(ctx, uid, tid); err != nil {
+		return entity.Attachment{}, nil, err
+	}
+	a, err := s.getAttachment(ctx, tid, aid)
+	if err != nil {
+		return entity.Attachment{}, nil, err
+	}
+	rc, err := s.store.Open(ctx, a.BlobKey)
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		return entity.Attachment{}, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return entity.Attachment{}, nil, err
+	}
+	return a, rc, nil
+}
+
+// DeleteAttachment removes the metadata and then tries to delete the blob
+// right away; if that fails the purge worker picks it up later.
+func (s *AttachmentService) DeleteAttachment(ctx context.Context, uid, tid, aid int64) error {
+	if err := s.checkTask(ctx, uid, tid); err != nil {
+		return err
+	}
+	a, err := s.getAttachment(ctx, tid, aid)
+	if err != nil {
+		return err
+	}
+	err = s.repo.Delete(ctx, a.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAttachmentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	s.dropBlob(ctx, a.BlobKey)
+	return nil
+}
+
+func (s *AttachmentService) dropBlob(ctx context.Context, key string) bool {
+	if err := s.store.Delete(ctx, key); err != nil {
+		log.Printf("attachments: delete blob %s: %v", key, err)
+		return false
+	}
+	if err := s.repo.DropGarbage(ctx, key); err != nil {
+		log.Printf("attachments: forget blob %s: %v", key, err)
+		return false
+	}
+	return true
+}
+
+// SweepBlobs deletes blobs left behind by attachments that are gone, most
+// often because their task was purged from the trash. It returns how many
+// blobs were deleted.
+func (s *AttachmentService) SweepBlobs(ctx context.Context) (int, error) {
+	keys, err := s.repo.Garbage(ctx, sweepBatch)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, k := range keys {
+		if ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+		if s.dropBlob(ctx, k) {
+			n++
+		}
+	}
+	return n, nil
+}
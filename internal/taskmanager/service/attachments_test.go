@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestValidateAttachmentName(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"report.pdf", "report.pdf", false},
+		{`C:\Users\me\report.pdf`, "report.pdf", false},
+		{"  notes.txt ", "notes.txt", false},
+		{"dir/", "", true},
+		{"..", "", true},
+		{"bad\nname", "", true},
+		{strings.Repeat("я", MaxAttachmentNameLen+1), "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := validateAttachmentName(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrBadAttachmentName) {
+				t.Errorf("%q: expected ErrBadAttachmentName, got %v", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeContentType(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "application/octet-stream", false},
+		{"image/PNG", "image/png", false},
+		{"text/plain; charset=UTF-8", "text/plain; charset=UTF-8", false},
+		{"text/html", "text/html", false},
+		{"not a type", "", true},
+		{"text/plain; charset", "", true},
+		{"application/" + strings.Repeat("x", MaxContentTypeLen), "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeContentType(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrBadContentType) {
+				t.Errorf("%q: expected ErrBadContentType, got %v", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestInlineSafe(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"image/png", true},
+		{"image/jpeg", true},
+		{"application/pdf", true},
+		{"text/plain; charset=utf-8", true},
+		{"image/svg+xml", false},
+		{"text/html", false},
+		{"application/xhtml+xml", false},
+		{"application/octet-stream", false},
+		{"garbage", false},
+	}
+
+	for _, tt := range tests {
+		if got := InlineSafe(tt.in); got != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStoreBlob(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewFSBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	content := "hello, attachment"
+	size, sum, err := storeBlob(ctx, store, "ab/abcdef", strings.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	want := sha256.Sum256([]byte(content))
+	if size != int64(len(content)) || sum != hex.EncodeToString(want[:]) {
+		t.Errorf("got size %d checksum %s", size, sum)
+	}
+
+	rc, err := store.Open(ctx, "ab/abcdef")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := rc.Seek(7, io.SeekStart); err != nil {
+		t.Fatalf("seek: %v", err)
+	}
+	rest, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(rest) != content[7:] {
+		t.Errorf("read after seek: got %q", rest)
+	}
+
+	_, _, err = storeBlob(ctx, store, "cd/cdef01", strings.NewReader(content), int64(len(content))-1)
+	if !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Fatalf("expected ErrAttachmentTooLarge, got %v", err)
+	}
+	if _, err := store.Open(ctx, "cd/cdef01"); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("oversized blob was kept: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"time"
+)
+
+const MaxBatchOps = 500
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchPatch  = "patch"
+	BatchDelete = "delete"
+)
+
+type BatchMode string
+
+const (
+	// BatchAtomic commits either every operation or none of them.
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort commits the operations that succeed and reports the
+	// rest; each one runs in its own savepoint.
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+var (
+	ErrBadBatchMode = errors.New("bad batch mode")
+	ErrBadBatchOp   = errors.New("bad batch operation")
+	ErrBatchEmpty   = errors.New("empty batch")
+	ErrBatchTooBig  = errors.New("too many batch operations")
+	// ErrRolledBack is reported for the operations of an atomic batch that
+	// succeeded but were undone because another one failed.
+	ErrRolledBack = errors.New("rolled back")
+	// ErrNotRun is reported for the operations of an atomic batch that come
+	// after the failed one.
+	ErrNotRun = errors.New("not run")
+)
+
+// BatchOp is one operation of a batch. Create and Update use the task
+// fields, Patch uses Patch, Delete uses Children; TaskID is ignored for
+// Create.
+type BatchOp struct {
+	Op          string
+	TaskID      int64
+	Title       string
+	Description string
+	Status      string
+	Priority    int64
+	DueAt       *time.Time
+	Opts        []TaskOption
+	Patch       entity.TaskPatch
+	Children    ChildPolicy
+	IfVersion   *int64
+}
+
+// BatchResult is the outcome of the operation at the same index. Task is
+// empty for deletes and for operations that did not commit.
+type BatchResult struct {
+	Task entity.Task
+	Err  error
+}
+
+// RunBatch applies ops for user uid inside one transaction and reports a
+// result for every operation. The returned bool tells whether anything was
+// committed; the error is set only when the transaction itself failed.
+func (s *TaskService) RunBatch(ctx context.Context, uid int64, ops []BatchOp, mode BatchMode) ([]BatchResult, bool, error) {
+	if mode == "" {
+		mode = BatchAtomic
+	}
+	if mode != BatchAtomic && mode != BatchBestEffort {
+		return nil, false, ErrBadBatchMode
+	}
+	if len(ops) == 0 {
+		return nil, false, ErrBatchEmpty
+	}
+	if len(ops) > MaxBatchOps {
+		return nil, false, ErrBatchTooBig
+	}
+
+	results := make([]BatchResult, len(ops))
+	failed := -1
+
+	err := s.inTx(ctx, func(tx *TaskService) error {
+		for i, op := range ops {
+			if mode == BatchAtomic {
+				results[i].Task, results[i].Err = tx.runBatchOp(ctx, uid, op)
+				if results[i].Err != nil {
+					failed = i
+					return results[i].Err
+				}
+				continue
+			}
+
+			err := tx.inTx(ctx, func(sp *TaskService) error {
+				results[i].Task, results[i].Err = sp.runBatchOp(ctx, uid, op)
+				return results[i].Err
+			})
+			if err != nil && results[i].Err == nil {
+				return err
+			}
+			if results[i].Err != nil {
+				results[i].Task = entity.Task{}
+			}
+		}
+		return nil
+	})
+
+	if failed >= 0 {
+		for i := range results {
+			switch {
+			case i < failed:
+				results[i] = BatchResult{Err: ErrRolledBack}
+			case i > failed:
+				results[i] = BatchResult{Err: ErrNotRun}
+			}
+		}
+		return results, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return results, true, nil
+}
+
+func (s *TaskService) runBatchOp(ctx context.Context, uid int64, op BatchOp) (entity.Task, error) {
+	switch op.Op {
+	case BatchCreate:
+		return s.CreateTask(ctx, uid, op.Title, op.Description, op.Status, op.Priority, op.DueAt, op.Opts...)
+	case BatchUpdate:
+		return s.UpdateTask(ctx, uid, op.TaskID, op.Title, op.Description, op.Status, op.Priority, op.DueAt, op.IfVersion)
+	case BatchPatch:
+		p := op.Patch
+		if p.IfVersion == nil {
+			p.IfVersion = op.IfVersion
+		}
+		return s.PatchTask(ctx, uid, op.TaskID, p)
+	case BatchDelete:
+		return entity.Task{}, s.DeleteTaskByUser(ctx, uid, op.TaskID, op.Children, op.IfVersion)
+	}
+	return entity.Task{}, ErrBadBatchOp
+}
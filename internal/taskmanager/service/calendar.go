@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/ical"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"strconv"
+)
+
+var (
+	ErrBadCalendarToken = errors.New("bad calendar token")
+	ErrNoCalendarToken  = errors.New("calendar feed is not enabled")
+)
+
+type CalendarService struct {
+	repo  *storage.CalendarRepo
+	tasks *TaskService
+}
+
+func NewCalendarService(repo *storage.CalendarRepo, tasks *TaskService) *CalendarService {
+	return &CalendarService{repo: repo, tasks: tasks}
+}
+
+func hashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RotateToken issues a new feed token for uid. The previous one, if any,
+// stops working immediately.
+func (s *CalendarService) RotateToken(ctx context.Context, uid int64) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	if err := s.repo.SetToken(ctx, uid, hashCalendarToken(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *CalendarService) RevokeToken(ctx context.Context, uid int64) error {
+	err := s.repo.DeleteToken(ctx, uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoCalendarToken
+	}
+	return err
+}
+
+// CheckToken reports ErrBadCalendarToken both for a wrong token and for a
+// user without a feed, so the feed URL does not reveal either.
+func (s *CalendarService) CheckToken(ctx context.Context, uid int64, token string) error {
+	if token == "" {
+		return ErrBadCalendarToken
+	}
+	want, err := s.repo.TokenHash(ctx, uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrBadCalendarToken
+	}
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(want), []byte(hashCalendarToken(token))) != 1 {
+		return ErrBadCalendarToken
+	}
+	return nil
+}
+
+// CalendarUID is the iCalendar UID of a task; it depends only on the task
+// id so that clients update the entry when the task changes.
+func CalendarUID(taskID int64) string {
+	return "task-" + strconv.FormatInt(taskID, 10) + "@taskmanager"
+}
+
+// calendarPriority maps task priority 1..5, 1 being the most urgent, onto
+// the iCalendar scale 1..9.
+func calendarPriority(p int64) int {
+	if !isValidPriority(p) {
+		return 0
+	}
+	return int(2*p - 1)
+}
+
+func calendarStatus(status string) string {
+	switch status {
+	case StatusDone:
+		return ical.Completed
+	case StatusTodo:
+		return ical.NeedsAction
+	}
+	return ical.InProcess
+}
+
+// calendarItem turns a task with a due date into a feed entry. Sequence
+// follows the task version, which grows on every write.
+func calendarItem(t entity.Task) ical.Item {
+	it := ical.Item{
+		UID:         CalendarUID(t.ID),
+		Summary:     t.Title,
+		Description: t.Description,
+		Status:      calendarStatus(t.Status),
+		Priority:    calendarPriority(t.Priority),
+		Stamp:       t.UpdatedAt,
+		Sequence:    t.Version,
+		Categories:  []string{t.Status},
+	}
+	if t.DueAt != nil {
+		it.Due = *t.DueAt
+	}
+	for _, l := range t.Labels {
+		it.Categories = append(it.Categories, l.Name)
+	}
+	return it
+}
+
+// Feed streams the tasks of uid that have a due date to fn as feed entries.
+func (s *CalendarService) Feed(ctx context.Context, uid int64, fn func(ical.Item) error) error {
+	return s.tasks.ExportTasks(ctx, uid, func(t entity.Task) error {
+		if t.DueAt == nil {
+			return nil
+		}
+		return fn(calendarItem(t))
+	})
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const MaxCommentLen = 4000
+
+var (
+	ErrEmptyComment    = errors.New("empty comment")
+	ErrCommentTooLong  = errors.New("comment too long")
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrBadReply        = errors.New("bad reply target")
+	ErrNotAuthor       = errors.New("not the comment author")
+)
+
+type CommentPage struct {
+	Comments   []entity.Comment
+	NextCursor string
+}
+
+// CommentService manages the discussion of a task. Everyone who can see
+// the task may read and post comments: its owner, its assignee and the
+// members of its project. A comment can only be changed or removed by its
+// author.
+type CommentService struct {
+	repo     storage.CommentRepository
+	tasks    storage.TaskRepository
+	projects storage.ProjectRepository
+}
+
+func NewCommentService(repo storage.CommentRepository, tasks storage.TaskRepository, projects storage.ProjectRepository) *CommentService {
+	return &CommentService{repo: repo, tasks: tasks, projects: projects}
+}
+
+func validateCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", ErrEmptyComment
+	}
+	if utf8.RuneCountInString(body) > MaxCommentLen {
+		return "", ErrCommentTooLong
+	}
+	return body, nil
+}
+
+func (s *CommentService) checkTask(ctx context.Context, uid, tid int64) error {
+	t, err := s.tasks.GetByID(ctx, tid)
+	if err != nil || !canSeeTask(ctx, s.projects, t, uid) {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+func (s *CommentService) getComment(ctx context.Context, tid, cid int64) (entity.Comment, error) {
+	c, err := s.repo.GetByID(ctx, cid)
+	if err != nil || c.TaskID != tid {
+		return entity.Comment{}, ErrCommentNotFound
+	}
+	return c, nil
+}
+
+// AddComment posts a comment on a task. Replies are allowed one level
+// deep: parentID must point to a top-level comment of the same task.
+func (s *CommentService) AddComment(ctx context.Context, uid, tid int64, parentID *int64, body string) (entity.Comment, error) {
+	if err := s.checkTask(ctx, uid, tid); err != nil {
+		return entity.Comment{}, err
+	}
+	body, err := validateCommentBody(body)
+	if err != nil {
+		return entity.Comment{}, err
+	}
+	if parentID != nil {
+		parent, err := s.getComment(ctx, tid, *parentID)
+		if err != nil || parent.ParentID != nil {
+			return entity.Comment{}, ErrBadReply
+		}
+	}
+
+	c := &entity.Comment{TaskID: tid, UserID: uid, ParentID: parentID, Body: body}
+	if err := s.repo.Create(ctx, c); err != nil {
+		return entity.Comment{}, err
+	}
+	return *c, nil
+}
+
+// ListComments pages through the top-level comments of a task in creation
+// order, each with all of its replies attached.
+func (s *CommentService) ListComments(ctx context.Context, uid, tid int64, cursor string, limit int) (CommentPage, error) {
+	if err := s.checkTask(ctx, uid, tid); err != nil {
+		return CommentPage{}, err
+	}
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	var after *time.Time
+	var afterID int64
+	if cursor != "" {
+		c, err := decodeCursor(cursor)
+		if err != nil {
+			return CommentPage{}, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil || c.Sort != SortCreatedAt {
+			return CommentPage{}, ErrBadCursor
+		}
+		after, afterID = &t, c.ID
+	}
+
+	top, err := s.repo.ListTopLevel(ctx, tid, after, afterID, limit+1)
+	if err != nil {
+		return CommentPage{}, err
+	}
+
+	var page CommentPage
+	if len(top) > limit {
+		top = top[:limit]
+		last := top[limit-1]
+		page.NextCursor = encodeCursor(taskCursor{
+			Sort:  SortCreatedAt,
+			Value: last.CreatedAt.UTC().Format(time.RFC3339Nano),
+			ID:    last.ID,
+		})
+	}
+
+	ids := make([]int64, 0, len(top))
+	byID := make(map[int64]int, len(top))
+	for i, c := range top {
+		ids = append(ids, c.ID)
+		byID[c.ID] = i
+	}
+	replies, err := s.repo.ListReplies(ctx, ids)
+	if err != nil {
+		return CommentPage{}, err
+	}
+	for _, r := range replies {
+		i := byID[*r.ParentID]
+		top[i].Replies = append(top[i].Replies, r)
+	}
+
+	page.Comments = top
+	return page, nil
+}
+
+// EditComment changes the body of a comment; only its author may edit it.
+func (s *CommentService) EditComment(ctx context.Context, uid, tid, cid int64, body string) (entity.Comment, error) {
+	if err := s.checkTask(ctx, uid, tid); err != nil {
+		return entity.Comment{}, err
+	}
+	cur, err := s.getComment(ctx, tid, cid)
+	if err != nil {
+		return entity.Comment{}, err
+	}
+	if cur.UserID != uid {
+		return entity.Comment{}, ErrNotAuthor
+	}
+	body, err = validateCommentBody(body)
+	if err != nil {
+		return entity.Comment{}, err
+	}
+
+	c, err := s.repo.UpdateBody(ctx, cid, body)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Comment{}, ErrCommentNotFound
+	}
+	return c, err
+}
+
+// DeleteComment removes a comment together with its replies; only its
+// author may delete it.
+func (s *CommentService) DeleteComment(ctx context.Context, uid, tid, cid int64) error {
+	if err := s.checkTask(ctx, uid, tid); err != nil {
+		return err
+	}
+	cur, err := s.getComment(ctx, tid, cid)
+	if err != nil {
+		return err
+	}
+	if cur.UserID != uid {
+		return ErrNotAuthor
+	}
+	err = s.repo.Delete(ctx, cid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCommentNotFound
+	}
+	return err
+}
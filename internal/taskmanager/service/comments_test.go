@@ -0,0 +1,367 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/mocks"
+	"github.com/golang/mock/gomock"
+	"strings"
+	"testing"
+)
+
+func TestValidateCommentBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr error
+	}{
+		{name: "trimmed", body: "  looks good \n", want: "looks good"},
+		{name: "at limit", body: strings.Repeat("ж", MaxCommentLen), want: strings.Repeat("ж", MaxCommentLen)},
+		{name: "blank", body: " \t ", wantErr: ErrEmptyComment},
+		{name: "too long", body: strings.Repeat("a", MaxCommentLen+1), wantErr: ErrCommentTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateCommentBody(tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCommentService_AddComment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTasks := mocks.NewMockTaskRepository(ctrl)
+	mockRepo := mocks.NewMockCommentRepository(ctrl)
+	svc := NewCommentService(mockRepo, mockTasks, mocks.NewMockProjectRepository(ctrl))
+
+	mockTasks.EXPECT().GetByID(gomock.Any(), int64(10)).Return(entity.Task{ID: 10, UserID: 1}, nil).AnyTimes()
+	mockTasks.EXPECT().GetByID(gomock.Any(), int64(404)).Return(entity.Task{}, sql.ErrNoRows).AnyTimes()
+
+	top := int64(100)
+	reply := int64(101)
+	foreign := int64(200)
+	missing := int64(300)
+
+	tests := []struct {
+		name      string
+		uid       int64
+		tid       int64
+		parentID  *int64
+		body      string
+		mockSetup func()
+		wantErr   error
+	}{
+		{
+			name: "top level",
+			uid:  1,
+			tid:  10,
+			body: "first",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					Create(gomock.Any(), &entity.Comment{TaskID: 10, UserID: 1, Body: "first"}).
+					Return(nil)
+			},
+		},
+		{
+			name:     "reply",
+			uid:      1,
+			tid:      10,
+			parentID: &top,
+			body:     "second",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetByID(gomock.Any(), top).
+					Return(entity.Comment{ID: top, TaskID: 10, UserID: 1}, nil)
+				mockRepo.EXPECT().
+					Create(gomock.Any(), gomock.Any()).
+					Return(nil)
+			},
+		},
+		{
+			name:     "reply to a reply",
+			uid:      1,
+			tid:      10,
+			parentID: &reply,
+			body:     "third",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetByID(gomock.Any(), reply).
+					Return(entity.Comment{ID: reply, TaskID: 10, ParentID: &top}, nil)
+			},
+			wantErr: ErrBadReply,
+		},
+		{
+			name:     "parent on another task",
+			uid:      1,
+			tid:      10,
+			parentID: &foreign,
+			body:     "hi",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetByID(gomock.Any(), foreign).
+					Return(entity.Comment{ID: foreign, TaskID: 11, UserID: 1}, nil)
+			},
+			wantErr: ErrBadReply,
+		},
+		{
+			name:     "missing parent",
+			uid:      1,
+			tid:      10,
+			parentID: &missing,
+			body:     "hi",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetByID(gomock.Any(), missing).
+					Return(entity.Comment{}, sql.ErrNoRows)
+			},
+			wantErr: ErrBadReply,
+		},
+		{
+			name:      "empty body",
+			uid:       1,
+			tid:       10,
+			body:      "  ",
+			mockSetup: func() {},
+			wantErr:   ErrEmptyComment,
+		},
+		{
+			name:      "not the task owner",
+			uid:       2,
+			tid:       10,
+			body:      "hi",
+			mockSetup: func() {},
+			wantErr:   ErrTaskNotFound,
+		},
+		{
+			name:      "missing task",
+			uid:       1,
+			tid:       404,
+			body:      "hi",
+			mockSetup: func() {},
+			wantErr:   ErrTaskNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			_, err := svc.AddComment(context.Background(), tt.uid, tt.tid, tt.parentID, tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCommentService_Visibility(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTasks := mocks.NewMockTaskRepository(ctrl)
+	mockRepo := mocks.NewMockCommentRepository(ctrl)
+	mockProjects := mocks.NewMockProjectRepository(ctrl)
+	svc := NewCommentService(mockRepo, mockTasks, mockProjects)
+	ctx := context.Background()
+
+	// Task 10 is owned by user 1, assigned to user 3 and filed under
+	// project 5, which user 4 is a member of.
+	assignee, project := int64(3), int64(5)
+	task := entity.Task{ID: 10, UserID: 1, AssigneeID: &assignee, ProjectID: &project}
+	mockTasks.EXPECT().GetByID(gomock.Any(), int64(10)).Return(task, nil).AnyTimes()
+	mockProjects.EXPECT().GetMember(gomock.Any(), int64(5), int64(4)).Return(entity.ProjectMember{ProjectID: 5, UserID: 4}, nil).AnyTimes()
+	mockProjects.EXPECT().GetMember(gomock.Any(), int64(5), int64(2)).Return(entity.ProjectMember{}, sql.ErrNoRows).AnyTimes()
+
+	// The comment repository must not be touched for a stranger.
+	if _, err := svc.ListComments(ctx, 2, 10, "", 0); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("stranger list: expected %v, got %v", ErrTaskNotFound, err)
+	}
+	if _, err := svc.AddComment(ctx, 2, 10, nil, "hi"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("stranger add: expected %v, got %v", ErrTaskNotFound, err)
+	}
+	if _, err := svc.EditComment(ctx, 2, 10, 100, "mine now"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("stranger edit: expected %v, got %v", ErrTaskNotFound, err)
+	}
+	if err := svc.DeleteComment(ctx, 2, 10, 100); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("stranger delete: expected %v, got %v", ErrTaskNotFound, err)
+	}
+
+	mockRepo.EXPECT().
+		Create(gomock.Any(), &entity.Comment{TaskID: 10, UserID: 3, Body: "on it"}).
+		Return(nil)
+	if _, err := svc.AddComment(ctx, 3, 10, nil, "on it"); err != nil {
+		t.Errorf("assignee add: unexpected error %v", err)
+	}
+
+	mockRepo.EXPECT().
+		ListTopLevel(gomock.Any(), int64(10), nil, int64(0), DefaultPageSize+1).
+		Return(nil, nil)
+	mockRepo.EXPECT().ListReplies(gomock.Any(), []int64{}).Return(nil, nil)
+	if _, err := svc.ListComments(ctx, 4, 10, "", 0); err != nil {
+		t.Errorf("member list: unexpected error %v", err)
+	}
+
+	// A member sees the owner's comment but may not change it.
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(100)).Return(entity.Comment{ID: 100, TaskID: 10, UserID: 1}, nil).Times(2)
+	if _, err := svc.EditComment(ctx, 4, 10, 100, "rewritten"); !errors.Is(err, ErrNotAuthor) {
+		t.Errorf("member edit: expected %v, got %v", ErrNotAuthor, err)
+	}
+	if err := svc.DeleteComment(ctx, 4, 10, 100); !errors.Is(err, ErrNotAuthor) {
+		t.Errorf("member delete: expected %v, got %v", ErrNotAuthor, err)
+	}
+}
+
+func TestCommentService_EditComment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTasks := mocks.NewMockTaskRepository(ctrl)
+	mockRepo := mocks.NewMockCommentRepository(ctrl)
+	svc := NewCommentService(mockRepo, mockTasks, mocks.NewMockProjectRepository(ctrl))
+
+	mockTasks.EXPECT().GetByID(gomock.Any(), int64(10)).Return(entity.Task{ID: 10, UserID: 1}, nil).AnyTimes()
+
+	tests := []struct {
+		name      string
+		cid       int64
+		body      string
+		mockSetup func()
+		wantErr   error
+	}{
+		{
+			name: "author",
+			cid:  100,
+			body: " fixed ",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetByID(gomock.Any(), int64(100)).
+					Return(entity.Comment{ID: 100, TaskID: 10, UserID: 1}, nil)
+				mockRepo.EXPECT().
+					UpdateBody(gomock.Any(), int64(100), "fixed").
+					Return(entity.Comment{ID: 100, TaskID: 10, UserID: 1, Body: "fixed", Edited: true}, nil)
+			},
+		},
+		{
+			name: "not the author",
+			cid:  101,
+			body: "rewritten",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetByID(gomock.Any(), int64(101)).
+					Return(entity.Comment{ID: 101, TaskID: 10, UserID: 2}, nil)
+			},
+			wantErr: ErrNotAuthor,
+		},
+		{
+			name: "comment on another task",
+			cid:  102,
+			body: "rewritten",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetByID(gomock.Any(), int64(102)).
+					Return(entity.Comment{ID: 102, TaskID: 11, UserID: 1}, nil)
+			},
+			wantErr: ErrCommentNotFound,
+		},
+		{
+			name: "empty body",
+			cid:  103,
+			body: "",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetByID(gomock.Any(), int64(103)).
+					Return(entity.Comment{ID: 103, TaskID: 10, UserID: 1}, nil)
+			},
+			wantErr: ErrEmptyComment,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			_, err := svc.EditComment(context.Background(), 1, 10, tt.cid, tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCommentService_DeleteComment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTasks := mocks.NewMockTaskRepository(ctrl)
+	mockRepo := mocks.NewMockCommentRepository(ctrl)
+	svc := NewCommentService(mockRepo, mockTasks, mocks.NewMockProjectRepository(ctrl))
+	ctx := context.Background()
+
+	mockTasks.EXPECT().GetByID(gomock.Any(), int64(10)).Return(entity.Task{ID: 10, UserID: 1}, nil).AnyTimes()
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(100)).Return(entity.Comment{ID: 100, TaskID: 10, UserID: 1}, nil)
+	mockRepo.EXPECT().Delete(gomock.Any(), int64(100)).Return(nil)
+	if err := svc.DeleteComment(ctx, 1, 10, 100); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Even the task owner may not delete someone else's comment.
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(103)).Return(entity.Comment{ID: 103, TaskID: 10, UserID: 2}, nil)
+	if err := svc.DeleteComment(ctx, 1, 10, 103); !errors.Is(err, ErrNotAuthor) {
+		t.Errorf("expected %v, got %v", ErrNotAuthor, err)
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(101)).Return(entity.Comment{ID: 101, TaskID: 11, UserID: 1}, nil)
+	if err := svc.DeleteComment(ctx, 1, 10, 101); !errors.Is(err, ErrCommentNotFound) {
+		t.Errorf("expected %v, got %v", ErrCommentNotFound, err)
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(102)).Return(entity.Comment{ID: 102, TaskID: 10, UserID: 1}, nil)
+	mockRepo.EXPECT().Delete(gomock.Any(), int64(102)).Return(sql.ErrNoRows)
+	if err := svc.DeleteComment(ctx, 1, 10, 102); !errors.Is(err, ErrCommentNotFound) {
+		t.Errorf("expected %v, got %v", ErrCommentNotFound, err)
+	}
+}
+
+func TestCommentService_ListComments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTasks := mocks.NewMockTaskRepository(ctrl)
+	mockRepo := mocks.NewMockCommentRepository(ctrl)
+	svc := NewCommentService(mockRepo, mockTasks, mocks.NewMockProjectRepository(ctrl))
+
+	p1, p2 := int64(1), int64(2)
+	mockTasks.EXPECT().GetByID(gomock.Any(), int64(10)).Return(entity.Task{ID: 10, UserID: 1}, nil)
+	mockRepo.EXPECT().
+		ListTopLevel(gomock.Any(), int64(10), nil, int64(0), 3).
+		Return([]entity.Comment{{ID: 1}, {ID: 2}, {ID: 3}}, nil)
+	mockRepo.EXPECT().
+		ListReplies(gomock.Any(), []int64{1, 2}).
+		Return([]entity.Comment{{ID: 4, ParentID: &p2}, {ID: 5, ParentID: &p1}, {ID: 6, ParentID: &p2}}, nil)
+
+	page, err := svc.ListComments(context.Background(), 1, 10, "", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Comments) != 2 || page.NextCursor == "" {
+		t.Fatalf("expected 2 comments and a cursor, got %d, %q", len(page.Comments), page.NextCursor)
+	}
+	if n := len(page.Comments[0].Replies); n != 1 {
+		t.Errorf("expected 1 reply on comment 1, got %d", n)
+	}
+	if n := len(page.Comments[1].Replies); n != 2 {
+		t.Errorf("expected 2 replies on comment 2, got %d", n)
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"strconv"
+	"time"
+)
+
+// taskCursor is the keyset position after the last task of a page.
+// It is handed to clients as an opaque base64 token.
+type taskCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+func encodeCursor(c taskCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (taskCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return taskCursor{}, ErrBadCursor
+	}
+	var c taskCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID <= 0 {
+		return taskCursor{}, ErrBadCursor
+	}
+	return c, nil
+}
+
+func taskSortValue(t entity.Task, sortBy string) string {
+	switch sortBy {
+	case SortUpdatedAt:
+		return t.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case SortDueAt:
+		if t.DueAt == nil {
+			return "infinity"
+		}
+		return t.DueAt.UTC().Format(time.RFC3339Nano)
+	case SortPriority:
+		return strconv.FormatInt(t.Priority, 10)
+	case SortTitle:
+		return t.Title
+	case SortEstimate:
+		return optSortValue(t.EstimateMinutes)
+	case SortPoints:
+		return optSortValue(t.StoryPoints)
+	case SortRank:
+		return t.Rank
+	default:
+		return t.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// optSortValue mirrors the COALESCE the repository sorts unset estimates
+// with.
+func optSortValue(v *int64) string {
+	if v == nil {
+		return "2147483647"
+	}
+	return strconv.FormatInt(*v, 10)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"sort"
+	"time"
+)
+
+// EffortByPoints is the tracked time of the done tasks sharing one story
+// point value.
+type EffortByPoints struct {
+	Points       int64
+	Tasks        int
+	SpentSeconds int64
+}
+
+// EffortReport compares estimated and tracked effort of done tasks. Tasks
+// nobody tracked time on are only counted in Untracked: an empty actual
+// would make every estimate look too high.
+type EffortReport struct {
+	From     *time.Time
+	To       *time.Time
+	Location *time.Location
+
+	Tasks     int
+	Untracked int
+
+	// Tasks estimated in minutes.
+	Estimated       int
+	EstimateMinutes int64
+	SpentSeconds    int64
+	Over            int
+	Under           int
+
+	// Tasks estimated in story points.
+	Pointed        int
+	Points         int64
+	PointedSeconds int64
+	ByPoints       []EffortByPoints
+}
+
+// Ratio is tracked time per estimated time; above 1 means tasks take
+// longer than planned.
+func (r EffortReport) Ratio() float64 {
+	if r.EstimateMinutes == 0 {
+		return 0
+	}
+	return float64(r.SpentSeconds) / float64(r.EstimateMinutes*60)
+}
+
+// SecondsPerPoint is the tracked time one story point stood for.
+func (r EffortReport) SecondsPerPoint() float64 {
+	if r.Points == 0 {
+		return 0
+	}
+	return float64(r.PointedSeconds) / float64(r.Points)
+}
+
+func buildEffortReport(list []entity.TaskEffort) EffortReport {
+	var rep EffortReport
+	byPoints := make(map[int64]int)
+	for _, e := range list {
+		rep.Tasks++
+		if e.SpentSeconds == 0 {
+			rep.Untracked++
+			continue
+		}
+
+		if e.EstimateMinutes != nil {
+			est := *e.EstimateMinutes * 60
+			rep.Estimated++
+			rep.EstimateMinutes += *e.EstimateMinutes
+			rep.SpentSeconds += e.SpentSeconds
+			switch {
+			case e.SpentSeconds > est:
+				rep.Over++
+			case e.SpentSeconds < est:
+				rep.Under++
+			}
+		}
+
+		if e.StoryPoints != nil {
+			rep.Pointed++
+			rep.Points += *e.StoryPoints
+			rep.PointedSeconds += e.SpentSeconds
+
+			i, ok := byPoints[*e.StoryPoints]
+			if !ok {
+				i = len(rep.ByPoints)
+				byPoints[*e.StoryPoints] = i
+				rep.ByPoints = append(rep.ByPoints, EffortByPoints{Points: *e.StoryPoints})
+			}
+			rep.ByPoints[i].Tasks++
+			rep.ByPoints[i].SpentSeconds += e.SpentSeconds
+		}
+	}
+	sort.Slice(rep.ByPoints, func(i, j int) bool { return rep.ByPoints[i].Points < rep.ByPoints[j].Points })
+	return rep
+}
+
+// EffortReport compares estimates with tracked time for the done tasks of
+// a user, optionally only those completed between two dates (inclusive,
+// in tz). Either date may be left out for an open-ended range.
+func (s *TaskService) EffortReport(ctx context.Context, uid int64, from, to, tz string) (EffortReport, error) {
+	var start, end, last *time.Time
+	loc, err := parseTimeZone(tz)
+	if err != nil {
+		return EffortReport{}, err
+	}
+	switch {
+	case from != "" && to != "":
+		a, b, _, err := parseReportRange(from, to, tz)
+		if err != nil {
+			return EffortReport{}, err
+		}
+		start, end = &a, &b
+	case from != "":
+		a, err := time.ParseInLocation(time.DateOnly, from, loc)
+		if err != nil {
+			return EffortReport{}, ErrBadReportRange
+		}
+		start = &a
+	case to != "":
+		b, err := time.ParseInLocation(time.DateOnly, to, loc)
+		if err != nil {
+			return EffortReport{}, ErrBadReportRange
+		}
+		b = b.AddDate(0, 0, 1)
+		end = &b
+	}
+
+	list, err := s.repo.ListEffort(ctx, uid, start, end)
+	if err != nil {
+		return EffortReport{}, err
+	}
+	rep := buildEffortReport(list)
+	rep.Location = loc
+	if end != nil {
+		l := end.AddDate(0, 0, -1)
+		last = &l
+	}
+	rep.From, rep.To = start, last
+	return rep, nil
+}
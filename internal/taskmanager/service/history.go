@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+)
+
+const sortHistory = "history"
+
+type HistoryPage struct {
+	Events     []entity.TaskEvent
+	NextCursor string
+}
+
+// TaskHistory pages through the recorded changes of a task, newest first.
+// The owner and the assignee may read it.
+func (s *TaskService) TaskHistory(ctx context.Context, uid, tid int64, cursor string, limit int) (HistoryPage, error) {
+	if _, err := s.GetTaskForUser(ctx, uid, tid); err != nil {
+		return HistoryPage{}, err
+	}
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	var beforeID int64
+	if cursor != "" {
+		c, err := decodeCursor(cursor)
+		if err != nil {
+			return HistoryPage{}, err
+		}
+		if c.Sort != sortHistory {
+			return HistoryPage{}, ErrBadCursor
+		}
+		beforeID = c.ID
+	}
+
+	events, err := s.repo.GetHistory(ctx, tid, beforeID, limit+1)
+	if err != nil {
+		return HistoryPage{}, err
+	}
+
+	page := HistoryPage{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.NextCursor = encodeCursor(taskCursor{Sort: sortHistory, Desc: true, ID: page.Events[limit-1].ID})
+	}
+	return page, nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"strings"
+	"time"
+)
+
+const MaxImportRows = 10000
+
+var (
+	ErrBadDueAt     = errors.New("bad due date")
+	ErrImportEmpty  = errors.New("nothing to import")
+	ErrImportTooBig = errors.New("too many rows to import")
+
+	errImportRollback = errors.New("import rolled back")
+)
+
+// ImportRow is one task read from an import file. Row is its 1-based
+// position among the records, for the report. Err is set by the decoder
+// when the row could not be read at all, e.g. a priority that is not a
+// number.
+type ImportRow struct {
+	Row         int
+	Title       string
+	Description string
+	Status      string
+	Priority    int64
+	DueAt       string
+	WorkflowID  *int64
+	Err         error
+}
+
+type ImportError struct {
+	Row int
+	Err error
+}
+
+type ImportReport struct {
+	Rows    int
+	Created int
+	DryRun  bool
+	Errors  []ImportError
+}
+
+// ExportTasks streams every live task of a user to fn.
+func (s *TaskService) ExportTasks(ctx context.Context, uid int64, fn func(entity.Task) error) error {
+	return s.repo.EachByUserID(ctx, uid, fn)
+}
+
+// ImportTasks creates a task for every row through CreateTask, each in its
+// own savepoint so that one bad row does not hide the others. The import is
+// all or nothing: when any row fails, or on a dry run, the transaction is
+// rolled back and only the report is returned.
+func (s *TaskService) ImportTasks(ctx context.Context, uid int64, rows []ImportRow, dryRun bool) (ImportReport, error) {
+	if len(rows) == 0 {
+		return ImportReport{}, ErrImportEmpty
+	}
+	if len(rows) > MaxImportRows {
+		return ImportReport{}, ErrImportTooBig
+	}
+
+	report := ImportReport{Rows: len(rows), DryRun: dryRun}
+	created := 0
+
+	err := s.inTx(ctx, func(tx *TaskService) error {
+		for _, row := range rows {
+			if row.Err != nil {
+				report.Errors = append(report.Errors, ImportError{Row: row.Row, Err: row.Err})
+				continue
+			}
+
+			var rowErr error
+			err := tx.inTx(ctx, func(sp *TaskService) error {
+				rowErr = sp.importRow(ctx, uid, row)
+				return rowErr
+			})
+			if err != nil && rowErr == nil {
+				return err
+			}
+			if rowErr != nil {
+				report.Errors = append(report.Errors, ImportError{Row: row.Row, Err: rowErr})
+				continue
+			}
+			created++
+		}
+		if dryRun || len(report.Errors) > 0 {
+			return errImportRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRollback) {
+		return ImportReport{}, err
+	}
+	if err == nil {
+		report.Created = created
+	}
+	return report, nil
+}
+
+func (s *TaskService) importRow(ctx context.Context, uid int64, row ImportRow) error {
+	var due *time.Time
+	if v := strings.TrimSpace(row.DueAt); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return ErrBadDueAt
+		}
+		due = &t
+	}
+
+	var opts []TaskOption
+	if row.WorkflowID != nil {
+		opts = append(opts, WithWorkflow(*row.WorkflowID))
+	}
+	_, err := s.CreateTask(ctx, uid, strings.TrimSpace(row.Title), row.Description, strings.TrimSpace(row.Status), row.Priority, due, opts...)
+	return err
+}
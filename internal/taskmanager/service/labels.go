@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DefaultLabelColor = "#808080"
+	MaxLabelNameLen   = 50
+)
+
+var (
+	ErrEmptyLabelName = errors.New("empty label name")
+	ErrBadLabelName   = errors.New("bad label name")
+	ErrBadColor       = errors.New("bad color")
+	ErrLabelNotFound  = errors.New("label not found")
+	ErrLabelExists    = errors.New("label already exists")
+	ErrBadLabel       = errors.New("bad label")
+)
+
+var colorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type LabelService struct {
+	repo storage.LabelRepository
+}
+
+func NewLabelService(repo storage.LabelRepository) *LabelService {
+	return &LabelService{repo: repo}
+}
+
+func validateLabelName(name string) error {
+	if name == "" {
+		return ErrEmptyLabelName
+	}
+	if utf8.RuneCountInString(name) > MaxLabelNameLen || strings.Contains(name, ",") {
+		return ErrBadLabelName
+	}
+	return nil
+}
+
+func (s *LabelService) CreateLabel(ctx context.Context, userID int64, name, color string) (entity.Label, error) {
+	name = strings.TrimSpace(name)
+	if err := validateLabelName(name); err != nil {
+		return entity.Label{}, err
+	}
+	if color == "" {
+		color = DefaultLabelColor
+	}
+	if !colorRe.MatchString(color) {
+		return entity.Label{}, ErrBadColor
+	}
+
+	l := &entity.Label{UserID: userID, Name: name, Color: color}
+	if err := s.repo.Create(ctx, l); err != nil {
+		if errors.Is(err, storage.ErrDuplicate) {
+			return entity.Label{}, ErrLabelExists
+		}
+		return entity.Label{}, err
+	}
+	return *l, nil
+}
+
+func (s *LabelService) ListLabels(ctx context.Context, userID int64) ([]entity.Label, error) {
+	return s.repo.GetByUserID(ctx, userID)
+}
+
+func (s *LabelService) GetLabel(ctx context.Context, userID, id int64) (entity.Label, error) {
+	l, err := s.repo.GetByID(ctx, id)
+	if err != nil || l.UserID != userID {
+		return entity.Label{}, ErrLabelNotFound
+	}
+	return l, nil
+}
+
+func (s *LabelService) PatchLabel(ctx context.Context, userID, id int64, name, color *string) (entity.Label, error) {
+	if _, err := s.GetLabel(ctx, userID, id); err != nil {
+		return entity.Label{}, err
+	}
+	if name != nil {
+		trimmed := strings.TrimSpace(*name)
+		if err := validateLabelName(trimmed); err != nil {
+			return entity.Label{}, err
+		}
+		name = &trimmed
+	}
+	if color != nil && !colorRe.MatchString(*color) {
+		return entity.Label{}, ErrBadColor
+	}
+
+	l, err := s.repo.Patch(ctx, id, name, color)
+	if errors.Is(err, storage.ErrDuplicate) {
+		return entity.Label{}, ErrLabelExists
+	}
+	return l, err
+}
+
+func (s *LabelService) DeleteLabel(ctx context.Context, userID, id int64) error {
+	if _, err := s.GetLabel(ctx, userID, id); err != nil {
+		return err
+	}
+	err := s.repo.Delete(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrLabelNotFound
+	}
+	return err
+}
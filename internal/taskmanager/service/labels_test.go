@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/mocks"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"github.com/golang/mock/gomock"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateLabelName(t *testing.T) {
+	tests := []struct {
+		name    string
+		label   string
+		wantErr error
+	}{
+		{name: "ok", label: "bug"},
+		{name: "unicode at limit", label: strings.Repeat("ы", MaxLabelNameLen)},
+		{name: "empty", label: "", wantErr: ErrEmptyLabelName},
+		{name: "too long", label: strings.Repeat("a", MaxLabelNameLen+1), wantErr: ErrBadLabelName},
+		{name: "comma", label: "bug,urgent", wantErr: ErrBadLabelName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateLabelName(tt.label); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLabelService_CreateLabel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockLabelRepository(ctrl)
+	svc := NewLabelService(mockRepo)
+
+	tests := []struct {
+		name      string
+		label     string
+		color     string
+		mockSetup func()
+		wantColor string
+		wantErr   error
+	}{
+		{
+			name:  "default color",
+			label: "  bug ",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					Create(gomock.Any(), &entity.Label{UserID: 1, Name: "bug", Color: DefaultLabelColor}).
+					Return(nil)
+			},
+			wantColor: DefaultLabelColor,
+		},
+		{
+			name:  "custom color",
+			label: "urgent",
+			color: "#FF0000",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					Create(gomock.Any(), gomock.Any()).
+					Return(nil)
+			},
+			wantColor: "#FF0000",
+		},
+		{
+			name:      "blank name",
+			label:     "   ",
+			mockSetup: func() {},
+			wantErr:   ErrEmptyLabelName,
+		},
+		{
+			name:      "bad color",
+			label:     "bug",
+			color:     "red",
+			mockSetup: func() {},
+			wantErr:   ErrBadColor,
+		},
+		{
+			name:      "short color",
+			label:     "bug",
+			color:     "#fff",
+			mockSetup: func() {},
+			wantErr:   ErrBadColor,
+		},
+		{
+			name:  "duplicate",
+			label: "bug",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					Create(gomock.Any(), gomock.Any()).
+					Return(storage.ErrDuplicate)
+			},
+			wantErr: ErrLabelExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			l, err := svc.CreateLabel(context.Background(), 1, tt.label, tt.color)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && l.Color != tt.wantColor {
+				t.Errorf("expected color %q, got %q", tt.wantColor, l.Color)
+			}
+		})
+	}
+}
+
+func TestLabelService_UserScoping(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockLabelRepository(ctrl)
+	svc := NewLabelService(mockRepo)
+	ctx := context.Background()
+
+	foreign := entity.Label{ID: 7, UserID: 2, Name: "theirs", Color: DefaultLabelColor}
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(7)).Return(foreign, nil).Times(3)
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(8)).Return(entity.Label{}, sql.ErrNoRows)
+
+	name := "mine"
+	if _, err := svc.GetLabel(ctx, 1, 7); !errors.Is(err, ErrLabelNotFound) {
+		t.Errorf("get: expected %v, got %v", ErrLabelNotFound, err)
+	}
+	if _, err := svc.PatchLabel(ctx, 1, 7, &name, nil); !errors.Is(err, ErrLabelNotFound) {
+		t.Errorf("patch: expected %v, got %v", ErrLabelNotFound, err)
+	}
+	if err := svc.DeleteLabel(ctx, 1, 7); !errors.Is(err, ErrLabelNotFound) {
+		t.Errorf("delete: expected %v, got %v", ErrLabelNotFound, err)
+	}
+	if _, err := svc.GetLabel(ctx, 1, 8); !errors.Is(err, ErrLabelNotFound) {
+		t.Errorf("missing: expected %v, got %v", ErrLabelNotFound, err)
+	}
+}
+
+func TestLabelService_PatchLabel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockLabelRepository(ctrl)
+	svc := NewLabelService(mockRepo)
+	ctx := context.Background()
+
+	own := entity.Label{ID: 3, UserID: 1, Name: "bug", Color: DefaultLabelColor}
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(3)).Return(own, nil).AnyTimes()
+
+	bad := "a,b"
+	if _, err := svc.PatchLabel(ctx, 1, 3, &bad, nil); !errors.Is(err, ErrBadLabelName) {
+		t.Errorf("expected %v, got %v", ErrBadLabelName, err)
+	}
+	color := "blue"
+	if _, err := svc.PatchLabel(ctx, 1, 3, nil, &color); !errors.Is(err, ErrBadColor) {
+		t.Errorf("expected %v, got %v", ErrBadColor, err)
+	}
+
+	padded, trimmed := " feature ", "feature"
+	mockRepo.EXPECT().
+		Patch(gomock.Any(), int64(3), &trimmed, nil).
+		Return(entity.Label{ID: 3, UserID: 1, Name: trimmed}, nil)
+	if l, err := svc.PatchLabel(ctx, 1, 3, &padded, nil); err != nil || l.Name != trimmed {
+		t.Errorf("expected renamed label, got %+v, %v", l, err)
+	}
+
+	mockRepo.EXPECT().
+		Patch(gomock.Any(), int64(3), gomock.Any(), nil).
+		Return(entity.Label{}, storage.ErrDuplicate)
+	if _, err := svc.PatchLabel(ctx, 1, 3, &trimmed, nil); !errors.Is(err, ErrLabelExists) {
+		t.Errorf("expected %v, got %v", ErrLabelExists, err)
+	}
+}
+
+func TestTaskService_ForeignLabel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+	ctx := context.Background()
+
+	mockRepo.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(storage.ErrForeignLabel)
+	if _, err := svc.CreateTask(ctx, 1, "Task", "", StatusTodo, 3, nil, WithLabels(7)); !errors.Is(err, ErrBadLabel) {
+		t.Errorf("create: expected %v, got %v", ErrBadLabel, err)
+	}
+
+	mockRepo.EXPECT().
+		GetByID(gomock.Any(), int64(5)).
+		Return(entity.Task{ID: 5, UserID: 1, Status: StatusTodo}, nil)
+	mockRepo.EXPECT().
+		Patch(gomock.Any(), int64(1), int64(5), gomock.Any()).
+		Return(entity.Task{}, storage.ErrForeignLabel)
+	if _, err := svc.PatchTask(ctx, 1, 5, entity.TaskPatch{AddLabels: []int64{7}}); !errors.Is(err, ErrBadLabel) {
+		t.Errorf("patch: expected %v, got %v", ErrBadLabel, err)
+	}
+}
+
+func TestTaskService_ListTasks_LabelFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+
+	tests := []struct {
+		name  string
+		query TaskListQuery
+		want  []string
+	}{
+		{name: "all labels", query: TaskListQuery{Labels: []string{"bug", "urgent"}}, want: []string{"bug", "urgent"}},
+		{name: "any label", query: TaskListQuery{Labels: []string{"bug", "urgent"}, AnyLabel: true}, want: []string{"bug", "urgent"}},
+		{name: "repeated label", query: TaskListQuery{Labels: []string{"bug", "urgent", "bug"}}, want: []string{"bug", "urgent"}},
+		{name: "no labels", query: TaskListQuery{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo.EXPECT().
+				List(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, f storage.TaskListFilter) ([]entity.Task, error) {
+					if f.UserID != 1 {
+						t.Errorf("expected tasks of user 1, got %d", f.UserID)
+					}
+					if !reflect.DeepEqual(f.Labels, tt.want) || f.AnyLabel != tt.query.AnyLabel {
+						t.Errorf("expected labels %v (any=%v), got %v (any=%v)", tt.want, tt.query.AnyLabel, f.Labels, f.AnyLabel)
+					}
+					return nil, nil
+				})
+
+			if _, err := svc.ListTasks(context.Background(), 1, tt.query); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
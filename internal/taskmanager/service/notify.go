@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/events"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"log"
+	"time"
+)
+
+// RecipientLister finds the users to notify about a task: its watchers
+// who have not muted it.
+type RecipientLister interface {
+	Recipients(ctx context.Context, taskID int64) ([]int64, error)
+}
+
+// SetPublisher makes the service publish an event, addressed to the task's
+// watchers, for every change it commits. Without a publisher no events are
+// sent.
+func (s *TaskService) SetPublisher(pub events.Publisher, watchers RecipientLister) {
+	s.pub = pub
+	s.watchers = watchers
+}
+
+// taskChange is a change to a task made by actor, waiting to be published.
+type taskChange struct {
+	typ   string
+	actor int64
+	task  entity.Task
+	at    time.Time
+	data  map[string]any
+}
+
+// record publishes a change. Inside a transaction the change is held back
+// until the transaction commits, and dropped if it does not.
+func (s *TaskService) record(ctx context.Context, c taskChange) {
+	if s.pending != nil {
+		*s.pending = append(*s.pending, c)
+		return
+	}
+	s.publish(ctx, c)
+}
+
+// publish sends a committed change. It is already stored, so a failure to
+// notify is only logged.
+func (s *TaskService) publish(ctx context.Context, c taskChange) {
+	if s.pub == nil {
+		return
+	}
+	watchers, err := s.watchers.Recipients(ctx, c.task.ID)
+	if err != nil {
+		log.Printf("events: watchers of task %d: %v", c.task.ID, err)
+		return
+	}
+	if err := s.pub.Publish(ctx, taskEvent(c, watchers)); err != nil {
+		log.Printf("events: task %d: %v", c.task.ID, err)
+	}
+}
+
+// inTx runs fn in a transaction, or in a savepoint when s is already in
+// one, and records the changes fn made once they are committed.
+func (s *TaskService) inTx(ctx context.Context, fn func(tx *TaskService) error) error {
+	var changes []taskChange
+	err := s.repo.InTx(ctx, func(repo storage.TaskRepository) error {
+		return fn(&TaskService{repo: repo, pending: &changes})
+	})
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		s.record(ctx, c)
+	}
+	return nil
+}
+
+// publishChange reports an update of a task; one that moved the task to
+// another status is reported as task.status_changed.
+func (s *TaskService) publishChange(ctx context.Context, actor int64, before, after entity.Task) {
+	c := taskChange{typ: events.TypeTaskUpdated, actor: actor, task: after, at: after.UpdatedAt}
+	if before.Status != after.Status {
+		c.typ = events.TypeTaskStatusChanged
+		c.data = map[string]any{"from_status": before.Status}
+	}
+	s.record(ctx, c)
+}
+
+// publishTouched announces a committed change that only moved the derived
+// fields of a task, such as its blockers.
+func (s *TaskService) publishTouched(ctx context.Context, actor, tid int64) {
+	if s.pub == nil && s.pending == nil {
+		return
+	}
+	t, err := s.repo.GetByID(ctx, tid)
+	if err != nil {
+		log.Printf("events: task %d: %v", tid, err)
+		return
+	}
+	s.record(ctx, taskChange{typ: events.TypeTaskUpdated, actor: actor, task: t})
+}
+
+func taskEvent(c taskChange, watchers []int64) events.Event {
+	to := make([]int64, 0, len(watchers))
+	for _, id := range watchers {
+		if id != c.actor {
+			to = append(to, id)
+		}
+	}
+	data := map[string]any{
+		"actor_id": c.actor,
+		"title":    c.task.Title,
+		"status":   c.task.Status,
+		"version":  c.task.Version,
+	}
+	for k, v := range c.data {
+		data[k] = v
+	}
+	at := c.at
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	return events.Event{
+		Type:       c.typ,
+		TaskID:     c.task.ID,
+		UserID:     c.task.UserID,
+		Watchers:   to,
+		OccurredAt: at,
+		Data:       data,
+	}
+}
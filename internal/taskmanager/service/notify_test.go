@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/events"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/mocks"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"github.com/golang/mock/gomock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, e events.Event) error {
+	p.events = append(p.events, e)
+	return nil
+}
+
+type staticRecipients []int64
+
+func (r staticRecipients) Recipients(context.Context, int64) ([]int64, error) {
+	return r, nil
+}
+
+func TestChangeEvent(t *testing.T) {
+	task := entity.Task{ID: 2, UserID: 1, Title: "report", Status: StatusDone, Version: 4}
+
+	e := taskEvent(taskChange{typ: events.TypeTaskUpdated, actor: 7, task: task}, []int64{1, 7, 9})
+	if e.Type != events.TypeTaskUpdated || e.TaskID != 2 || e.UserID != 1 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if !reflect.DeepEqual(e.Watchers, []int64{1, 9}) {
+		t.Errorf("watchers = %v, want the actor left out", e.Watchers)
+	}
+	if e.Data["actor_id"] != int64(7) || e.Data["status"] != StatusDone {
+		t.Errorf("unexpected data: %v", e.Data)
+	}
+
+	if e := taskEvent(taskChange{typ: events.TypeTaskUpdated, actor: 1, task: task}, []int64{1}); e.Watchers == nil || len(e.Watchers) != 0 {
+		t.Errorf("owner changing an unwatched task: watchers = %#v, want empty", e.Watchers)
+	}
+}
+
+func TestTaskService_PublishesChanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+	pub := &recordingPublisher{}
+	svc.SetPublisher(pub, staticRecipients{1, 7, 9})
+
+	assignee := int64(7)
+	cur := entity.Task{ID: 2, UserID: 1, AssigneeID: &assignee, Title: "old", Status: StatusTodo}
+	title := "renamed"
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(cur, nil)
+	mockRepo.EXPECT().Patch(gomock.Any(), int64(1), int64(2), gomock.Any()).
+		Return(entity.Task{ID: 2, UserID: 1, AssigneeID: &assignee, Title: title, Status: StatusTodo}, nil)
+
+	if _, err := svc.PatchTask(context.Background(), 1, 2, entity.TaskPatch{Title: &title}); err != nil {
+		t.Fatalf("PatchTask: %v", err)
+	}
+	if len(pub.events) != 1 || !reflect.DeepEqual(pub.events[0].Watchers, []int64{7, 9}) {
+		t.Fatalf("published %+v", pub.events)
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(cur, nil)
+	if _, err := svc.PatchTask(context.Background(), 1, 2, entity.TaskPatch{Title: new(string)}); err == nil {
+		t.Fatal("expected an error for an empty title")
+	}
+	if len(pub.events) != 1 {
+		t.Errorf("a failed update published an event")
+	}
+}
+
+func TestTaskService_PublishesStatusChangeAndDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+	pub := &recordingPublisher{}
+	svc.SetPublisher(pub, staticRecipients{1})
+
+	cur := entity.Task{ID: 2, UserID: 1, Title: "report", Status: StatusTodo}
+	done := cur
+	done.Status = StatusDone
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(cur, nil)
+	mockRepo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(done, nil)
+	if _, err := svc.UpdateTask(context.Background(), 1, 2, "report", "", StatusDone, 3, nil, nil); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(done, nil)
+	mockRepo.EXPECT().Delete(gomock.Any(), int64(2), nil).Return(nil)
+	if err := svc.DeleteTaskByUser(context.Background(), 1, 2, ChildrenBlock, nil); err != nil {
+		t.Fatalf("DeleteTaskByUser: %v", err)
+	}
+
+	if len(pub.events) != 2 {
+		t.Fatalf("published %d events, want 2", len(pub.events))
+	}
+	if e := pub.events[0]; e.Type != events.TypeTaskStatusChanged || e.Data["from_status"] != StatusTodo {
+		t.Errorf("status change: got %+v", e)
+	}
+	if e := pub.events[1]; e.Type != events.TypeTaskDeleted || e.Data["children"] != string(ChildrenBlock) || e.OccurredAt.IsZero() {
+		t.Errorf("delete: got %+v", e)
+	}
+}
+
+func TestTaskService_PublishesMovesAndDependencies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+	pub := &recordingPublisher{}
+	svc.SetPublisher(pub, staticRecipients{1})
+	ctx := context.Background()
+
+	task := entity.Task{ID: 2, UserID: 1, Title: "report", Status: StatusTodo, Version: 1}
+	bumped := func(v int64) entity.Task {
+		t := task
+		t.Version = v
+		return t
+	}
+	after := int64(3)
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(task, nil)
+	mockRepo.EXPECT().Move(gomock.Any(), int64(1), int64(2), &after, nil).Return(bumped(2), nil)
+	if _, err := svc.MoveTask(ctx, 1, 2, &after, nil); err != nil {
+		t.Fatalf("MoveTask: %v", err)
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(bumped(2), nil)
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(3)).Return(entity.Task{ID: 3, UserID: 1}, nil)
+	mockRepo.EXPECT().AddDependency(gomock.Any(), int64(2), int64(3)).Return(nil)
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(bumped(3), nil)
+	if err := svc.AddDependency(ctx, 1, 2, 3); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(bumped(3), nil)
+	mockRepo.EXPECT().RemoveDependency(gomock.Any(), int64(2), int64(3)).Return(nil)
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(bumped(4), nil)
+	if err := svc.RemoveDependency(ctx, 1, 2, 3); err != nil {
+		t.Fatalf("RemoveDependency: %v", err)
+	}
+
+	if len(pub.events) != 3 {
+		t.Fatalf("published %d events, want 3", len(pub.events))
+	}
+	for i, e := range pub.events {
+		if e.Type != events.TypeTaskUpdated || e.TaskID != 2 || e.Data["version"] != int64(i+2) {
+			t.Errorf("event %d: got %+v", i, e)
+		}
+	}
+}
+
+// TestTaskService_PublishesAfterCommit checks that changes made inside a
+// batch are published only once it commits, without the operations that
+// were rolled back.
+func TestTaskService_PublishesAfterCommit(t *testing.T) {
+	tests := []struct {
+		name string
+		mode BatchMode
+		want []string
+	}{
+		{"atomic", BatchAtomic, nil},
+		{"best effort", BatchBestEffort, []string{events.TypeTaskCreated, events.TypeTaskCreated}},
+	}
+	ops := []BatchOp{
+		{Op: BatchCreate, Title: "first"},
+		{Op: BatchCreate, Title: ""},
+		{Op: BatchCreate, Title: "third"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockTaskRepository(ctrl)
+			svc := NewTaskService(mockRepo)
+			pub := &recordingPublisher{}
+			svc.SetPublisher(pub, staticRecipients{1})
+
+			mockRepo.EXPECT().InTx(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, fn func(storage.TaskRepository) error) error {
+					if len(pub.events) != 0 {
+						t.Errorf("published before commit")
+					}
+					return fn(mockRepo)
+				}).AnyTimes()
+			mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, task *entity.Task) error {
+					task.CreatedAt = time.Now()
+					return nil
+				}).AnyTimes()
+
+			if _, _, err := svc.RunBatch(context.Background(), 1, ops, tt.mode); err != nil {
+				t.Fatalf("RunBatch: %v", err)
+			}
+			var got []string
+			for _, e := range pub.events {
+				got = append(got, e.Type)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("published %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+)
+
+var ErrBadMove = errors.New("bad move")
+
+// MoveTask reorders a task within its status column, placing it right
+// after afterID and right before beforeID. At least one neighbour is
+// required, and both must be tasks of the same owner and status. Only the
+// owner may do it.
+func (s *TaskService) MoveTask(ctx context.Context, uid, tid int64, afterID, beforeID *int64) (entity.Task, error) {
+	if afterID == nil && beforeID == nil {
+		return entity.Task{}, ErrBadMove
+	}
+	if afterID != nil && beforeID != nil && *afterID == *beforeID {
+		return entity.Task{}, ErrBadMove
+	}
+	cur, err := s.repo.GetByID(ctx, tid)
+	if err != nil {
+		return entity.Task{}, ErrTaskNotFound
+	}
+	if err := checkOwner(cur, uid); err != nil {
+		return entity.Task{}, err
+	}
+
+	out, err := s.repo.Move(ctx, uid, tid, afterID, beforeID)
+	switch {
+	case errors.Is(err, storage.ErrBadNeighbour):
+		return entity.Task{}, ErrBadMove
+	case errors.Is(err, sql.ErrNoRows):
+		return entity.Task{}, ErrTaskNotFound
+	case err != nil:
+		return entity.Task{}, err
+	}
+	s.publishChange(ctx, uid, cur, out)
+	return out, nil
+}
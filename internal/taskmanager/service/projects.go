@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	RoleOwner  = "owner"
+	RoleMember = "member"
+
+	MaxProjectNameLen = 100
+)
+
+var (
+	ErrEmptyProjectName = errors.New("empty project name")
+	ErrBadProjectName   = errors.New("bad project name")
+	ErrProjectNotFound  = errors.New("project not found")
+	ErrNotProjectOwner  = errors.New("only the project owner can do this")
+	ErrMemberExists     = errors.New("user is already a member")
+	ErrMemberNotFound   = errors.New("member not found")
+	ErrBadMember        = errors.New("bad member")
+)
+
+type ProjectService struct {
+	repo  storage.ProjectRepository
+	tasks *TaskService
+}
+
+func NewProjectService(repo storage.ProjectRepository, tasks *TaskService) *ProjectService {
+	return &ProjectService{repo: repo, tasks: tasks}
+}
+
+func validateProjectName(name string) error {
+	if name == "" {
+		return ErrEmptyProjectName
+	}
+	if utf8.RuneCountInString(name) > MaxProjectNameLen {
+		return ErrBadProjectName
+	}
+	return nil
+}
+
+// member returns the project together with the caller's membership. A
+// project the caller does not belong to is reported as not found.
+func (s *ProjectService) member(ctx context.Context, uid, pid int64) (entity.Project, entity.ProjectMember, error) {
+	m, err := s.repo.GetMember(ctx, pid, uid)
+	if err != nil {
+		return entity.Project{}, entity.ProjectMember{}, ErrProjectNotFound
+	}
+	p, err := s.repo.GetByID(ctx, pid)
+	if err != nil {
+		return entity.Project{}, entity.ProjectMember{}, ErrProjectNotFound
+	}
+	return p, m, nil
+}
+
+func (s *ProjectService) owner(ctx context.Context, uid, pid int64) (entity.Project, error) {
+	p, m, err := s.member(ctx, uid, pid)
+	if err != nil {
+		return entity.Project{}, err
+	}
+	if m.Role != RoleOwner {
+		return entity.Project{}, ErrNotProjectOwner
+	}
+	return p, nil
+}
+
+func (s *ProjectService) CreateProject(ctx context.Context, uid int64, name, description string) (entity.Project, error) {
+	name = strings.TrimSpace(name)
+	if err := validateProjectName(name); err != nil {
+		return entity.Project{}, err
+	}
+
+	p := &entity.Project{OwnerID: uid, Name: name, Description: description}
+	if err := s.repo.Create(ctx, p); err != nil {
+		if errors.Is(err, storage.ErrUnknownUser) {
+			return entity.Project{}, ErrUserNotFound
+		}
+		return entity.Project{}, err
+	}
+	return *p, nil
+}
+
+func (s *ProjectService) ListProjects(ctx context.Context, uid int64) ([]entity.Project, error) {
+	return s.repo.GetByMember(ctx, uid)
+}
+
+func (s *ProjectService) GetProject(ctx context.Context, uid, pid int64) (entity.Project, error) {
+	p, _, err := s.member(ctx, uid, pid)
+	return p, err
+}
+
+func (s *ProjectService) PatchProject(ctx context.Context, uid, pid int64, name, description *string) (entity.Project, error) {
+	if _, err := s.owner(ctx, uid, pid); err != nil {
+		return entity.Project{}, err
+	}
+	if name != nil {
+		trimmed := strings.TrimSpace(*name)
+		if err := validateProjectName(trimmed); err != nil {
+			return entity.Project{}, err
+		}
+		name = &trimmed
+	}
+
+	p, err := s.repo.Patch(ctx, pid, name, description)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Project{}, ErrProjectNotFound
+	}
+	return p, err
+}
+
+// DeleteProject removes the project. Its tasks stay with their owners and
+// simply lose the project reference.
+func (s *ProjectService) DeleteProject(ctx context.Context, uid, pid int64) error {
+	if _, err := s.owner(ctx, uid, pid); err != nil {
+		return err
+	}
+	err := s.repo.Delete(ctx, pid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProjectNotFound
+	}
+	return err
+}
+
+func (s *ProjectService) ListMembers(ctx context.Context, uid, pid int64) ([]entity.ProjectMember, error) {
+	if _, _, err := s.member(ctx, uid, pid); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, pid)
+}
+
+func (s *ProjectService) AddMember(ctx context.Context, uid, pid, memberID int64) (entity.ProjectMember, error) {
+	if _, err := s.owner(ctx, uid, pid); err != nil {
+		return entity.ProjectMember{}, err
+	}
+	if memberID <= 0 {
+		return entity.ProjectMember{}, ErrBadMember
+	}
+
+	m := &entity.ProjectMember{ProjectID: pid, UserID: memberID, Role: RoleMember}
+	err := s.repo.AddMember(ctx, m)
+	switch {
+	case errors.Is(err, storage.ErrDuplicate):
+		return entity.ProjectMember{}, ErrMemberExists
+	case errors.Is(err, storage.ErrUnknownUser):
+		return entity.ProjectMember{}, ErrBadMember
+	case err != nil:
+		return entity.ProjectMember{}, err
+	}
+	return *m, nil
+}
+
+// RemoveMember lets the owner remove anybody but themselves and lets any
+// other member leave the project.
+func (s *ProjectService) RemoveMember(ctx context.Context, uid, pid, memberID int64) error {
+	p, m, err := s.member(ctx, uid, pid)
+	if err != nil {
+		return err
+	}
+	if memberID == p.OwnerID {
+		return ErrBadMember
+	}
+	if m.Role != RoleOwner && memberID != uid {
+		return ErrNotProjectOwner
+	}
+
+	err = s.repo.RemoveMember(ctx, pid, memberID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMemberNotFound
+	}
+	return err
+}
+
+// ListTasks pages through the project's tasks regardless of who owns them.
+func (s *ProjectService) ListTasks(ctx context.Context, uid, pid int64, q TaskListQuery) (TaskPage, error) {
+	if _, _, err := s.member(ctx, uid, pid); err != nil {
+		return TaskPage{}, err
+	}
+	return s.tasks.ListProjectTasks(ctx, pid, q)
+}
+
+// canSeeTask reports whether uid may see t: its owner and assignee can, and
+// so can the members of its project.
+func canSeeTask(ctx context.Context, projects storage.ProjectRepository, t entity.Task, uid int64) bool {
+	if t.UserID == uid || isAssignee(t, uid) {
+		return true
+	}
+	if t.ProjectID == nil {
+		return false
+	}
+	_, err := projects.GetMember(ctx, *t.ProjectID, uid)
+	return err == nil
+}
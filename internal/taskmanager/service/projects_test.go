@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/mocks"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"github.com/golang/mock/gomock"
+	"testing"
+)
+
+// expectProjectMembers makes project 5, owned by user 1 with user 2 as a
+// plain member, visible to the mock. Anybody else is not a member.
+func expectProjectMembers(repo *mocks.MockProjectRepository) {
+	project := entity.Project{ID: 5, OwnerID: 1, Name: "Board"}
+	repo.EXPECT().GetByID(gomock.Any(), int64(5)).Return(project, nil).AnyTimes()
+	repo.EXPECT().
+		GetMember(gomock.Any(), int64(5), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pid, uid int64) (entity.ProjectMember, error) {
+			switch uid {
+			case 1:
+				return entity.ProjectMember{ProjectID: pid, UserID: uid, Role: RoleOwner}, nil
+			case 2:
+				return entity.ProjectMember{ProjectID: pid, UserID: uid, Role: RoleMember}, nil
+			}
+			return entity.ProjectMember{}, sql.ErrNoRows
+		}).
+		AnyTimes()
+}
+
+func TestProjectService_NonMember(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockProjectRepository(ctrl)
+	mockTasks := mocks.NewMockTaskRepository(ctrl)
+	svc := NewProjectService(mockRepo, NewTaskService(mockTasks))
+	expectProjectMembers(mockRepo)
+	ctx := context.Background()
+
+	// No task or member queries may run for an outsider.
+	if _, err := svc.GetProject(ctx, 3, 5); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("get: expected %v, got %v", ErrProjectNotFound, err)
+	}
+	if _, err := svc.ListTasks(ctx, 3, 5, TaskListQuery{}); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("list tasks: expected %v, got %v", ErrProjectNotFound, err)
+	}
+	if _, err := svc.ListMembers(ctx, 3, 5); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("list members: expected %v, got %v", ErrProjectNotFound, err)
+	}
+	if _, err := svc.AddMember(ctx, 3, 5, 3); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("add member: expected %v, got %v", ErrProjectNotFound, err)
+	}
+	if err := svc.RemoveMember(ctx, 3, 5, 2); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("remove member: expected %v, got %v", ErrProjectNotFound, err)
+	}
+}
+
+func TestProjectService_ListTasks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockProjectRepository(ctrl)
+	mockTasks := mocks.NewMockTaskRepository(ctrl)
+	svc := NewProjectService(mockRepo, NewTaskService(mockTasks))
+	expectProjectMembers(mockRepo)
+
+	mockTasks.EXPECT().
+		List(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, f storage.TaskListFilter) ([]entity.Task, error) {
+			if f.ProjectID == nil || *f.ProjectID != 5 || f.UserID != 0 {
+				t.Errorf("expected a project-wide filter, got %+v", f)
+			}
+			return []entity.Task{{ID: 1, UserID: 1}, {ID: 2, UserID: 2}}, nil
+		})
+
+	page, err := svc.ListTasks(context.Background(), 2, 5, TaskListQuery{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Tasks) != 2 {
+		t.Errorf("expected 2 tasks, got %d", len(page.Tasks))
+	}
+}
+
+func TestTaskService_CreateTask_NotProjectMember(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTasks := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockTasks)
+
+	mockTasks.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(storage.ErrNotProjectMember)
+
+	_, err := svc.CreateTask(context.Background(), 3, "Task", "", StatusTodo, 3, nil, WithProject(5))
+	if !errors.Is(err, ErrBadProject) {
+		t.Errorf("expected %v, got %v", ErrBadProject, err)
+	}
+}
+
+func TestProjectService_OwnerOnly(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockProjectRepository(ctrl)
+	svc := NewProjectService(mockRepo, nil)
+	expectProjectMembers(mockRepo)
+	ctx := context.Background()
+
+	name := "Renamed"
+	if _, err := svc.AddMember(ctx, 2, 5, 3); !errors.Is(err, ErrNotProjectOwner) {
+		t.Errorf("add member: expected %v, got %v", ErrNotProjectOwner, err)
+	}
+	if _, err := svc.PatchProject(ctx, 2, 5, &name, nil); !errors.Is(err, ErrNotProjectOwner) {
+		t.Errorf("patch: expected %v, got %v", ErrNotProjectOwner, err)
+	}
+	if err := svc.DeleteProject(ctx, 2, 5); !errors.Is(err, ErrNotProjectOwner) {
+		t.Errorf("delete: expected %v, got %v", ErrNotProjectOwner, err)
+	}
+}
+
+func TestProjectService_AddMember(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockProjectRepository(ctrl)
+	svc := NewProjectService(mockRepo, nil)
+	expectProjectMembers(mockRepo)
+
+	tests := []struct {
+		name      string
+		memberID  int64
+		mockSetup func()
+		wantErr   error
+	}{
+		{
+			name:     "success",
+			memberID: 3,
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					AddMember(gomock.Any(), &entity.ProjectMember{ProjectID: 5, UserID: 3, Role: RoleMember}).
+					Return(nil)
+			},
+		},
+		{
+			name:      "bad id",
+			memberID:  0,
+			mockSetup: func() {},
+			wantErr:   ErrBadMember,
+		},
+		{
+			name:     "already a member",
+			memberID: 2,
+			mockSetup: func() {
+				mockRepo.EXPECT().AddMember(gomock.Any(), gomock.Any()).Return(storage.ErrDuplicate)
+			},
+			wantErr: ErrMemberExists,
+		},
+		{
+			name:     "unknown user",
+			memberID: 99,
+			mockSetup: func() {
+				mockRepo.EXPECT().AddMember(gomock.Any(), gomock.Any()).Return(storage.ErrUnknownUser)
+			},
+			wantErr: ErrBadMember,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			_, err := svc.AddMember(context.Background(), 1, 5, tt.memberID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestProjectService_RemoveMember(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockProjectRepository(ctrl)
+	svc := NewProjectService(mockRepo, nil)
+	expectProjectMembers(mockRepo)
+
+	tests := []struct {
+		name      string
+		uid       int64
+		memberID  int64
+		mockSetup func()
+		wantErr   error
+	}{
+		{
+			name:     "owner removes a member",
+			uid:      1,
+			memberID: 2,
+			mockSetup: func() {
+				mockRepo.EXPECT().RemoveMember(gomock.Any(), int64(5), int64(2)).Return(nil)
+			},
+		},
+		{
+			name:     "member leaves",
+			uid:      2,
+			memberID: 2,
+			mockSetup: func() {
+				mockRepo.EXPECT().RemoveMember(gomock.Any(), int64(5), int64(2)).Return(nil)
+			},
+		},
+		{
+			name:      "owner cannot leave",
+			uid:       1,
+			memberID:  1,
+			mockSetup: func() {},
+			wantErr:   ErrBadMember,
+		},
+		{
+			name:      "member cannot remove the owner",
+			uid:       2,
+			memberID:  1,
+			mockSetup: func() {},
+			wantErr:   ErrBadMember,
+		},
+		{
+			name:      "member cannot remove others",
+			uid:       2,
+			memberID:  3,
+			mockSetup: func() {},
+			wantErr:   ErrNotProjectOwner,
+		},
+		{
+			name:     "not a member",
+			uid:      1,
+			memberID: 3,
+			mockSetup: func() {
+				mockRepo.EXPECT().RemoveMember(gomock.Any(), int64(5), int64(3)).Return(sql.ErrNoRows)
+			},
+			wantErr: ErrMemberNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			err := svc.RemoveMember(context.Background(), tt.uid, 5, tt.memberID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/events"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/rrule"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"time"
+)
+
+const (
+	DefaultUpcoming = 5
+	MaxUpcoming     = 100
+)
+
+var (
+	ErrBadRRule           = errors.New("bad rrule")
+	ErrRecurrenceNeedsDue = errors.New("recurring task needs a due date")
+	ErrNotRecurring       = errors.New("task is not recurring")
+	ErrBadSeriesPatch     = errors.New("only title, description, priority, estimates and labels can be changed for a series")
+)
+
+type Recurrence struct {
+	Series   entity.TaskSeries
+	Upcoming []time.Time
+}
+
+func parseRecurrence(rule string, dueAt *time.Time) (*rrule.Rule, error) {
+	if dueAt == nil {
+		return nil, ErrRecurrenceNeedsDue
+	}
+	r, err := rrule.Parse(rule, *dueAt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadRRule, err)
+	}
+	return r, nil
+}
+
+// schedulesNext reports whether moving cur to status completes an instance
+// of a series, so that the next one has to be created.
+func schedulesNext(cur entity.Task, status string) bool {
+	return status == StatusDone && cur.Status != StatusDone && cur.SeriesID != nil && cur.OccurrenceAt != nil
+}
+
+// completeWith runs write, which moves cur to status. When that completes
+// an instance of a series, the next instance is created in the same
+// transaction, so a failed write leaves neither of them behind.
+func (s *TaskService) completeWith(ctx context.Context, actor int64, wf entity.Workflow, cur entity.Task, status string, write func(tx *TaskService) error) error {
+	if !schedulesNext(cur, status) {
+		return write(s)
+	}
+	return s.inTx(ctx, func(tx *TaskService) error {
+		if err := tx.completing(ctx, actor, wf, cur); err != nil {
+			return err
+		}
+		return write(tx)
+	})
+}
+
+// completing creates the instance of cur's series that follows cur, in the
+// initial state of wf. The unique (series_id, occurrence_at) index turns a
+// retry into a no-op.
+func (s *TaskService) completing(ctx context.Context, actor int64, wf entity.Workflow, cur entity.Task) error {
+	series, err := s.repo.GetSeries(ctx, *cur.SeriesID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	rule, err := rrule.Parse(series.RRule, series.DTStart)
+	if err != nil {
+		return err
+	}
+	next, ok := rule.After(*cur.OccurrenceAt)
+	if !ok {
+		return nil
+	}
+
+	t := &entity.Task{
+		UserID:          cur.UserID,
+		AssigneeID:      cur.AssigneeID,
+		ProjectID:       cur.ProjectID,
+		WorkflowID:      cur.WorkflowID,
+		ParentID:        cur.ParentID,
+		Title:           cur.Title,
+		Description:     cur.Description,
+		Status:          wf.InitialState,
+		Priority:        cur.Priority,
+		DueAt:           &next,
+		EstimateMinutes: cur.EstimateMinutes,
+		StoryPoints:     cur.StoryPoints,
+		Labels:          cur.Labels,
+		SeriesID:        cur.SeriesID,
+		OccurrenceAt:    &next,
+	}
+	err = s.repo.Create(ctx, t)
+	if errors.Is(err, storage.ErrDuplicate) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.record(ctx, taskChange{typ: events.TypeTaskCreated, actor: actor, task: *t, at: t.CreatedAt})
+	return nil
+}
+
+func (s *TaskService) recurringTask(ctx context.Context, uid, tid int64) (entity.Task, entity.TaskSeries, error) {
+	cur, err := s.ownedTask(ctx, uid, tid)
+	if err != nil {
+		return entity.Task{}, entity.TaskSeries{}, err
+	}
+	if cur.SeriesID == nil {
+		return entity.Task{}, entity.TaskSeries{}, ErrNotRecurring
+	}
+	series, err := s.repo.GetSeries(ctx, *cur.SeriesID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Task{}, entity.TaskSeries{}, ErrNotRecurring
+	}
+	if err != nil {
+		return entity.Task{}, entity.TaskSeries{}, err
+	}
+	return cur, series, nil
+}
+
+// GetRecurrence returns the series of a task and up to n occurrences that
+// follow the task's own slot.
+func (s *TaskService) GetRecurrence(ctx context.Context, uid, tid int64, n int) (Recurrence, error) {
+	cur, series, err := s.recurringTask(ctx, uid, tid)
+	if err != nil {
+		return Recurrence{}, err
+	}
+	if n <= 0 {
+		n = DefaultUpcoming
+	}
+	if n > MaxUpcoming {
+		n = MaxUpcoming
+	}
+
+	rule, err := rrule.Parse(series.RRule, series.DTStart)
+	if err != nil {
+		return Recurrence{}, err
+	}
+	from := series.DTStart.Add(-time.Nanosecond)
+	if cur.OccurrenceAt != nil {
+		from = *cur.OccurrenceAt
+	}
+	return Recurrence{Series: series, Upcoming: rule.Upcoming(from, n)}, nil
+}
+
+// SetRecurrence starts a series at the task's due date or, for a task that
+// already recurs, replaces the rule of the whole series. A replaced rule is
+// re-anchored at the task's slot so that past instances are unaffected.
+func (s *TaskService) SetRecurrence(ctx context.Context, uid, tid int64, rule string) (Recurrence, error) {
+	cur, err := s.ownedTask(ctx, uid, tid)
+	if err != nil {
+		return Recurrence{}, err
+	}
+
+	anchor := cur.DueAt
+	if cur.SeriesID != nil && cur.OccurrenceAt != nil {
+		anchor = cur.OccurrenceAt
+	}
+	parsed, err := parseRecurrence(rule, anchor)
+	if err != nil {
+		return Recurrence{}, err
+	}
+
+	series := entity.TaskSeries{UserID: uid, RRule: parsed.String(), DTStart: *anchor}
+	if cur.SeriesID == nil {
+		err = s.repo.StartSeries(ctx, tid, &series)
+	} else {
+		series.ID = *cur.SeriesID
+		err = s.repo.UpdateSeries(ctx, &series)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return Recurrence{}, ErrTaskNotFound
+	}
+	if err != nil {
+		return Recurrence{}, err
+	}
+	return s.GetRecurrence(ctx, uid, tid, DefaultUpcoming)
+}
+
+// StopRecurrence ends the series. Instances created so far are kept.
+func (s *TaskService) StopRecurrence(ctx context.Context, uid, tid int64) error {
+	_, series, err := s.recurringTask(ctx, uid, tid)
+	if err != nil {
+		return err
+	}
+	err = s.repo.DeleteSeries(ctx, series.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotRecurring
+	}
+	return err
+}
+
+// PatchSeries applies p to every open instance of the task's series, the
+// task itself included, in one transaction. Fields that belong to a single
+// slot, such as the status or the due date, have to be changed per
+// instance.
+func (s *TaskService) PatchSeries(ctx context.Context, uid, tid int64, p entity.TaskPatch) (entity.Task, error) {
+	cur, series, err := s.recurringTask(ctx, uid, tid)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	if p.Status != nil || p.DueAtSet || p.ParentSet || p.ProjectSet || p.WorkflowSet {
+		return entity.Task{}, ErrBadSeriesPatch
+	}
+	if err := checkVersion(cur, p.IfVersion); err != nil {
+		return entity.Task{}, err
+	}
+
+	out := cur
+	err = s.inTx(ctx, func(tx *TaskService) error {
+		tasks, err := tx.repo.GetSeriesTasks(ctx, series.ID)
+		if err != nil {
+			return err
+		}
+		for _, t := range tasks {
+			if t.Status == StatusDone && t.ID != tid {
+				continue
+			}
+			tp := p
+			if t.ID != tid {
+				tp.IfVersion = nil
+			}
+			patched, err := tx.PatchTask(ctx, uid, t.ID, tp)
+			if err != nil {
+				return err
+			}
+			if t.ID == tid {
+				out = patched
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return entity.Task{}, err
+	}
+	return out, nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/events"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"log"
+	"sort"
+	"time"
+)
+
+const reminderBatch = 500
+
+// ReminderThreshold fires once per task and due date when the due date is
+// at most Before away. Before == 0 means "overdue".
+type ReminderThreshold struct {
+	Name   string
+	Before time.Duration
+}
+
+var DefaultReminderThresholds = []ReminderThreshold{
+	{Name: "24h", Before: 24 * time.Hour},
+	{Name: "1h", Before: time.Hour},
+	{Name: "overdue", Before: 0},
+}
+
+// ReminderClaimer marks the due reminders of a window as sent and returns
+// them; ok is false when another replica is doing the work.
+type ReminderClaimer interface {
+	Claim(ctx context.Context, w storage.ReminderWindow, limit int) (claimed []entity.TaskReminder, ok bool, err error)
+}
+
+type ReminderService struct {
+	repo    ReminderClaimer
+	pub     events.Publisher
+	windows []storage.ReminderWindow
+}
+
+// NewReminderService turns the thresholds into non-overlapping windows, so
+// a task that is created one hour before its due date gets the 1h reminder
+// but not a late 24h one.
+func NewReminderService(repo ReminderClaimer, pub events.Publisher, thresholds []ReminderThreshold) *ReminderService {
+	ths := append([]ReminderThreshold(nil), thresholds...)
+	sort.Slice(ths, func(i, j int) bool { return ths[i].Before > ths[j].Before })
+
+	windows := make([]storage.ReminderWindow, 0, len(ths))
+	for i, th := range ths {
+		w := storage.ReminderWindow{Threshold: th.Name, Lead: th.Before}
+		if i+1 < len(ths) {
+			until := ths[i+1].Before
+			w.Until = &until
+		}
+		windows = append(windows, w)
+	}
+	return &ReminderService{repo: repo, pub: pub, windows: windows}
+}
+
+// RunOnce scans every window and publishes the reminders that are due.
+// They are published only after the claim is committed, so a failed claim
+// sends nothing and is simply retried on the next scan. A failure to
+// publish is only logged: the reminder is already recorded as sent.
+func (s *ReminderService) RunOnce(ctx context.Context) (int, error) {
+	total := 0
+	for _, w := range s.windows {
+		for {
+			claimed, ok, err := s.repo.Claim(ctx, w, reminderBatch)
+			if err != nil {
+				return total, err
+			}
+			for _, rem := range claimed {
+				if err := s.pub.Publish(ctx, reminderEvent(rem)); err != nil {
+					log.Printf("reminders: task %d: %v", rem.TaskID, err)
+				}
+			}
+			total += len(claimed)
+			if !ok || len(claimed) < reminderBatch {
+				break
+			}
+		}
+	}
+	return total, nil
+}
+
+// Run scans every interval until ctx is cancelled.
+func (s *ReminderService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("reminders: %v", err)
+		} else if n > 0 {
+			log.Printf("reminders: sent %d", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func reminderEvent(rem entity.TaskReminder) events.Event {
+	data := map[string]any{
+		"threshold": rem.Threshold,
+		"title":     rem.Title,
+		"due_at":    rem.DueAt,
+	}
+	if rem.AssigneeID != nil {
+		data["assignee_id"] = *rem.AssigneeID
+	}
+	return events.Event{
+		Type:       events.TypeTaskReminder,
+		TaskID:     rem.TaskID,
+		UserID:     rem.UserID,
+		Watchers:   rem.Watchers,
+		OccurredAt: rem.SentAt,
+		Data:       data,
+	}
+}
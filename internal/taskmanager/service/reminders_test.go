@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/events"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"testing"
+	"time"
+)
+
+func TestNewReminderService_Windows(t *testing.T) {
+	svc := NewReminderService(nil, nil, []ReminderThreshold{
+		{Name: "overdue", Before: 0},
+		{Name: "24h", Before: 24 * time.Hour},
+		{Name: "1h", Before: time.Hour},
+	})
+
+	want := []struct {
+		name  string
+		lead  time.Duration
+		until *time.Duration
+	}{
+		{"24h", 24 * time.Hour, durationPtr(time.Hour)},
+		{"1h", time.Hour, durationPtr(0)},
+		{"overdue", 0, nil},
+	}
+
+	if len(svc.windows) != len(want) {
+		t.Fatalf("expected %d windows, got %d", len(want), len(svc.windows))
+	}
+	for i, w := range want {
+		got := svc.windows[i]
+		if got.Threshold != w.name || got.Lead != w.lead {
+			t.Errorf("window %d: got %s/%v, want %s/%v", i, got.Threshold, got.Lead, w.name, w.lead)
+		}
+		if (got.Until == nil) != (w.until == nil) || (got.Until != nil && *got.Until != *w.until) {
+			t.Errorf("window %d: unexpected upper bound %v", i, got.Until)
+		}
+	}
+}
+
+// fakeClaimer hands out its reminders for the "1h" window, or fails the
+// claim with err as a commit failure would.
+type fakeClaimer struct {
+	reminders []entity.TaskReminder
+	err       error
+}
+
+func (c *fakeClaimer) Claim(_ context.Context, w storage.ReminderWindow, _ int) ([]entity.TaskReminder, bool, error) {
+	if c.err != nil {
+		return nil, true, c.err
+	}
+	if w.Threshold != "1h" {
+		return nil, true, nil
+	}
+	return c.reminders, true, nil
+}
+
+func TestReminderService_RunOnce(t *testing.T) {
+	due := time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+	rem := entity.TaskReminder{TaskID: 2, UserID: 1, Threshold: "1h", Title: "report", DueAt: due, Watchers: []int64{1, 7}}
+	commitFailed := errors.New("commit failed")
+
+	tests := []struct {
+		name    string
+		claimer *fakeClaimer
+		want    int
+		wantErr error
+	}{
+		{name: "published after the claim", claimer: &fakeClaimer{reminders: []entity.TaskReminder{rem}}, want: 1},
+		{name: "nothing due", claimer: &fakeClaimer{}},
+		{name: "claim not committed", claimer: &fakeClaimer{reminders: []entity.TaskReminder{rem}, err: commitFailed}, wantErr: commitFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := &recordingPublisher{}
+			svc := NewReminderService(tt.claimer, pub, DefaultReminderThresholds)
+
+			n, err := svc.RunOnce(context.Background())
+			if !errors.Is(err, tt.wantErr) || n != tt.want {
+				t.Fatalf("got %d, %v; want %d, %v", n, err, tt.want, tt.wantErr)
+			}
+			if len(pub.events) != tt.want {
+				t.Fatalf("published %d events, want %d", len(pub.events), tt.want)
+			}
+			if tt.want > 0 {
+				if e := pub.events[0]; e.Type != events.TypeTaskReminder || e.TaskID != 2 || e.Data["threshold"] != "1h" {
+					t.Errorf("unexpected event: %+v", e)
+				}
+			}
+		})
+	}
+}
+
+func durationPtr(d time.Duration) *time.Duration { return &d }
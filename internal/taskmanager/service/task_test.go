@@ -2,14 +2,19 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/events"
 	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/mocks"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
 	"github.com/golang/mock/gomock"
+	"reflect"
 	"testing"
+	"time"
 )
 
-func TestIsValidStatus(t *testing.T) {
+func TestHasState(t *testing.T) {
 	tests := []struct {
 		name  string
 		input string
@@ -25,9 +30,9 @@ func TestIsValidStatus(t *testing.T) {
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			got := isValidStatus(tt.input)
+			got := hasState(DefaultWorkflow, tt.input)
 			if got != tt.want {
-				t.Errorf("isValidStatus(%s): got %v, want %v", tt.input, got, tt.want)
+				t.Errorf("hasState(%s): got %v, want %v", tt.input, got, tt.want)
 			}
 		})
 	}
@@ -57,6 +62,30 @@ func TestIsValidPriority(t *testing.T) {
 	}
 }
 
+func TestCheckEstimates(t *testing.T) {
+	n := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name            string
+		minutes, points *int64
+		want            error
+	}{
+		{"not estimated", nil, nil, nil},
+		{"both set", n(90), n(3), nil},
+		{"zero points", nil, n(0), nil},
+		{"max estimate", n(MaxEstimateMinutes), nil, nil},
+		{"zero minutes", n(0), nil, ErrBadEstimate},
+		{"too long", n(MaxEstimateMinutes + 1), nil, ErrBadEstimate},
+		{"negative points", nil, n(-1), ErrBadPoints},
+		{"too many points", nil, n(MaxStoryPoints + 1), ErrBadPoints},
+	}
+	for _, tt := range tests {
+		if err := checkEstimates(tt.minutes, tt.points); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
 func TestTaskService_CreateTask(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -71,6 +100,7 @@ func TestTaskService_CreateTask(t *testing.T) {
 		title     string
 		status    string
 		priority  int64
+		opts      []TaskOption
 		mockSetup func()
 		wantErr   error
 	}{
@@ -110,6 +140,24 @@ func TestTaskService_CreateTask(t *testing.T) {
 			mockSetup: func() {},
 			wantErr:   ErrBadPriority,
 		},
+		{
+			name:      "invalid estimate",
+			title:     "bob",
+			status:    StatusTodo,
+			priority:  3,
+			opts:      []TaskOption{WithEstimate(0)},
+			mockSetup: func() {},
+			wantErr:   ErrBadEstimate,
+		},
+		{
+			name:      "invalid story points",
+			title:     "bob",
+			status:    StatusTodo,
+			priority:  3,
+			opts:      []TaskOption{WithEstimate(30), WithStoryPoints(500)},
+			mockSetup: func() {},
+			wantErr:   ErrBadPoints,
+		},
 		{
 			name:     "repository error",
 			title:    "Task 1",
@@ -131,7 +179,7 @@ func TestTaskService_CreateTask(t *testing.T) {
 
 			_, err := svc.CreateTask(
 				context.Background(),
-				1, tt.title, "desc", tt.status, tt.priority, nil,
+				1, tt.title, "desc", tt.status, tt.priority, nil, tt.opts...,
 			)
 
 			if (err == nil) != (tt.wantErr == nil) {
@@ -169,6 +217,9 @@ func TestTaskService_UpdateTask(t *testing.T) {
 			status:   StatusTodo,
 			priority: 3,
 			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetByID(gomock.Any(), int64(0)).
+					Return(entity.Task{Status: StatusTodo}, nil)
 				mockRepo.EXPECT().
 					Update(gomock.Any(), gomock.Any()).
 					Return(entity.Task{
@@ -202,6 +253,9 @@ func TestTaskService_UpdateTask(t *testing.T) {
 			status:   "skebob",
 			priority: 3,
 			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetByID(gomock.Any(), int64(0)).
+					Return(entity.Task{Status: StatusTodo}, nil)
 				mockRepo.EXPECT().
 					Update(gomock.Any(), gomock.Any()).
 					Times(0)
@@ -226,6 +280,9 @@ func TestTaskService_UpdateTask(t *testing.T) {
 			status:   StatusTodo,
 			priority: 3,
 			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetByID(gomock.Any(), int64(0)).
+					Return(entity.Task{Status: StatusTodo}, nil)
 				mockRepo.EXPECT().
 					Update(
 						gomock.Any(),
@@ -241,7 +298,7 @@ func TestTaskService_UpdateTask(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockSetup()
 			_, err := svc.UpdateTask(context.Background(),
-				tt.uid, tt.tid, tt.title, "", tt.status, tt.priority, nil)
+				tt.uid, tt.tid, tt.title, "", tt.status, tt.priority, nil, nil)
 
 			if (err == nil) != (tt.wantErr == nil) {
 				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
@@ -252,3 +309,954 @@ func TestTaskService_UpdateTask(t *testing.T) {
 		})
 	}
 }
+
+func TestTaskService_ListTasks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+
+	low, high := int64(4), int64(2)
+	badPriority := int64(9)
+	badPoints := int64(-1)
+
+	tests := []struct {
+		name       string
+		query      TaskListQuery
+		mockSetup  func()
+		wantErr    error
+		wantLen    int
+		wantCursor bool
+	}{
+		{
+			name:  "last page",
+			query: TaskListQuery{Limit: 2},
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					List(gomock.Any(), gomock.Any()).
+					Return([]entity.Task{{ID: 1}}, nil)
+			},
+			wantLen: 1,
+		},
+		{
+			name:  "more pages",
+			query: TaskListQuery{Limit: 2, SortBy: SortPriority},
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					List(gomock.Any(), gomock.Any()).
+					Return([]entity.Task{{ID: 1}, {ID: 2}, {ID: 3}}, nil)
+			},
+			wantLen:    2,
+			wantCursor: true,
+		},
+		{
+			name:      "invalid sort",
+			query:     TaskListQuery{SortBy: "skebob"},
+			mockSetup: func() {},
+			wantErr:   ErrBadSort,
+		},
+		{
+			name:      "invalid status",
+			query:     TaskListQuery{Statuses: []string{"Skebob!"}},
+			mockSetup: func() {},
+			wantErr:   ErrBadStatus,
+		},
+		{
+			name:      "invalid priority",
+			query:     TaskListQuery{MaxPriority: &badPriority},
+			mockSetup: func() {},
+			wantErr:   ErrBadPriority,
+		},
+		{
+			name:      "inverted priority range",
+			query:     TaskListQuery{MinPriority: &low, MaxPriority: &high},
+			mockSetup: func() {},
+			wantErr:   ErrBadFilter,
+		},
+		{
+			name:      "invalid story points",
+			query:     TaskListQuery{MinPoints: &badPoints},
+			mockSetup: func() {},
+			wantErr:   ErrBadPoints,
+		},
+		{
+			name:      "inverted estimate range",
+			query:     TaskListQuery{MinEstimate: &low, MaxEstimate: &high},
+			mockSetup: func() {},
+			wantErr:   ErrBadFilter,
+		},
+		{
+			name:      "garbage cursor",
+			query:     TaskListQuery{Cursor: "%%%"},
+			mockSetup: func() {},
+			wantErr:   ErrBadCursor,
+		},
+		{
+			name: "cursor for another sort",
+			query: TaskListQuery{
+				SortBy: SortTitle,
+				Cursor: encodeCursor(taskCursor{Sort: SortPriority, Value: "3", ID: 7}),
+			},
+			mockSetup: func() {},
+			wantErr:   ErrBadCursor,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			page, err := svc.ListTasks(context.Background(), 1, tt.query)
+
+			if (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if len(page.Tasks) != tt.wantLen {
+				t.Errorf("expected %d tasks, got %d", tt.wantLen, len(page.Tasks))
+			}
+			if (page.NextCursor != "") != tt.wantCursor {
+				t.Errorf("expected cursor %v, got %q", tt.wantCursor, page.NextCursor)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	in := taskCursor{Sort: SortDueAt, Desc: true, Value: "infinity", ID: 42}
+	out, err := decodeCursor(encodeCursor(in))
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if out != in {
+		t.Errorf("round trip: got %+v, want %+v", out, in)
+	}
+}
+
+func TestTaskService_SearchTasks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+
+	if _, err := svc.SearchTasks(context.Background(), nil, "   ", 0, 0); !errors.Is(err, ErrEmptyQuery) {
+		t.Fatalf("expected %v, got %v", ErrEmptyQuery, err)
+	}
+
+	uid := int64(1)
+	mockRepo.EXPECT().
+		Search(gomock.Any(), &uid, "report", DefaultPageSize, 0).
+		Return([]entity.TaskSearchHit{{Task: entity.Task{ID: 1}}}, nil)
+
+	hits, err := svc.SearchTasks(context.Background(), &uid, " report ", 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hits) != 1 {
+		t.Errorf("expected 1 hit, got %d", len(hits))
+	}
+}
+
+func TestTaskService_SearchTasks_EscapesSnippets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+
+	mark := func(s string) string { return storage.SnippetStart + s + storage.SnippetStop }
+	mockRepo.EXPECT().
+		Search(gomock.Any(), nil, "script", DefaultPageSize, 0).
+		Return([]entity.TaskSearchHit{{
+			Task:               entity.Task{ID: 1, Title: "<script>alert(1)</script>"},
+			TitleSnippet:       "<" + mark("script") + ">alert(1)</" + mark("script") + ">",
+			DescriptionSnippet: `a & b "` + mark("script") + `"`,
+		}}, nil)
+
+	hits, err := svc.SearchTasks(context.Background(), nil, "script", 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "&lt;<mark>script</mark>&gt;alert(1)&lt;/<mark>script</mark>&gt;"; hits[0].TitleSnippet != want {
+		t.Errorf("title snippet: got %q, want %q", hits[0].TitleSnippet, want)
+	}
+	if want := "a &amp; b &#34;<mark>script</mark>&#34;"; hits[0].DescriptionSnippet != want {
+		t.Errorf("description snippet: got %q, want %q", hits[0].DescriptionSnippet, want)
+	}
+}
+
+func TestTaskService_PatchTask_Subtasks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+
+	done := StatusDone
+	parentID := int64(3)
+
+	tests := []struct {
+		name      string
+		patch     entity.TaskPatch
+		mockSetup func()
+		wantErr   error
+	}{
+		{
+			name:  "done with open subtasks",
+			patch: entity.TaskPatch{Status: &done},
+			mockSetup: func() {
+				mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).
+					Return(entity.Task{ID: 2, UserID: 1, Status: StatusInProgress, SubtasksTotal: 2, SubtasksDone: 1}, nil)
+			},
+			wantErr: ErrOpenSubtasks,
+		},
+		{
+			name:  "done with finished subtasks",
+			patch: entity.TaskPatch{Status: &done},
+			mockSetup: func() {
+				mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).
+					Return(entity.Task{ID: 2, UserID: 1, Status: StatusInProgress, SubtasksTotal: 2, SubtasksDone: 2}, nil)
+				mockRepo.EXPECT().Patch(gomock.Any(), int64(1), int64(2), gomock.Any()).
+					Return(entity.Task{ID: 2, UserID: 1, Status: StatusDone}, nil)
+			},
+		},
+		{
+			name:  "parent inside own subtree",
+			patch: entity.TaskPatch{ParentSet: true, ParentID: &parentID},
+			mockSetup: func() {
+				mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).
+					Return(entity.Task{ID: 2, UserID: 1}, nil)
+				mockRepo.EXPECT().GetByID(gomock.Any(), parentID).
+					Return(entity.Task{ID: parentID, UserID: 1}, nil)
+				mockRepo.EXPECT().GetSubtree(gomock.Any(), int64(2)).
+					Return([]entity.Task{{ID: parentID}}, nil)
+			},
+			wantErr: ErrBadParent,
+		},
+		{
+			name:  "parent of another user",
+			patch: entity.TaskPatch{ParentSet: true, ParentID: &parentID},
+			mockSetup: func() {
+				mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).
+					Return(entity.Task{ID: 2, UserID: 1}, nil)
+				mockRepo.EXPECT().GetByID(gomock.Any(), parentID).
+					Return(entity.Task{ID: parentID, UserID: 9}, nil)
+			},
+			wantErr: ErrBadParent,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			_, err := svc.PatchTask(context.Background(), 1, 2, tt.patch)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// expectTx makes the mock run the next transaction on itself.
+func expectTx(mockRepo *mocks.MockTaskRepository) {
+	mockRepo.EXPECT().InTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(storage.TaskRepository) error) error {
+			return fn(mockRepo)
+		})
+}
+
+func TestTaskService_DeleteTaskByUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+
+	withChildren := entity.Task{ID: 2, UserID: 1, SubtasksTotal: 1, Version: 4}
+	v4, v3 := int64(4), int64(3)
+
+	tests := []struct {
+		name      string
+		policy    ChildPolicy
+		ifVersion *int64
+		mockSetup func()
+		wantErr   error
+	}{
+		{
+			name:   "block by default",
+			policy: "",
+			mockSetup: func() {
+				mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(withChildren, nil)
+			},
+			wantErr: ErrHasSubtasks,
+		},
+		{
+			name:   "cascade",
+			policy: ChildrenCascade,
+			mockSetup: func() {
+				mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(withChildren, nil)
+				mockRepo.EXPECT().Delete(gomock.Any(), int64(2), nil).Return(nil)
+			},
+		},
+		{
+			name:   "orphan",
+			policy: ChildrenOrphan,
+			mockSetup: func() {
+				mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(withChildren, nil)
+				expectTx(mockRepo)
+				mockRepo.EXPECT().OrphanChildren(gomock.Any(), int64(2), nil).Return(nil)
+				mockRepo.EXPECT().Delete(gomock.Any(), int64(2), nil).Return(nil)
+			},
+		},
+		{
+			name:   "orphan rolled back when the delete fails",
+			policy: ChildrenOrphan,
+			mockSetup: func() {
+				mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(withChildren, nil)
+				expectTx(mockRepo)
+				mockRepo.EXPECT().OrphanChildren(gomock.Any(), int64(2), nil).Return(nil)
+				mockRepo.EXPECT().Delete(gomock.Any(), int64(2), nil).Return(sql.ErrNoRows)
+			},
+			wantErr: sql.ErrNoRows,
+		},
+		{
+			name:      "orphan with If-Match",
+			policy:    ChildrenOrphan,
+			ifVersion: &v4,
+			mockSetup: func() {
+				mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(withChildren, nil)
+				expectTx(mockRepo)
+				mockRepo.EXPECT().OrphanChildren(gomock.Any(), int64(2), &v4).Return(nil)
+				mockRepo.EXPECT().Delete(gomock.Any(), int64(2), &v4).Return(nil)
+			},
+		},
+		{
+			name:      "orphan with a stale If-Match",
+			policy:    ChildrenOrphan,
+			ifVersion: &v3,
+			mockSetup: func() {
+				mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(withChildren, nil)
+			},
+			wantErr: ErrVersionMismatch,
+		},
+		{
+			name:      "orphan after a concurrent change",
+			policy:    ChildrenOrphan,
+			ifVersion: &v4,
+			mockSetup: func() {
+				mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(withChildren, nil)
+				expectTx(mockRepo)
+				mockRepo.EXPECT().OrphanChildren(gomock.Any(), int64(2), &v4).Return(storage.ErrVersionMismatch)
+			},
+			wantErr: ErrVersionMismatch,
+		},
+		{
+			name:   "unknown policy",
+			policy: "skebob",
+			mockSetup: func() {
+				mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(withChildren, nil)
+			},
+			wantErr: ErrBadPolicy,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			err := svc.DeleteTaskByUser(context.Background(), 1, 2, tt.policy, tt.ifVersion)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		name   string
+		cur    entity.Task
+		status string
+		want   error
+	}{
+		{"start unblocked", entity.Task{Status: StatusTodo}, StatusInProgress, nil},
+		{"start blocked", entity.Task{Status: StatusTodo, OpenBlockers: 1}, StatusInProgress, ErrBlocked},
+		{"finish blocked", entity.Task{Status: StatusInProgress, OpenBlockers: 2}, StatusDone, ErrBlocked},
+		{"back to todo while blocked", entity.Task{Status: StatusInProgress, OpenBlockers: 1}, StatusTodo, nil},
+		{"unchanged status", entity.Task{Status: StatusInProgress, OpenBlockers: 1}, StatusInProgress, nil},
+		{"finish with open subtasks", entity.Task{Status: StatusInProgress, SubtasksTotal: 1}, StatusDone, ErrOpenSubtasks},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkTransition(DefaultWorkflow, tt.cur, tt.status, ""); !errors.Is(got, tt.want) {
+				t.Errorf("checkTransition: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTransition_CustomWorkflow(t *testing.T) {
+	wf := entity.Workflow{
+		InitialState: "backlog",
+		States:       []string{"backlog", "review", StatusDone},
+		Transitions: []entity.WorkflowTransition{
+			{From: "backlog", To: "review", RequiresDescription: true},
+			{From: "review", To: StatusDone, RequiresAssignee: true},
+			{From: "review", To: "backlog"},
+		},
+	}
+	assignee := int64(7)
+
+	tests := []struct {
+		name        string
+		cur         entity.Task
+		status      string
+		description string
+		want        error
+	}{
+		{"allowed move", entity.Task{Status: "backlog"}, "review", "spec", nil},
+		{"skipping a state", entity.Task{Status: "backlog"}, StatusDone, "spec", ErrBadTransition},
+		{"unknown state", entity.Task{Status: "backlog"}, StatusInProgress, "", ErrBadStatus},
+		{"missing description", entity.Task{Status: "backlog"}, "review", " ", ErrTransitionGuard},
+		{"missing assignee", entity.Task{Status: "review"}, StatusDone, "", ErrTransitionGuard},
+		{"with assignee", entity.Task{Status: "review", AssigneeID: &assignee}, StatusDone, "", nil},
+		{"blocked task to initial state", entity.Task{Status: "review", OpenBlockers: 1}, "backlog", "", nil},
+		{"blocked task forward", entity.Task{Status: "backlog", OpenBlockers: 1}, "review", "spec", ErrBlocked},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkTransition(wf, tt.cur, tt.status, tt.description); !errors.Is(got, tt.want) {
+				t.Errorf("checkTransition: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var te *TransitionError
+	err := checkTransition(wf, entity.Task{Status: "backlog"}, StatusDone, "")
+	if !errors.As(err, &te) || len(te.Allowed) != 1 || te.Allowed[0] != "review" {
+		t.Errorf("expected allowed states [review], got %v", err)
+	}
+}
+
+func TestValidateWorkflow(t *testing.T) {
+	tests := []struct {
+		name string
+		wf   entity.Workflow
+		want error
+	}{
+		{"valid", entity.Workflow{Name: "flow", States: []string{"new", StatusDone}, Transitions: []entity.WorkflowTransition{{From: "new", To: StatusDone}}}, nil},
+		{"empty name", entity.Workflow{Name: " ", States: []string{StatusDone}}, ErrEmptyWorkflowName},
+		{"no done state", entity.Workflow{Name: "flow", States: []string{"new"}}, ErrBadWorkflowDef},
+		{"duplicate state", entity.Workflow{Name: "flow", States: []string{StatusDone, StatusDone}}, ErrBadWorkflowDef},
+		{"bad state name", entity.Workflow{Name: "flow", States: []string{"In Review", StatusDone}}, ErrBadWorkflowDef},
+		{"unknown initial state", entity.Workflow{Name: "flow", InitialState: "new", States: []string{StatusDone}}, ErrBadWorkflowDef},
+		{"transition to unknown state", entity.Workflow{Name: "flow", States: []string{StatusDone}, Transitions: []entity.WorkflowTransition{{From: StatusDone, To: "new"}}}, ErrBadWorkflowDef},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateWorkflow(&tt.wf); !errors.Is(got, tt.want) {
+				t.Errorf("validateWorkflow: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskService_AddDependency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+
+	if err := svc.AddDependency(context.Background(), 1, 2, 2); !errors.Is(err, ErrBadDependency) {
+		t.Fatalf("self dependency: expected %v, got %v", ErrBadDependency, err)
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(entity.Task{ID: 2, UserID: 1}, nil)
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(3)).Return(entity.Task{ID: 3, UserID: 1}, nil)
+	mockRepo.EXPECT().AddDependency(gomock.Any(), int64(2), int64(3)).Return(storage.ErrDependencyCycle)
+
+	if err := svc.AddDependency(context.Background(), 1, 2, 3); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("cycle: expected %v, got %v", ErrDependencyCycle, err)
+	}
+}
+
+func TestTaskService_AssigneePermissions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+
+	assignee := int64(7)
+	task := entity.Task{ID: 2, UserID: 1, AssigneeID: &assignee, Status: StatusTodo}
+	doing := StatusInProgress
+	title := "renamed"
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(task, nil)
+	mockRepo.EXPECT().UpdateStatus(gomock.Any(), assignee, int64(2), StatusInProgress, nil).Return(nil)
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(entity.Task{ID: 2, UserID: 1, AssigneeID: &assignee, Status: StatusInProgress}, nil)
+
+	got, err := svc.PatchTask(context.Background(), assignee, 2, entity.TaskPatch{Status: &doing})
+	if err != nil || got.Status != StatusInProgress {
+		t.Fatalf("assignee status change: got %v, %v", got.Status, err)
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(task, nil)
+	if _, err := svc.PatchTask(context.Background(), assignee, 2, entity.TaskPatch{Status: &doing, Title: &title}); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("assignee title change: expected %v, got %v", ErrNotOwner, err)
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(task, nil)
+	if err := svc.DeleteTaskByUser(context.Background(), assignee, 2, "", nil); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("assignee delete: expected %v, got %v", ErrNotOwner, err)
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(task, nil)
+	if err := svc.DeleteTaskByUser(context.Background(), 9, 2, "", nil); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("stranger delete: expected %v, got %v", ErrTaskNotFound, err)
+	}
+}
+
+func TestTaskService_PatchTask_SchedulesNextOccurrence(t *testing.T) {
+	seriesID := int64(4)
+	slot := time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)
+	cur := entity.Task{ID: 2, UserID: 1, Title: "standup", Status: StatusInProgress, Priority: 3, DueAt: &slot, SeriesID: &seriesID, OccurrenceAt: &slot}
+	done := StatusDone
+
+	tests := []struct {
+		name       string
+		patchErr   error
+		wantErr    error
+		wantEvents []string
+	}{
+		{
+			name:       "success",
+			wantEvents: []string{events.TypeTaskCreated, events.TypeTaskStatusChanged},
+		},
+		{
+			name:     "status write fails",
+			patchErr: storage.ErrVersionMismatch,
+			wantErr:  ErrVersionMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockTaskRepository(ctrl)
+			svc := NewTaskService(mockRepo)
+			pub := &recordingPublisher{}
+			svc.SetPublisher(pub, staticRecipients{1})
+
+			mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(cur, nil)
+			// The next occurrence and the status write share one transaction.
+			mockRepo.EXPECT().InTx(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, fn func(storage.TaskRepository) error) error {
+					return fn(mockRepo)
+				})
+			mockRepo.EXPECT().GetSeries(gomock.Any(), seriesID).
+				Return(entity.TaskSeries{ID: seriesID, UserID: 1, RRule: "FREQ=WEEKLY;BYDAY=MO", DTStart: slot}, nil)
+			mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, next *entity.Task) error {
+				want := slot.AddDate(0, 0, 7)
+				if next.DueAt == nil || !next.DueAt.Equal(want) || next.Status != StatusTodo || *next.SeriesID != seriesID {
+					t.Errorf("unexpected next occurrence: %+v", next)
+				}
+				next.ID = 3
+				return nil
+			})
+			mockRepo.EXPECT().Patch(gomock.Any(), int64(1), int64(2), gomock.Any()).
+				Return(entity.Task{ID: 2, UserID: 1, Status: StatusDone}, tt.patchErr)
+
+			_, err := svc.PatchTask(context.Background(), 1, 2, entity.TaskPatch{Status: &done})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			var got []string
+			for _, e := range pub.events {
+				got = append(got, e.Type)
+			}
+			if !reflect.DeepEqual(got, tt.wantEvents) {
+				t.Errorf("events = %v, want %v", got, tt.wantEvents)
+			}
+			if len(pub.events) > 0 && pub.events[0].TaskID != 3 {
+				t.Errorf("task.created for task %d, want the next occurrence", pub.events[0].TaskID)
+			}
+		})
+	}
+}
+
+func TestTaskService_PatchSeries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+	pub := &recordingPublisher{}
+	svc.SetPublisher(pub, staticRecipients{1})
+
+	seriesID := int64(4)
+	slot := time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)
+	first := entity.Task{ID: 2, UserID: 1, Title: "standup", Status: StatusTodo, SeriesID: &seriesID, OccurrenceAt: &slot}
+	second := entity.Task{ID: 3, UserID: 1, Title: "standup", Status: StatusTodo, SeriesID: &seriesID}
+	title := "daily"
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(first, nil).Times(2)
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(3)).Return(second, nil)
+	mockRepo.EXPECT().GetSeries(gomock.Any(), seriesID).Return(entity.TaskSeries{ID: seriesID, UserID: 1}, nil)
+	mockRepo.EXPECT().GetSeriesTasks(gomock.Any(), seriesID).Return([]entity.Task{first, second}, nil)
+	mockRepo.EXPECT().InTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(storage.TaskRepository) error) error {
+			return fn(mockRepo)
+		})
+	mockRepo.EXPECT().Patch(gomock.Any(), int64(1), int64(2), gomock.Any()).
+		Return(entity.Task{ID: 2, UserID: 1, Title: title, Status: StatusTodo}, nil)
+	mockRepo.EXPECT().Patch(gomock.Any(), int64(1), int64(3), gomock.Any()).
+		Return(entity.Task{}, storage.ErrVersionMismatch)
+
+	if _, err := svc.PatchSeries(context.Background(), 1, 2, entity.TaskPatch{Title: &title}); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("expected %v, got %v", ErrVersionMismatch, err)
+	}
+	if len(pub.events) != 0 {
+		t.Errorf("a rolled back series patch published %d events", len(pub.events))
+	}
+}
+
+func TestTaskService_RestoreTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+
+	mockRepo.EXPECT().Restore(gomock.Any(), int64(1), int64(2)).Return(entity.Task{ID: 2, UserID: 1}, nil)
+	mockRepo.EXPECT().Restore(gomock.Any(), int64(1), int64(3)).Return(entity.Task{}, sql.ErrNoRows)
+
+	if got, err := svc.RestoreTask(context.Background(), 1, 2); err != nil || got.ID != 2 {
+		t.Fatalf("restore: got %+v, %v", got, err)
+	}
+	if _, err := svc.RestoreTask(context.Background(), 1, 3); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestTaskService_MoveTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+
+	assignee := int64(7)
+	task := entity.Task{ID: 2, UserID: 1, AssigneeID: &assignee, Status: StatusTodo}
+	after, before := int64(3), int64(4)
+
+	if _, err := svc.MoveTask(context.Background(), 1, 2, nil, nil); !errors.Is(err, ErrBadMove) {
+		t.Errorf("no neighbours: expected %v, got %v", ErrBadMove, err)
+	}
+	if _, err := svc.MoveTask(context.Background(), 1, 2, &after, &after); !errors.Is(err, ErrBadMove) {
+		t.Errorf("same neighbour twice: expected %v, got %v", ErrBadMove, err)
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(task, nil)
+	if _, err := svc.MoveTask(context.Background(), assignee, 2, &after, nil); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("assignee move: expected %v, got %v", ErrNotOwner, err)
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(task, nil)
+	mockRepo.EXPECT().Move(gomock.Any(), int64(1), int64(2), nil, &before).Return(entity.Task{}, storage.ErrBadNeighbour)
+	if _, err := svc.MoveTask(context.Background(), 1, 2, nil, &before); !errors.Is(err, ErrBadMove) {
+		t.Errorf("foreign neighbour: expected %v, got %v", ErrBadMove, err)
+	}
+
+	moved := task
+	moved.Rank = "i"
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(task, nil)
+	mockRepo.EXPECT().Move(gomock.Any(), int64(1), int64(2), &after, &before).Return(moved, nil)
+	if got, err := svc.MoveTask(context.Background(), 1, 2, &after, &before); err != nil || got.Rank != "i" {
+		t.Fatalf("move: got %+v, %v", got, err)
+	}
+}
+
+func TestTaskService_PatchTask_IfVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+
+	title := "renamed"
+	cur := entity.Task{ID: 2, UserID: 1, Title: "old", Status: StatusTodo, Version: 5}
+	stale, fresh := int64(4), int64(5)
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(cur, nil).Times(2)
+	mockRepo.EXPECT().Patch(gomock.Any(), int64(1), int64(2), gomock.Any()).
+		Return(entity.Task{}, storage.ErrVersionMismatch)
+
+	_, err := svc.PatchTask(context.Background(), 1, 2, entity.TaskPatch{Title: &title, IfVersion: &stale})
+	if !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("stale version: expected ErrVersionMismatch, got %v", err)
+	}
+	_, err = svc.PatchTask(context.Background(), 1, 2, entity.TaskPatch{Title: &title, IfVersion: &fresh})
+	if !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("concurrent write: expected ErrVersionMismatch, got %v", err)
+	}
+}
+
+func TestTaskService_RunBatch(t *testing.T) {
+	ops := []BatchOp{
+		{Op: BatchCreate, Title: "first"},
+		{Op: BatchCreate, Title: ""},
+		{Op: BatchCreate, Title: "third"},
+	}
+
+	tests := []struct {
+		name          string
+		mode          BatchMode
+		creates       int
+		wantCommitted bool
+		wantErrs      []error
+	}{
+		{"atomic", BatchAtomic, 1, false, []error{ErrRolledBack, ErrEmptyTitle, ErrNotRun}},
+		{"best effort", BatchBestEffort, 2, true, []error{nil, ErrEmptyTitle, nil}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockTaskRepository(ctrl)
+			svc := NewTaskService(mockRepo)
+
+			mockRepo.EXPECT().InTx(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, fn func(storage.TaskRepository) error) error {
+					return fn(mockRepo)
+				}).AnyTimes()
+			mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(tt.creates)
+
+			results, committed, err := svc.RunBatch(context.Background(), 1, ops, tt.mode)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if committed != tt.wantCommitted {
+				t.Errorf("committed: got %v, want %v", committed, tt.wantCommitted)
+			}
+			for i, want := range tt.wantErrs {
+				if !errors.Is(results[i].Err, want) {
+					t.Errorf("op %d: got %v, want %v", i, results[i].Err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTaskService_ImportTasks(t *testing.T) {
+	tests := []struct {
+		name        string
+		rows        []ImportRow
+		dryRun      bool
+		creates     int
+		wantCreated int
+		wantErrs    []error
+	}{
+		{
+			name: "all valid",
+			rows: []ImportRow{
+				{Row: 1, Title: "a", Priority: 2},
+				{Row: 2, Title: "b", Status: StatusDone, DueAt: "2025-09-05T18:00:00Z"},
+			},
+			creates:     2,
+			wantCreated: 2,
+		},
+		{
+			name: "dry run",
+			rows: []ImportRow{
+				{Row: 1, Title: "a"},
+			},
+			dryRun:  true,
+			creates: 1,
+		},
+		{
+			name: "row errors",
+			rows: []ImportRow{
+				{Row: 1, Title: "a"},
+				{Row: 2, Title: "", Status: StatusTodo},
+				{Row: 3, Title: "c", Status: "skebob"},
+				{Row: 4, Title: "d", Priority: 9},
+				{Row: 5, Title: "e", DueAt: "tomorrow"},
+				{Row: 6, Err: ErrBadPriority},
+			},
+			creates:  1,
+			wantErrs: []error{ErrEmptyTitle, ErrBadStatus, ErrBadPriority, ErrBadDueAt, ErrBadPriority},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockTaskRepository(ctrl)
+			svc := NewTaskService(mockRepo)
+
+			mockRepo.EXPECT().InTx(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, fn func(storage.TaskRepository) error) error {
+					return fn(mockRepo)
+				}).AnyTimes()
+			mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(tt.creates)
+
+			report, err := svc.ImportTasks(context.Background(), 1, tt.rows, tt.dryRun)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if report.Created != tt.wantCreated {
+				t.Errorf("created: got %d, want %d", report.Created, tt.wantCreated)
+			}
+			if len(report.Errors) != len(tt.wantErrs) {
+				t.Fatalf("errors: got %v, want %v", report.Errors, tt.wantErrs)
+			}
+			for i, want := range tt.wantErrs {
+				if !errors.Is(report.Errors[i].Err, want) {
+					t.Errorf("row %d: got %v, want %v", report.Errors[i].Row, report.Errors[i].Err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCalendarItem(t *testing.T) {
+	due := time.Date(2025, 9, 5, 18, 0, 0, 0, time.UTC)
+	task := entity.Task{ID: 42, Title: "Report", Status: StatusInProgress, Priority: 1, DueAt: &due, Version: 4}
+
+	it := calendarItem(task)
+	if it.UID != CalendarUID(42) || it.UID != "task-42@taskmanager" {
+		t.Errorf("UID: got %q", it.UID)
+	}
+	if it.Status != "IN-PROCESS" || it.Priority != 1 || it.Sequence != 4 || !it.Due.Equal(due) {
+		t.Errorf("unexpected item: %+v", it)
+	}
+
+	for p, want := range map[int64]int{1: 1, 3: 5, 5: 9, 0: 0} {
+		if got := calendarPriority(p); got != want {
+			t.Errorf("calendarPriority(%d): got %d, want %d", p, got, want)
+		}
+	}
+	if calendarStatus(StatusDone) != "COMPLETED" || calendarStatus(StatusTodo) != "NEEDS-ACTION" {
+		t.Errorf("unexpected status mapping")
+	}
+}
+
+func TestBuildEffortReport(t *testing.T) {
+	n := func(v int64) *int64 { return &v }
+
+	rep := buildEffortReport([]entity.TaskEffort{
+		{TaskID: 1, EstimateMinutes: n(60), StoryPoints: n(2), SpentSeconds: 5400},
+		{TaskID: 2, EstimateMinutes: n(120), SpentSeconds: 3600},
+		{TaskID: 3, StoryPoints: n(2), SpentSeconds: 1800},
+		{TaskID: 4, StoryPoints: n(1), SpentSeconds: 900},
+		{TaskID: 5, EstimateMinutes: n(30)},
+	})
+
+	if rep.Tasks != 5 || rep.Untracked != 1 {
+		t.Errorf("tasks %d, untracked %d", rep.Tasks, rep.Untracked)
+	}
+	if rep.Estimated != 2 || rep.EstimateMinutes != 180 || rep.SpentSeconds != 9000 || rep.Over != 1 || rep.Under != 1 {
+		t.Errorf("unexpected estimates: %+v", rep)
+	}
+	if got := rep.Ratio(); got < 0.83 || got > 0.84 {
+		t.Errorf("ratio: got %v", got)
+	}
+	if rep.Pointed != 3 || rep.Points != 5 || rep.SecondsPerPoint() != 1620 {
+		t.Errorf("unexpected points: %+v", rep)
+	}
+	want := []EffortByPoints{{Points: 1, Tasks: 1, SpentSeconds: 900}, {Points: 2, Tasks: 2, SpentSeconds: 7200}}
+	if len(rep.ByPoints) != len(want) || rep.ByPoints[0] != want[0] || rep.ByPoints[1] != want[1] {
+		t.Errorf("by points: got %+v", rep.ByPoints)
+	}
+}
+
+func TestTaskService_EffortReport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("no tz data: %v", err)
+	}
+	may1 := time.Date(2025, 5, 1, 0, 0, 0, 0, moscow)
+	jun1 := time.Date(2025, 6, 1, 0, 0, 0, 0, moscow)
+
+	tests := []struct {
+		name      string
+		from, to  string
+		tz        string
+		wantStart *time.Time
+		wantEnd   *time.Time
+		wantErr   error
+	}{
+		{name: "everything"},
+		{name: "closed range", from: "2025-05-01", to: "2025-05-31", tz: "Europe/Moscow", wantStart: &may1, wantEnd: &jun1},
+		{name: "from only", from: "2025-05-01", tz: "Europe/Moscow", wantStart: &may1},
+		{name: "to only", to: "2025-05-31", tz: "Europe/Moscow", wantEnd: &jun1},
+		{name: "bad from", from: "May 1", wantErr: ErrBadReportRange},
+		{name: "to before from", from: "2025-05-31", to: "2025-05-01", wantErr: ErrBadReportRange},
+		{name: "bad tz without a range", tz: "Mars/Olympus", wantErr: ErrBadTimeZone},
+		{name: "bad tz with from only", from: "2025-05-01", tz: "Mars/Olympus", wantErr: ErrBadTimeZone},
+	}
+
+	sameTime := func(a, b *time.Time) bool {
+		return (a == nil) == (b == nil) && (a == nil || a.Equal(*b))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr == nil {
+				mockRepo.EXPECT().
+					ListEffort(gomock.Any(), int64(1), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int64, from, to *time.Time) ([]entity.TaskEffort, error) {
+						if !sameTime(from, tt.wantStart) || !sameTime(to, tt.wantEnd) {
+							t.Errorf("range: got %v..%v, want %v..%v", from, to, tt.wantStart, tt.wantEnd)
+						}
+						return nil, nil
+					})
+			}
+
+			rep, err := svc.EffortReport(context.Background(), 1, tt.from, tt.to, tt.tz)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if (rep.From == nil) != (tt.from == "") || (rep.To == nil) != (tt.to == "") {
+				t.Errorf("bounds: got %v..%v", rep.From, rep.To)
+			}
+		})
+	}
+}
@@ -2,9 +2,13 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/events"
 	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"html"
+	"strings"
 	"time"
 )
 
@@ -15,6 +19,32 @@ const (
 
 	MinPriority = 1
 	MaxPriority = 5
+
+	MinEstimateMinutes = 1
+	MaxEstimateMinutes = 100000
+	MinStoryPoints     = 0
+	MaxStoryPoints     = 100
+
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+	SortDueAt     = "due_at"
+	SortPriority  = "priority"
+	SortTitle     = "title"
+	SortEstimate  = "estimate_minutes"
+	SortPoints    = "story_points"
+	SortRank      = "rank"
+
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// ChildPolicy decides what happens to subtasks when their parent is deleted.
+type ChildPolicy string
+
+const (
+	ChildrenBlock   ChildPolicy = "block"
+	ChildrenCascade ChildPolicy = "cascade"
+	ChildrenOrphan  ChildPolicy = "orphan"
 )
 
 var (
@@ -22,45 +52,193 @@ var (
 	ErrEmptyTitle   = errors.New("empty title")
 	ErrBadStatus    = errors.New("bad status")
 	ErrBadPriority  = errors.New("bad priority")
+	ErrBadEstimate  = errors.New("bad estimate")
+	ErrBadPoints    = errors.New("bad story points")
 	ErrTaskNotFound = errors.New("task not found")
+	ErrBadSort      = errors.New("bad sort")
+	ErrBadCursor    = errors.New("bad cursor")
+	ErrBadFilter    = errors.New("bad filter")
+	ErrEmptyQuery   = errors.New("empty search query")
+	ErrBadParent    = errors.New("bad parent task")
+	ErrOpenSubtasks = errors.New("task has open subtasks")
+	ErrHasSubtasks  = errors.New("task has subtasks")
+	ErrBadPolicy    = errors.New("bad child policy")
+
+	ErrBadDependency      = errors.New("bad dependency")
+	ErrDependencyCycle    = errors.New("dependency cycle")
+	ErrDependencyExists   = errors.New("dependency already exists")
+	ErrDependencyNotFound = errors.New("dependency not found")
+	ErrBlocked            = errors.New("task is blocked by unfinished tasks")
+
+	ErrNotOwner    = errors.New("only the task owner can do this")
+	ErrBadAssignee = errors.New("bad assignee")
+	ErrBadProject  = errors.New("bad project")
+
+	ErrVersionMismatch = errors.New("version mismatch")
 )
 
+type TaskOption func(*entity.Task)
+
+func WithParent(parentID int64) TaskOption {
+	return func(t *entity.Task) { t.ParentID = &parentID }
+}
+
+func WithAssignee(assigneeID int64) TaskOption {
+	return func(t *entity.Task) { t.AssigneeID = &assigneeID }
+}
+
+func WithProject(projectID int64) TaskOption {
+	return func(t *entity.Task) { t.ProjectID = &projectID }
+}
+
+func WithWorkflow(workflowID int64) TaskOption {
+	return func(t *entity.Task) { t.WorkflowID = &workflowID }
+}
+
+func WithEstimate(minutes int64) TaskOption {
+	return func(t *entity.Task) { t.EstimateMinutes = &minutes }
+}
+
+func WithStoryPoints(points int64) TaskOption {
+	return func(t *entity.Task) { t.StoryPoints = &points }
+}
+
+// WithRecurrence makes the task the first instance of a series driven by
+// an iCalendar RRULE, anchored at the task's due date.
+func WithRecurrence(rule string) TaskOption {
+	return func(t *entity.Task) { t.RRule = rule }
+}
+
+func WithLabels(labelIDs ...int64) TaskOption {
+	return func(t *entity.Task) {
+		for _, id := range labelIDs {
+			t.Labels = append(t.Labels, entity.Label{ID: id})
+		}
+	}
+}
+
+type TaskListQuery struct {
+	Statuses    []string
+	MinPriority *int64
+	MaxPriority *int64
+	MinEstimate *int64
+	MaxEstimate *int64
+	MinPoints   *int64
+	MaxPoints   *int64
+	DueAfter    *time.Time
+	DueBefore   *time.Time
+	OverdueOnly bool
+	Labels      []string
+	AnyLabel    bool
+	SortBy      string
+	Desc        bool
+	Cursor      string
+	Limit       int
+}
+
+type TaskPage struct {
+	Tasks      []entity.Task
+	NextCursor string
+}
+
 type TaskService struct {
-	repo storage.TaskRepository
+	repo     storage.TaskRepository
+	pub      events.Publisher
+	watchers RecipientLister
+	// pending collects the changes made inside a transaction; see inTx.
+	pending *[]taskChange
 }
 
 func NewTaskService(repo storage.TaskRepository) *TaskService {
 	return &TaskService{repo: repo}
 }
 
-func isValidStatus(status string) bool {
-	switch status {
-	case StatusTodo, StatusInProgress, StatusDone:
+func isValidPriority(priority int64) bool {
+	return priority >= MinPriority && priority <= MaxPriority
+}
+
+func isValidEstimate(minutes int64) bool {
+	return minutes >= MinEstimateMinutes && minutes <= MaxEstimateMinutes
+}
+
+func isValidStoryPoints(points int64) bool {
+	return points >= MinStoryPoints && points <= MaxStoryPoints
+}
+
+// checkEstimates validates the optional estimates of a task; nil means
+// not estimated.
+func checkEstimates(minutes, points *int64) error {
+	if minutes != nil && !isValidEstimate(*minutes) {
+		return ErrBadEstimate
+	}
+	if points != nil && !isValidStoryPoints(*points) {
+		return ErrBadPoints
+	}
+	return nil
+}
+
+func isValidSort(sortBy string) bool {
+	switch sortBy {
+	case SortCreatedAt, SortUpdatedAt, SortDueAt, SortPriority, SortTitle, SortEstimate, SortPoints, SortRank:
 		return true
 	}
 	return false
 }
 
-func isValidPriority(priority int64) bool {
-	return priority >= MinPriority && priority <= MaxPriority
+func isAssignee(t entity.Task, uid int64) bool {
+	return t.AssigneeID != nil && *t.AssigneeID == uid
 }
 
-func (s *TaskService) CreateTask(ctx context.Context, userID int64, title, desc, status string, priority int64, dueAt *time.Time) (entity.Task, error) {
-	if status == "" {
-		status = StatusTodo
+// checkOwner tells an assignee who tries an owner-only action apart from a
+// stranger, who must not learn that the task exists.
+func checkOwner(t entity.Task, uid int64) error {
+	if t.UserID == uid {
+		return nil
 	}
+	if isAssignee(t, uid) {
+		return ErrNotOwner
+	}
+	return ErrTaskNotFound
+}
+
+func hasOpenSubtasks(t entity.Task) bool {
+	return t.SubtasksDone < t.SubtasksTotal
+}
+
+// checkParent makes sure parentID is a task of the same user and, when
+// taskID is set, that it does not lie inside taskID's own subtree.
+func (s *TaskService) checkParent(ctx context.Context, userID, parentID, taskID int64) error {
+	if parentID == taskID {
+		return ErrBadParent
+	}
+	parent, err := s.repo.GetByID(ctx, parentID)
+	if err != nil || parent.UserID != userID {
+		return ErrBadParent
+	}
+	if taskID == 0 {
+		return nil
+	}
+	subtree, err := s.repo.GetSubtree(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	for _, t := range subtree {
+		if t.ID == parentID {
+			return ErrBadParent
+		}
+	}
+	return nil
+}
+
+// CreateTask validates and stores a new task. An empty status means the
+// initial state of the task's workflow.
+func (s *TaskService) CreateTask(ctx context.Context, userID int64, title, desc, status string, priority int64, dueAt *time.Time, opts ...TaskOption) (entity.Task, error) {
 	if priority == 0 {
 		priority = 3
 	}
 	if title == "" {
 		return entity.Task{}, ErrEmptyTitle
 	}
-	if !isValidStatus(status) {
-		return entity.Task{}, ErrBadStatus
-	}
-	if !isValidPriority(priority) {
-		return entity.Task{}, ErrBadPriority
-	}
 
 	t := &entity.Task{
 		UserID:      userID,
@@ -70,9 +248,53 @@ func (s *TaskService) CreateTask(ctx context.Context, userID int64, title, desc,
 		Priority:    priority,
 		DueAt:       dueAt,
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	wf, err := s.userWorkflow(ctx, userID, t.WorkflowID)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	if t.Status == "" {
+		t.Status = wf.InitialState
+	}
+	if !hasState(wf, t.Status) {
+		return entity.Task{}, ErrBadStatus
+	}
+	if !isValidPriority(priority) {
+		return entity.Task{}, ErrBadPriority
+	}
+	if err := checkEstimates(t.EstimateMinutes, t.StoryPoints); err != nil {
+		return entity.Task{}, err
+	}
+	if t.ParentID != nil {
+		if err := s.checkParent(ctx, userID, *t.ParentID, 0); err != nil {
+			return entity.Task{}, err
+		}
+	}
+	if t.AssigneeID != nil && *t.AssigneeID <= 0 {
+		return entity.Task{}, ErrBadAssignee
+	}
+	if t.RRule != "" {
+		rule, err := parseRecurrence(t.RRule, t.DueAt)
+		if err != nil {
+			return entity.Task{}, err
+		}
+		t.RRule = rule.String()
+	}
 	if err := s.repo.Create(ctx, t); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrForeignLabel):
+			return entity.Task{}, ErrBadLabel
+		case errors.Is(err, storage.ErrUnknownUser):
+			return entity.Task{}, ErrBadAssignee
+		case errors.Is(err, storage.ErrNotProjectMember):
+			return entity.Task{}, ErrBadProject
+		}
 		return entity.Task{}, err
 	}
+	s.record(ctx, taskChange{typ: events.TypeTaskCreated, actor: userID, task: *t, at: t.CreatedAt})
 	return *t, nil
 }
 
@@ -80,21 +302,209 @@ func (s *TaskService) GetTaskByID(ctx context.Context, id int64) (entity.Task, e
 	return s.repo.GetByID(ctx, id)
 }
 
-func (s *TaskService) ListTasksByUser(ctx context.Context, userID int64) ([]entity.Task, error) {
-	return s.repo.GetByUserID(ctx, userID)
+// GetTaskForUser returns a task that uid either owns or is assigned to.
+func (s *TaskService) GetTaskForUser(ctx context.Context, uid, tid int64) (entity.Task, error) {
+	t, err := s.repo.GetByID(ctx, tid)
+	if err != nil || (t.UserID != uid && !isAssignee(t, uid)) {
+		return entity.Task{}, ErrTaskNotFound
+	}
+	return t, nil
+}
+
+func (s *TaskService) ListTasksByUser(ctx context.Context, userID int64, order storage.TaskOrder) ([]entity.Task, error) {
+	return s.repo.GetByUserID(ctx, userID, order)
+}
+
+func (s *TaskService) ListTasks(ctx context.Context, userID int64, q TaskListQuery) (TaskPage, error) {
+	return s.listTasks(ctx, storage.TaskListFilter{UserID: userID}, q)
+}
+
+// ListAssignedTasks pages through the tasks assigned to userID, whoever
+// owns them.
+func (s *TaskService) ListAssignedTasks(ctx context.Context, userID int64, q TaskListQuery) (TaskPage, error) {
+	return s.listTasks(ctx, storage.TaskListFilter{AssigneeID: &userID}, q)
+}
+
+// ListProjectTasks pages through the tasks filed under a project. Callers
+// are expected to have checked project membership.
+func (s *TaskService) ListProjectTasks(ctx context.Context, projectID int64, q TaskListQuery) (TaskPage, error) {
+	return s.listTasks(ctx, storage.TaskListFilter{ProjectID: &projectID}, q)
+}
+
+// checkListQuery validates the filters and the sort order of q.
+func checkListQuery(q TaskListQuery) error {
+	if q.SortBy != "" && !isValidSort(q.SortBy) {
+		return ErrBadSort
+	}
+	for _, st := range q.Statuses {
+		if !stateNameRe.MatchString(st) {
+			return ErrBadStatus
+		}
+	}
+	if q.MinPriority != nil && !isValidPriority(*q.MinPriority) {
+		return ErrBadPriority
+	}
+	if q.MaxPriority != nil && !isValidPriority(*q.MaxPriority) {
+		return ErrBadPriority
+	}
+	if q.MinPriority != nil && q.MaxPriority != nil && *q.MinPriority > *q.MaxPriority {
+		return ErrBadFilter
+	}
+	if err := checkEstimates(q.MinEstimate, q.MinPoints); err != nil {
+		return err
+	}
+	if err := checkEstimates(q.MaxEstimate, q.MaxPoints); err != nil {
+		return err
+	}
+	if q.MinEstimate != nil && q.MaxEstimate != nil && *q.MinEstimate > *q.MaxEstimate {
+		return ErrBadFilter
+	}
+	if q.MinPoints != nil && q.MaxPoints != nil && *q.MinPoints > *q.MaxPoints {
+		return ErrBadFilter
+	}
+	if q.DueAfter != nil && q.DueBefore != nil && !q.DueAfter.Before(*q.DueBefore) {
+		return ErrBadFilter
+	}
+	return nil
+}
+
+// distinctLabels drops repeated label names: the "all labels" filter counts
+// the distinct names a task matches against the number asked for.
+func distinctLabels(names []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, n := range names {
+		if !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func (s *TaskService) listTasks(ctx context.Context, f storage.TaskListFilter, q TaskListQuery) (TaskPage, error) {
+	if q.SortBy == "" {
+		q.SortBy = SortCreatedAt
+	}
+	if err := checkListQuery(q); err != nil {
+		return TaskPage{}, err
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit > MaxPageSize {
+		q.Limit = MaxPageSize
+	}
+
+	f.Statuses = q.Statuses
+	f.MinPriority = q.MinPriority
+	f.MaxPriority = q.MaxPriority
+	f.MinEstimate = q.MinEstimate
+	f.MaxEstimate = q.MaxEstimate
+	f.MinPoints = q.MinPoints
+	f.MaxPoints = q.MaxPoints
+	f.DueAfter = q.DueAfter
+	f.DueBefore = q.DueBefore
+	f.OverdueOnly = q.OverdueOnly
+	f.Labels = distinctLabels(q.Labels)
+	f.AnyLabel = q.AnyLabel
+	f.SortBy = q.SortBy
+	f.Desc = q.Desc
+	f.Limit = q.Limit + 1
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return TaskPage{}, err
+		}
+		if c.Sort != q.SortBy || c.Desc != q.Desc {
+			return TaskPage{}, ErrBadCursor
+		}
+		f.AfterValue = &c.Value
+		f.AfterID = c.ID
+	}
+
+	tasks, err := s.repo.List(ctx, f)
+	if err != nil {
+		return TaskPage{}, err
+	}
+
+	page := TaskPage{Tasks: tasks}
+	if len(tasks) > q.Limit {
+		page.Tasks = tasks[:q.Limit]
+		last := page.Tasks[q.Limit-1]
+		page.NextCursor = encodeCursor(taskCursor{
+			Sort:  q.SortBy,
+			Desc:  q.Desc,
+			Value: taskSortValue(last, q.SortBy),
+			ID:    last.ID,
+		})
+	}
+	return page, nil
 }
 
-func (s *TaskService) UpdateTask(ctx context.Context, uid, tid int64, title, desc, status string, priority int64, dueAt *time.Time) (entity.Task, error) {
+// SearchTasks ranks tasks by full-text relevance with a trigram fallback
+// for misspelled words. A nil userID searches across all users.
+func (s *TaskService) SearchTasks(ctx context.Context, userID *int64, q string, limit, offset int) ([]entity.TaskSearchHit, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, ErrEmptyQuery
+	}
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if offset < 0 {
+		return nil, ErrBadFilter
+	}
+	hits, err := s.repo.Search(ctx, userID, q, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hits {
+		hits[i].TitleSnippet = highlightSnippet(hits[i].TitleSnippet)
+		hits[i].DescriptionSnippet = highlightSnippet(hits[i].DescriptionSnippet)
+	}
+	return hits, nil
+}
+
+var snippetMarks = strings.NewReplacer(storage.SnippetStart, "<mark>", storage.SnippetStop, "</mark>")
+
+// highlightSnippet HTML-escapes a search snippet and only then turns the
+// repository's match markers into <mark> tags.
+func highlightSnippet(s string) string {
+	return snippetMarks.Replace(html.EscapeString(s))
+}
+
+// UpdateTask replaces the editable fields of a task. A non-nil ifVersion
+// makes the update conditional on the task still being at that version.
+func (s *TaskService) UpdateTask(ctx context.Context, uid, tid int64, title, desc, status string, priority int64, dueAt *time.Time, ifVersion *int64) (entity.Task, error) {
 	if title == "" {
 		return entity.Task{}, ErrEmptyTitle
 	}
-	if !isValidStatus(status) {
+	if !stateNameRe.MatchString(status) {
 		return entity.Task{}, ErrBadStatus
 	}
 	if !isValidPriority(priority) {
 		return entity.Task{}, ErrBadPriority
 	}
 
+	cur, err := s.repo.GetByID(ctx, tid)
+	if err != nil || cur.UserID != uid {
+		return entity.Task{}, ErrTaskNotFound
+	}
+	if err := checkVersion(cur, ifVersion); err != nil {
+		return entity.Task{}, err
+	}
+	wf, err := s.workflowOf(ctx, cur)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	if err := checkTransition(wf, cur, status, desc); err != nil {
+		return entity.Task{}, err
+	}
+
 	t := &entity.Task{
 		ID:          tid,
 		UserID:      uid,
@@ -104,43 +514,316 @@ func (s *TaskService) UpdateTask(ctx context.Context, uid, tid int64, title, des
 		Priority:    priority,
 		DueAt:       dueAt,
 	}
-	return s.repo.Update(ctx, t)
+	if ifVersion != nil {
+		t.Version = *ifVersion
+	}
+	var out entity.Task
+	err = s.completeWith(ctx, uid, wf, cur, status, func(tx *TaskService) error {
+		var err error
+		out, err = tx.repo.Update(ctx, t)
+		return err
+	})
+	if errors.Is(err, storage.ErrVersionMismatch) {
+		return entity.Task{}, ErrVersionMismatch
+	}
+	if err != nil {
+		return entity.Task{}, err
+	}
+	s.publishChange(ctx, uid, cur, out)
+	return out, nil
 }
 
-func (s *TaskService) PatchTask(
-	ctx context.Context,
-	uid, tid int64,
-	title, desc, status *string,
-	priority *int,
-	dueAtProvided bool,
-	dueAt *time.Time,
-) (entity.Task, error) {
+// checkVersion fails early when the caller asked for a version the task is
+// no longer at; the storage layer repeats the check atomically.
+func checkVersion(cur entity.Task, ifVersion *int64) error {
+	if ifVersion != nil && cur.Version != *ifVersion {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// PatchTask applies a partial update. The owner may change any field; the
+// assignee may only move the task to another status.
+func (s *TaskService) PatchTask(ctx context.Context, uid, tid int64, p entity.TaskPatch) (entity.Task, error) {
 	cur, err := s.repo.GetByID(ctx, tid)
 	if err != nil {
 		return entity.Task{}, ErrTaskNotFound
 	}
 	if cur.UserID != uid {
-		return entity.Task{}, ErrTaskNotFound
+		if !isAssignee(cur, uid) {
+			return entity.Task{}, ErrTaskNotFound
+		}
+		if !p.OnlyStatus() {
+			return entity.Task{}, ErrNotOwner
+		}
+		if err := checkVersion(cur, p.IfVersion); err != nil {
+			return entity.Task{}, err
+		}
+		return s.setStatus(ctx, uid, cur, *p.Status, p.IfVersion)
 	}
-	if title != nil && *title == "" {
-		return entity.Task{}, ErrEmptyTitle
+	if err := checkVersion(cur, p.IfVersion); err != nil {
+		return entity.Task{}, err
 	}
-	if status != nil && !isValidStatus(*status) {
-		return entity.Task{}, ErrBadStatus
+	if p.Title != nil && *p.Title == "" {
+		return entity.Task{}, ErrEmptyTitle
 	}
-	if priority != nil && !isValidPriority(int64(*priority)) {
+	if p.Priority != nil && !isValidPriority(int64(*p.Priority)) {
 		return entity.Task{}, ErrBadPriority
 	}
-	return s.repo.Patch(ctx, uid, tid, title, desc, status, priority, dueAtProvided, dueAt)
+	if err := checkEstimates(p.Estimate, p.Points); err != nil {
+		return entity.Task{}, err
+	}
+	wf, err := s.patchWorkflow(ctx, cur, p)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	if p.ParentSet && p.ParentID != nil {
+		if err := s.checkParent(ctx, uid, *p.ParentID, tid); err != nil {
+			return entity.Task{}, err
+		}
+	}
+	status := cur.Status
+	if p.Status != nil {
+		status = *p.Status
+	}
+	var out entity.Task
+	err = s.completeWith(ctx, uid, wf, cur, status, func(tx *TaskService) error {
+		var err error
+		out, err = tx.repo.Patch(ctx, uid, tid, p)
+		return err
+	})
+	switch {
+	case errors.Is(err, storage.ErrForeignLabel):
+		return entity.Task{}, ErrBadLabel
+	case errors.Is(err, storage.ErrNotProjectMember):
+		return entity.Task{}, ErrBadProject
+	case errors.Is(err, storage.ErrVersionMismatch):
+		return entity.Task{}, ErrVersionMismatch
+	}
+	if err != nil {
+		return entity.Task{}, err
+	}
+	s.publishChange(ctx, uid, cur, out)
+	return out, nil
+}
+
+// patchWorkflow checks the status change of a patch and returns the
+// workflow the task ends up on. Moving a task to another workflow is a
+// re-mapping rather than a transition: its status only has to exist there.
+func (s *TaskService) patchWorkflow(ctx context.Context, cur entity.Task, p entity.TaskPatch) (entity.Workflow, error) {
+	description := cur.Description
+	if p.Description != nil {
+		description = *p.Description
+	}
+
+	if !p.WorkflowSet {
+		wf, err := s.workflowOf(ctx, cur)
+		if err != nil {
+			return entity.Workflow{}, err
+		}
+		if p.Status != nil {
+			if err := checkTransition(wf, cur, *p.Status, description); err != nil {
+				return entity.Workflow{}, err
+			}
+		}
+		return wf, nil
+	}
+
+	wf, err := s.userWorkflow(ctx, cur.UserID, p.WorkflowID)
+	if err != nil {
+		return entity.Workflow{}, err
+	}
+	status := cur.Status
+	if p.Status != nil {
+		status = *p.Status
+	}
+	if !hasState(wf, status) {
+		return entity.Workflow{}, ErrBadStatus
+	}
+	return wf, checkReady(wf, cur, status)
+}
+
+func (s *TaskService) setStatus(ctx context.Context, uid int64, cur entity.Task, status string, ifVersion *int64) (entity.Task, error) {
+	wf, err := s.workflowOf(ctx, cur)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	if err := checkTransition(wf, cur, status, cur.Description); err != nil {
+		return entity.Task{}, err
+	}
+	err = s.completeWith(ctx, uid, wf, cur, status, func(tx *TaskService) error {
+		return tx.repo.UpdateStatus(ctx, uid, cur.ID, status, ifVersion)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.Task{}, ErrTaskNotFound
+		}
+		if errors.Is(err, storage.ErrVersionMismatch) {
+			return entity.Task{}, ErrVersionMismatch
+		}
+		return entity.Task{}, err
+	}
+	out, err := s.repo.GetByID(ctx, cur.ID)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	s.publishChange(ctx, uid, cur, out)
+	return out, nil
 }
 
-func (s *TaskService) DeleteTaskByUser(ctx context.Context, uid, tid int64) error {
+// AssignTask sets, replaces or, with a nil assigneeID, clears the assignee.
+// Only the owner may do it.
+func (s *TaskService) AssignTask(ctx context.Context, uid, tid int64, assigneeID *int64) (entity.Task, error) {
 	cur, err := s.repo.GetByID(ctx, tid)
 	if err != nil {
-		return ErrTaskNotFound
+		return entity.Task{}, ErrTaskNotFound
 	}
-	if cur.UserID != uid {
+	if err := checkOwner(cur, uid); err != nil {
+		return entity.Task{}, err
+	}
+	if assigneeID != nil && *assigneeID <= 0 {
+		return entity.Task{}, ErrBadAssignee
+	}
+
+	out, err := s.repo.Assign(ctx, tid, assigneeID)
+	if errors.Is(err, storage.ErrUnknownUser) {
+		return entity.Task{}, ErrBadAssignee
+	}
+	if err != nil {
+		return entity.Task{}, err
+	}
+	s.publishChange(ctx, uid, cur, out)
+	return out, nil
+}
+
+func (s *TaskService) ListSubtasks(ctx context.Context, uid, tid int64) ([]entity.Task, error) {
+	if _, err := s.ownedTask(ctx, uid, tid); err != nil {
+		return nil, err
+	}
+	return s.repo.GetChildren(ctx, tid)
+}
+
+func (s *TaskService) ListSubtree(ctx context.Context, uid, tid int64) ([]entity.Task, error) {
+	if _, err := s.ownedTask(ctx, uid, tid); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSubtree(ctx, tid)
+}
+
+// DeleteTaskByUser removes a task. Subtasks are handled according to policy:
+// ChildrenBlock refuses to delete a task that still has subtasks,
+// ChildrenCascade removes the whole subtree and ChildrenOrphan detaches the
+// direct children and keeps them as top-level tasks. Only the owner may
+// delete; the assignee gets ErrNotOwner.
+func (s *TaskService) DeleteTaskByUser(ctx context.Context, uid, tid int64, policy ChildPolicy, ifVersion *int64) error {
+	cur, err := s.repo.GetByID(ctx, tid)
+	if err != nil {
 		return ErrTaskNotFound
 	}
-	return s.repo.Delete(ctx, tid)
+	if err := checkOwner(cur, uid); err != nil {
+		return err
+	}
+	if err := checkVersion(cur, ifVersion); err != nil {
+		return err
+	}
+
+	switch policy {
+	case "", ChildrenBlock:
+		if cur.SubtasksTotal > 0 {
+			return ErrHasSubtasks
+		}
+	case ChildrenCascade, ChildrenOrphan:
+	default:
+		return ErrBadPolicy
+	}
+
+	c := taskChange{typ: events.TypeTaskDeleted, actor: uid, task: cur}
+	if policy != "" {
+		c.data = map[string]any{"children": string(policy)}
+	}
+	remove := func(tx *TaskService) error {
+		var err error
+		if policy == ChildrenOrphan {
+			err = tx.repo.OrphanChildren(ctx, tid, ifVersion)
+		}
+		if err == nil {
+			err = tx.repo.Delete(ctx, tid, ifVersion)
+		}
+		if errors.Is(err, storage.ErrVersionMismatch) {
+			return ErrVersionMismatch
+		}
+		if err != nil {
+			return err
+		}
+		tx.record(ctx, c)
+		return nil
+	}
+	// Detaching the children and deleting the parent either both happen or
+	// neither does; the other policies are a single statement already.
+	if policy == ChildrenOrphan {
+		return s.inTx(ctx, remove)
+	}
+	return remove(s)
+}
+
+func (s *TaskService) ownedTask(ctx context.Context, uid, tid int64) (entity.Task, error) {
+	t, err := s.repo.GetByID(ctx, tid)
+	if err != nil || t.UserID != uid {
+		return entity.Task{}, ErrTaskNotFound
+	}
+	return t, nil
+}
+
+func (s *TaskService) AddDependency(ctx context.Context, uid, tid, blockedByID int64) error {
+	if tid == blockedByID {
+		return ErrBadDependency
+	}
+	if _, err := s.ownedTask(ctx, uid, tid); err != nil {
+		return err
+	}
+	if _, err := s.ownedTask(ctx, uid, blockedByID); err != nil {
+		return ErrBadDependency
+	}
+
+	err := s.repo.AddDependency(ctx, tid, blockedByID)
+	switch {
+	case errors.Is(err, storage.ErrDependencyCycle):
+		return ErrDependencyCycle
+	case errors.Is(err, storage.ErrDependencyExists):
+		return ErrDependencyExists
+	case err != nil:
+		return err
+	}
+	s.publishTouched(ctx, uid, tid)
+	return nil
+}
+
+func (s *TaskService) RemoveDependency(ctx context.Context, uid, tid, blockedByID int64) error {
+	if _, err := s.ownedTask(ctx, uid, tid); err != nil {
+		return err
+	}
+	err := s.repo.RemoveDependency(ctx, tid, blockedByID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrDependencyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	s.publishTouched(ctx, uid, tid)
+	return nil
+}
+
+func (s *TaskService) ListDependencies(ctx context.Context, uid, tid int64) (blockedBy, blocking []entity.Task, err error) {
+	if _, err := s.ownedTask(ctx, uid, tid); err != nil {
+		return nil, nil, err
+	}
+	blockedBy, err = s.repo.GetBlockers(ctx, tid)
+	if err != nil {
+		return nil, nil, err
+	}
+	blocking, err = s.repo.GetBlocked(ctx, tid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return blockedBy, blocking, nil
 }
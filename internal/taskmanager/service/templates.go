@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxTemplateNameLen  = 100
+	MaxTemplateSubtasks = 50
+)
+
+var (
+	ErrEmptyTemplateName = errors.New("empty template name")
+	ErrBadTemplateName   = errors.New("bad template name")
+	ErrBadTemplate       = errors.New("bad template")
+	ErrBadDueOffset      = errors.New("bad due offset")
+	ErrTemplateNotFound  = errors.New("template not found")
+	ErrTemplateExists    = errors.New("template already exists")
+	ErrMissingVar        = errors.New("missing template variable")
+)
+
+var (
+	templateVarRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	dueOffsetRe   = regexp.MustCompile(`^\+?(\d{1,4}[wdhm])+$`)
+	offsetPartRe  = regexp.MustCompile(`(\d+)([wdhm])`)
+)
+
+// dueOffset places a due date relative to the creation time: whole days,
+// which keep the wall-clock time, plus a clock duration.
+type dueOffset struct {
+	days  int
+	clock time.Duration
+}
+
+// parseDueOffset reads offsets like "+3d", "2w" or "+1d12h". Units are
+// w(eeks), d(ays), h(ours) and m(inutes). An empty string means no due
+// date and yields nil.
+func parseDueOffset(s string) (*dueOffset, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !dueOffsetRe.MatchString(s) {
+		return nil, fmt.Errorf("%w %q, use e.g. +3d, +2w or +1d12h", ErrBadDueOffset, s)
+	}
+	var off dueOffset
+	for _, m := range offsetPartRe.FindAllStringSubmatch(s, -1) {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "w":
+			off.days += 7 * n
+		case "d":
+			off.days += n
+		case "h":
+			off.clock += time.Duration(n) * time.Hour
+		case "m":
+			off.clock += time.Duration(n) * time.Minute
+		}
+	}
+	return &off, nil
+}
+
+func (o dueOffset) from(t time.Time) time.Time {
+	return t.AddDate(0, 0, o.days).Add(o.clock)
+}
+
+// checkPattern makes sure every "{{" in s opens a well-formed variable.
+func checkPattern(s string) error {
+	if strings.Contains(templateVarRe.ReplaceAllString(s, ""), "{{") {
+		return fmt.Errorf("%w: malformed variable in %q", ErrBadTemplate, s)
+	}
+	return nil
+}
+
+// expandPattern replaces every {{name}} in s with vars[name]. Substituted
+// values are not expanded again.
+func expandPattern(s string, vars map[string]string) (string, error) {
+	var missing string
+	out := templateVarRe.ReplaceAllStringFunc(s, func(m string) string {
+		name := templateVarRe.FindStringSubmatch(m)[1]
+		v, ok := vars[name]
+		if !ok && missing == "" {
+			missing = name
+		}
+		return v
+	})
+	if missing != "" {
+		return "", fmt.Errorf("%w %q", ErrMissingVar, missing)
+	}
+	return out, nil
+}
+
+func validateTemplate(t *entity.TaskTemplate) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return ErrEmptyTemplateName
+	}
+	if utf8.RuneCountInString(t.Name) > MaxTemplateNameLen {
+		return ErrBadTemplateName
+	}
+	if len(t.Subtasks) > MaxTemplateSubtasks {
+		return fmt.Errorf("%w: more than %d subtasks", ErrBadTemplate, MaxTemplateSubtasks)
+	}
+
+	t.Title = strings.TrimSpace(t.Title)
+	t.DueOffset = strings.TrimSpace(t.DueOffset)
+	if err := validateTemplateTask(t.Title, t.Description, t.Priority, t.DueOffset); err != nil {
+		return err
+	}
+	for i := range t.Subtasks {
+		st := &t.Subtasks[i]
+		st.Title = strings.TrimSpace(st.Title)
+		st.DueOffset = strings.TrimSpace(st.DueOffset)
+		if err := validateTemplateTask(st.Title, st.Description, st.Priority, st.DueOffset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateTemplateTask(title, desc string, priority int64, offset string) error {
+	if title == "" {
+		return fmt.Errorf("%w: empty title", ErrBadTemplate)
+	}
+	if err := checkPattern(title); err != nil {
+		return err
+	}
+	if err := checkPattern(desc); err != nil {
+		return err
+	}
+	if priority != 0 && !isValidPriority(priority) {
+		return ErrBadPriority
+	}
+	_, err := parseDueOffset(offset)
+	return err
+}
+
+// plannedTask is a task of a template with its variables substituted and
+// its due date resolved.
+type plannedTask struct {
+	Title       string
+	Description string
+	Priority    int64
+	DueAt       *time.Time
+}
+
+// planTemplate resolves a template into the task to create, followed by
+// its subtasks. Besides vars, {{date}} stands for the day of start.
+func planTemplate(tpl entity.TaskTemplate, vars map[string]string, start time.Time) ([]plannedTask, error) {
+	all := map[string]string{"date": start.Format("2006-01-02")}
+	for k, v := range vars {
+		all[k] = v
+	}
+
+	plan := func(title, desc string, priority int64, offset string) (plannedTask, error) {
+		var p plannedTask
+		var err error
+		if p.Title, err = expandPattern(title, all); err != nil {
+			return plannedTask{}, err
+		}
+		if p.Description, err = expandPattern(desc, all); err != nil {
+			return plannedTask{}, err
+		}
+		p.Priority = priority
+		off, err := parseDueOffset(offset)
+		if err != nil {
+			return plannedTask{}, err
+		}
+		if off != nil {
+			due := off.from(start)
+			p.DueAt = &due
+		}
+		return p, nil
+	}
+
+	root, err := plan(tpl.Title, tpl.Description, tpl.Priority, tpl.DueOffset)
+	if err != nil {
+		return nil, err
+	}
+	out := []plannedTask{root}
+	for _, st := range tpl.Subtasks {
+		priority := st.Priority
+		if priority == 0 {
+			priority = tpl.Priority
+		}
+		p, err := plan(st.Title, st.Description, priority, st.DueOffset)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// CreateFromTemplate creates a task and the template's subtasks under it
+// in one transaction. Every task goes through CreateTask, so it obeys the
+// same rules as one created on its own.
+func (s *TaskService) CreateFromTemplate(ctx context.Context, uid int64, tpl entity.TaskTemplate, vars map[string]string, start time.Time) (entity.Task, []entity.Task, error) {
+	plan, err := planTemplate(tpl, vars, start)
+	if err != nil {
+		return entity.Task{}, nil, err
+	}
+
+	var root entity.Task
+	var subtasks []entity.Task
+	err = s.inTx(ctx, func(tx *TaskService) error {
+		p := plan[0]
+		t, err := tx.CreateTask(ctx, uid, p.Title, p.Description, "", p.Priority, p.DueAt)
+		if err != nil {
+			return err
+		}
+		root = t
+		for _, p := range plan[1:] {
+			t, err := tx.CreateTask(ctx, uid, p.Title, p.Description, "", p.Priority, p.DueAt, WithParent(root.ID))
+			if err != nil {
+				return err
+			}
+			subtasks = append(subtasks, t)
+		}
+		root, err = tx.repo.GetByID(ctx, root.ID)
+		return err
+	})
+	if err != nil {
+		return entity.Task{}, nil, err
+	}
+	return root, subtasks, nil
+}
+
+type TemplateService struct {
+	repo  *storage.TemplateRepo
+	tasks *TaskService
+}
+
+func NewTemplateService(repo *storage.TemplateRepo, tasks *TaskService) *TemplateService {
+	return &TemplateService{repo: repo, tasks: tasks}
+}
+
+func (s *TemplateService) CreateTemplate(ctx context.Context, uid int64, t entity.TaskTemplate) (entity.TaskTemplate, error) {
+	if err := validateTemplate(&t); err != nil {
+		return entity.TaskTemplate{}, err
+	}
+	t.UserID = uid
+	if err := s.repo.Create(ctx, &t); err != nil {
+		if errors.Is(err, storage.ErrDuplicate) {
+			return entity.TaskTemplate{}, ErrTemplateExists
+		}
+		return entity.TaskTemplate{}, err
+	}
+	return t, nil
+}
+
+func (s *TemplateService) ListTemplates(ctx context.Context, uid int64) ([]entity.TaskTemplate, error) {
+	return s.repo.GetByUserID(ctx, uid)
+}
+
+func (s *TemplateService) GetTemplate(ctx context.Context, uid, id int64) (entity.TaskTemplate, error) {
+	t, err := s.repo.GetByID(ctx, id)
+	if err != nil || t.UserID != uid {
+		return entity.TaskTemplate{}, ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+// UpdateTemplate replaces a template and its subtasks.
+func (s *TemplateService) UpdateTemplate(ctx context.Context, uid, id int64, t entity.TaskTemplate) (entity.TaskTemplate, error) {
+	if _, err := s.GetTemplate(ctx, uid, id); err != nil {
+		return entity.TaskTemplate{}, err
+	}
+	if err := validateTemplate(&t); err != nil {
+		return entity.TaskTemplate{}, err
+	}
+	t.ID, t.UserID = id, uid
+
+	err := s.repo.Update(ctx, &t)
+	switch {
+	case errors.Is(err, storage.ErrDuplicate):
+		return entity.TaskTemplate{}, ErrTemplateExists
+	case errors.Is(err, sql.ErrNoRows):
+		return entity.TaskTemplate{}, ErrTemplateNotFound
+	case err != nil:
+		return entity.TaskTemplate{}, err
+	}
+	return t, nil
+}
+
+func (s *TemplateService) DeleteTemplate(ctx context.Context, uid, id int64) error {
+	if _, err := s.GetTemplate(ctx, uid, id); err != nil {
+		return err
+	}
+	err := s.repo.Delete(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTemplateNotFound
+	}
+	return err
+}
+
+// Instantiate creates a task from one of the user's templates. Due
+// offsets count from start, or from now when start is nil.
+func (s *TemplateService) Instantiate(ctx context.Context, uid, id int64, vars map[string]string, start *time.Time) (entity.Task, []entity.Task, error) {
+	tpl, err := s.GetTemplate(ctx, uid, id)
+	if err != nil {
+		return entity.Task{}, nil, err
+	}
+	from := time.Now().UTC()
+	if start != nil {
+		from = *start
+	}
+	return s.tasks.CreateFromTemplate(ctx, uid, tpl, vars, from)
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/mocks"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"github.com/golang/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestParseDueOffset(t *testing.T) {
+	start := time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantNil bool
+		wantErr bool
+	}{
+		{input: "", wantNil: true},
+		{input: "+3d", want: start.AddDate(0, 0, 3)},
+		{input: "2w", want: start.AddDate(0, 0, 14)},
+		{input: "+1d12h", want: start.AddDate(0, 0, 1).Add(12 * time.Hour)},
+		{input: "+90m", want: start.Add(90 * time.Minute)},
+		{input: "3", wantErr: true},
+		{input: "-1d", wantErr: true},
+		{input: "+d", wantErr: true},
+		{input: "+3y", wantErr: true},
+		{input: "+12345d", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			off, err := parseDueOffset(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrBadDueOffset) {
+					t.Fatalf("got %v, want ErrBadDueOffset", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantNil {
+				if off != nil {
+					t.Fatalf("got %+v, want nil", off)
+				}
+				return
+			}
+			if got := off.from(start); !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandPattern(t *testing.T) {
+	vars := map[string]string{"name": "Anna", "team": "{{name}}"}
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{input: "Onboard {{name}}", want: "Onboard Anna"},
+		{input: "{{ name }} joins {{team}}", want: "Anna joins {{name}}"},
+		{input: "no variables", want: "no variables"},
+		{input: "Call {{manager}}", wantErr: ErrMissingVar},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := expandPattern(tt.input, vars)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error: got %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	valid := func() entity.TaskTemplate {
+		return entity.TaskTemplate{
+			Name:      " onboarding ",
+			Title:     "Onboard {{name}}",
+			DueOffset: "+1w",
+			Subtasks:  []entity.TemplateSubtask{{Title: "Laptop for {{name}}", DueOffset: "+2d"}},
+		}
+	}
+	tests := []struct {
+		name    string
+		modify  func(*entity.TaskTemplate)
+		wantErr error
+	}{
+		{name: "valid", modify: func(*entity.TaskTemplate) {}},
+		{name: "empty name", modify: func(t *entity.TaskTemplate) { t.Name = "  " }, wantErr: ErrEmptyTemplateName},
+		{name: "long name", modify: func(t *entity.TaskTemplate) { t.Name = string(make([]rune, 101)) }, wantErr: ErrBadTemplateName},
+		{name: "empty title", modify: func(t *entity.TaskTemplate) { t.Title = "" }, wantErr: ErrBadTemplate},
+		{name: "malformed variable", modify: func(t *entity.TaskTemplate) { t.Title = "Onboard {{ name" }, wantErr: ErrBadTemplate},
+		{name: "bad priority", modify: func(t *entity.TaskTemplate) { t.Priority = 9 }, wantErr: ErrBadPriority},
+		{name: "bad subtask offset", modify: func(t *entity.TaskTemplate) { t.Subtasks[0].DueOffset = "soon" }, wantErr: ErrBadDueOffset},
+		{name: "empty subtask title", modify: func(t *entity.TaskTemplate) { t.Subtasks[0].Title = " " }, wantErr: ErrBadTemplate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := valid()
+			tt.modify(&tpl)
+			err := validateTemplate(&tpl)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && tpl.Name != "onboarding" {
+				t.Errorf("name not trimmed: %q", tpl.Name)
+			}
+		})
+	}
+}
+
+func TestPlanTemplate(t *testing.T) {
+	start := time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)
+	tpl := entity.TaskTemplate{
+		Title:     "Onboard {{name}}",
+		Priority:  2,
+		DueOffset: "+1w",
+		Subtasks: []entity.TemplateSubtask{
+			{Title: "Laptop for {{name}}", Description: "ordered {{date}}", DueOffset: "+2d"},
+			{Title: "Intro call", Priority: 4},
+		},
+	}
+
+	plan, err := planTemplate(tpl, map[string]string{"name": "Anna"}, start)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan) != 3 {
+		t.Fatalf("got %d tasks, want 3", len(plan))
+	}
+	if plan[0].Title != "Onboard Anna" || plan[0].DueAt == nil || !plan[0].DueAt.Equal(start.AddDate(0, 0, 7)) {
+		t.Errorf("root: got %+v", plan[0])
+	}
+	if plan[1].Description != "ordered 2025-09-01" || plan[1].Priority != 2 {
+		t.Errorf("first subtask: got %+v", plan[1])
+	}
+	if plan[2].Priority != 4 || plan[2].DueAt != nil {
+		t.Errorf("second subtask: got %+v", plan[2])
+	}
+
+	if _, err := planTemplate(tpl, nil, start); !errors.Is(err, ErrMissingVar) {
+		t.Errorf("without vars: got %v, want ErrMissingVar", err)
+	}
+}
+
+func TestTaskService_CreateFromTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTaskRepository(ctrl)
+	svc := NewTaskService(mockRepo)
+
+	tpl := entity.TaskTemplate{
+		Title:    "Release {{version}}",
+		Subtasks: []entity.TemplateSubtask{{Title: "Changelog"}, {Title: "Tag {{version}}"}},
+	}
+	start := time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)
+
+	mockRepo.EXPECT().InTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(storage.TaskRepository) error) error {
+			return fn(mockRepo)
+		})
+
+	var nextID int64
+	var created []entity.Task
+	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, task *entity.Task) error {
+			nextID++
+			task.ID = nextID
+			created = append(created, *task)
+			return nil
+		}).Times(3)
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(1)).
+		DoAndReturn(func(_ context.Context, id int64) (entity.Task, error) {
+			return entity.Task{ID: 1, UserID: 7, Title: "Release 1.2", SubtasksTotal: 2}, nil
+		}).Times(3)
+
+	root, subtasks, err := svc.CreateFromTemplate(context.Background(), 7, tpl, map[string]string{"version": "1.2"}, start)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if root.ID != 1 || root.SubtasksTotal != 2 {
+		t.Errorf("root: got %+v", root)
+	}
+	if len(subtasks) != 2 || subtasks[1].Title != "Tag 1.2" {
+		t.Fatalf("subtasks: got %+v", subtasks)
+	}
+	for _, st := range created[1:] {
+		if st.ParentID == nil || *st.ParentID != 1 {
+			t.Errorf("subtask %q is not under the root", st.Title)
+		}
+		if st.Status != StatusTodo || st.Priority != 3 {
+			t.Errorf("subtask %q: status %q, priority %d", st.Title, st.Status, st.Priority)
+		}
+	}
+}
+
+func TestTaskService_CreateFromTemplate_MissingVar(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := NewTaskService(mocks.NewMockTaskRepository(ctrl))
+	tpl := entity.TaskTemplate{Title: "Release {{version}}"}
+
+	_, _, err := svc.CreateFromTemplate(context.Background(), 7, tpl, nil, time.Now())
+	if !errors.Is(err, ErrMissingVar) {
+		t.Fatalf("got %v, want ErrMissingVar", err)
+	}
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxTimeNoteLen = 1000
+	MaxReportDays  = 366
+)
+
+var (
+	ErrTimerRunning      = errors.New("timer already running")
+	ErrNoTimer           = errors.New("no running timer")
+	ErrTimeEntryNotFound = errors.New("time entry not found")
+	ErrBadTimeEntry      = errors.New("bad time entry")
+	ErrTimeNoteTooLong   = errors.New("time entry note too long")
+	ErrTaskDone          = errors.New("task is done")
+	ErrBadReportRange    = errors.New("bad report range")
+	ErrBadTimeZone       = errors.New("bad time zone")
+)
+
+type TimeService struct {
+	repo  *storage.TimeEntryRepo
+	tasks storage.TaskRepository
+}
+
+func NewTimeService(repo *storage.TimeEntryRepo, tasks storage.TaskRepository) *TimeService {
+	return &TimeService{repo: repo, tasks: tasks}
+}
+
+type TimeReportTask struct {
+	TaskID  int64
+	Title   string
+	Seconds int64
+}
+
+type TimeReportDay struct {
+	Day     time.Time
+	Seconds int64
+	Tasks   []TimeReportTask
+}
+
+// TimeReport is the tracked time of a user over the days From..To
+// (inclusive) in Location, split by day and summed by task.
+type TimeReport struct {
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+	Seconds  int64
+	Days     []TimeReportDay
+	Tasks    []TimeReportTask
+}
+
+// checkTask lets the owner and the assignee track time on a task.
+func (s *TimeService) checkTask(ctx context.Context, uid, tid int64) (entity.Task, error) {
+	t, err := s.tasks.GetByID(ctx, tid)
+	if err != nil || (t.UserID != uid && !isAssignee(t, uid)) {
+		return entity.Task{}, ErrTaskNotFound
+	}
+	return t, nil
+}
+
+// getEntry returns an entry of the task that uid tracked; the entries of
+// other people are only listed, never changed.
+func (s *TimeService) getEntry(ctx context.Context, uid, tid, eid int64) (entity.TimeEntry, error) {
+	e, err := s.repo.GetByID(ctx, eid)
+	if err != nil || e.TaskID != tid || e.UserID != uid {
+		return entity.TimeEntry{}, ErrTimeEntryNotFound
+	}
+	return e, nil
+}
+
+func validateTimeNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > MaxTimeNoteLen {
+		return "", ErrTimeNoteTooLong
+	}
+	return note, nil
+}
+
+// validateTimeRange checks a manual entry: it must have ended, and not
+// after now.
+func validateTimeRange(start, end, now time.Time) error {
+	if start.IsZero() || end.IsZero() || !end.After(start) || end.After(now) {
+		return ErrBadTimeEntry
+	}
+	return nil
+}
+
+// StartTimer starts tracking time on a task. A user runs one timer at a
+// time; a second one is ErrTimerRunning.
+func (s *TimeService) StartTimer(ctx context.Context, uid, tid int64, note string) (entity.TimeEntry, error) {
+	t, err := s.checkTask(ctx, uid, tid)
+	if err != nil {
+		return entity.TimeEntry{}, err
+	}
+	if t.Status == StatusDone {
+		return entity.TimeEntry{}, ErrTaskDone
+	}
+	note, err = validateTimeNote(note)
+	if err != nil {
+		return entity.TimeEntry{}, err
+	}
+
+	e := entity.TimeEntry{TaskID: tid, UserID: uid, StartedAt: time.Now(), Note: note}
+	err = s.repo.Create(ctx, &e)
+	if errors.Is(err, storage.ErrTimerRunning) {
+		return entity.TimeEntry{}, ErrTimerRunning
+	}
+	return e, err
+}
+
+// StopTimer stops the running timer of a user, whatever task it is on.
+func (s *TimeService) StopTimer(ctx context.Context, uid int64) (entity.TimeEntry, error) {
+	e, err := s.repo.Stop(ctx, uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.TimeEntry{}, ErrNoTimer
+	}
+	return e, err
+}
+
+func (s *TimeService) RunningTimer(ctx context.Context, uid int64) (entity.TimeEntry, error) {
+	e, err := s.repo.Running(ctx, uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.TimeEntry{}, ErrNoTimer
+	}
+	return e, err
+}
+
+func (s *TimeService) ListEntries(ctx context.Context, uid, tid int64) ([]entity.TimeEntry, error) {
+	if _, err := s.checkTask(ctx, uid, tid); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByTask(ctx, tid)
+}
+
+func (s *TimeService) GetEntry(ctx context.Context, uid, tid, eid int64) (entity.TimeEntry, error) {
+	if _, err := s.checkTask(ctx, uid, tid); err != nil {
+		return entity.TimeEntry{}, err
+	}
+	return s.getEntry(ctx, uid, tid, eid)
+}
+
+func (s *TimeService) AddEntry(ctx context.Context, uid, tid int64, start, end time.Time, note string) (entity.TimeEntry, error) {
+	if _, err := s.checkTask(ctx, uid, tid); err != nil {
+		return entity.TimeEntry{}, err
+	}
+	if err := validateTimeRange(start, end, time.Now()); err != nil {
+		return entity.TimeEntry{}, err
+	}
+	note, err := validateTimeNote(note)
+	if err != nil {
+		return entity.TimeEntry{}, err
+	}
+
+	e := entity.TimeEntry{TaskID: tid, UserID: uid, StartedAt: start, EndedAt: &end, Note: note}
+	if err := s.repo.Create(ctx, &e); err != nil {
+		return entity.TimeEntry{}, err
+	}
+	return e, nil
+}
+
+// UpdateEntry rewrites a finished entry. A running timer has to be stopped
+// first.
+func (s *TimeService) UpdateEntry(ctx context.Context, uid, tid, eid int64, start, end time.Time, note string) (entity.TimeEntry, error) {
+	if _, err := s.checkTask(ctx, uid, tid); err != nil {
+		return entity.TimeEntry{}, err
+	}
+	e, err := s.getEntry(ctx, uid, tid, eid)
+	if err != nil {
+		return entity.TimeEntry{}, err
+	}
+	if e.EndedAt == nil {
+		return entity.TimeEntry{}, ErrTimerRunning
+	}
+	if err := validateTimeRange(start, end, time.Now()); err != nil {
+		return entity.TimeEntry{}, err
+	}
+	if e.Note, err = validateTimeNote(note); err != nil {
+		return entity.TimeEntry{}, err
+	}
+
+	e.StartedAt, e.EndedAt = start, &end
+	err = s.repo.Update(ctx, &e)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.TimeEntry{}, ErrTimeEntryNotFound
+	}
+	return e, err
+}
+
+func (s *TimeService) DeleteEntry(ctx context.Context, uid, tid, eid int64) error {
+	if _, err := s.checkTask(ctx, uid, tid); err != nil {
+		return err
+	}
+	if _, err := s.getEntry(ctx, uid, tid, eid); err != nil {
+		return err
+	}
+	err := s.repo.Delete(ctx, eid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTimeEntryNotFound
+	}
+	return err
+}
+
+// parseReportRange reads two YYYY-MM-DD dates as whole days in tz (UTC when
+// empty) and returns the half-open interval they cover.
+func parseReportRange(from, to, tz string) (time.Time, time.Time, *time.Location, error) {
+	loc, err := parseTimeZone(tz)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, err
+	}
+	start, err := time.ParseInLocation(time.DateOnly, from, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, ErrBadReportRange
+	}
+	last, err := time.ParseInLocation(time.DateOnly, to, loc)
+	if err != nil || last.Before(start) {
+		return time.Time{}, time.Time{}, nil, ErrBadReportRange
+	}
+	end := last.AddDate(0, 0, 1)
+	if end.After(start.AddDate(0, 0, MaxReportDays)) {
+		return time.Time{}, time.Time{}, nil, ErrBadReportRange
+	}
+	return start, end, loc, nil
+}
+
+// parseTimeZone loads an IANA time zone; an empty name means UTC.
+func parseTimeZone(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "Local" {
+		return nil, ErrBadTimeZone
+	}
+	return loc, nil
+}
+
+// buildTimeReport groups rows, ordered by day, into days and per-task
+// totals; tasks are listed by the time spent on them, most first.
+func buildTimeReport(rows []entity.TimeReportRow) TimeReport {
+	var rep TimeReport
+	byTask := make(map[int64]int)
+	for _, row := range rows {
+		if n := len(rep.Days); n == 0 || !rep.Days[n-1].Day.Equal(row.Day) {
+			rep.Days = append(rep.Days, TimeReportDay{Day: row.Day})
+		}
+		day := &rep.Days[len(rep.Days)-1]
+		day.Seconds += row.Seconds
+		day.Tasks = append(day.Tasks, TimeReportTask{TaskID: row.TaskID, Title: row.Title, Seconds: row.Seconds})
+		rep.Seconds += row.Seconds
+
+		i, ok := byTask[row.TaskID]
+		if !ok {
+			i = len(rep.Tasks)
+			byTask[row.TaskID] = i
+			rep.Tasks = append(rep.Tasks, TimeReportTask{TaskID: row.TaskID, Title: row.Title})
+		}
+		rep.Tasks[i].Seconds += row.Seconds
+	}
+	sort.Slice(rep.Tasks, func(i, j int) bool {
+		if rep.Tasks[i].Seconds != rep.Tasks[j].Seconds {
+			return rep.Tasks[i].Seconds > rep.Tasks[j].Seconds
+		}
+		return rep.Tasks[i].TaskID < rep.Tasks[j].TaskID
+	})
+	return rep
+}
+
+// Report sums the time uid tracked between two dates, both included.
+func (s *TimeService) Report(ctx context.Context, uid int64, from, to, tz string) (TimeReport, error) {
+	start, end, loc, err := parseReportRange(from, to, tz)
+	if err != nil {
+		return TimeReport{}, err
+	}
+	rows, err := s.repo.Report(ctx, uid, start, end, loc.String())
+	if err != nil {
+		return TimeReport{}, err
+	}
+	rep := buildTimeReport(rows)
+	rep.From, rep.To, rep.Location = start, end.AddDate(0, 0, -1), loc
+	return rep, nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"testing"
+	"time"
+)
+
+func TestValidateTimeRange(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		ok         bool
+	}{
+		{"ok", now.Add(-2 * time.Hour), now.Add(-time.Hour), true},
+		{"ends now", now.Add(-time.Hour), now, true},
+		{"missing start", time.Time{}, now, false},
+		{"missing end", now.Add(-time.Hour), time.Time{}, false},
+		{"empty", now.Add(-time.Hour), now.Add(-time.Hour), false},
+		{"reversed", now.Add(-time.Hour), now.Add(-2 * time.Hour), false},
+		{"future", now.Add(-time.Hour), now.Add(time.Minute), false},
+	}
+
+	for _, tt := range tests {
+		err := validateTimeRange(tt.start, tt.end, now)
+		if tt.ok && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrBadTimeEntry) {
+			t.Errorf("%s: expected ErrBadTimeEntry, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestParseReportRange(t *testing.T) {
+	start, end, loc, err := parseReportRange("2025-03-29", "2025-03-30", "Europe/Berlin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loc.String() != "Europe/Berlin" {
+		t.Errorf("location: got %s", loc)
+	}
+	// The range covers the switch to summer time, so it is 47 hours long.
+	if got := end.Sub(start); got != 47*time.Hour {
+		t.Errorf("range length: got %v", got)
+	}
+
+	for _, tc := range []struct {
+		from, to, tz string
+		want         error
+	}{
+		{"2025-05-02", "2025-05-01", "", ErrBadReportRange},
+		{"2025-05-01", "", "", ErrBadReportRange},
+		{"01.05.2025", "2025-05-02", "", ErrBadReportRange},
+		{"2024-01-01", "2025-01-01", "", ErrBadReportRange},
+		{"2025-05-01", "2025-05-02", "Mars/Olympus", ErrBadTimeZone},
+		{"2025-05-01", "2025-05-02", "Local", ErrBadTimeZone},
+	} {
+		if _, _, _, err := parseReportRange(tc.from, tc.to, tc.tz); !errors.Is(err, tc.want) {
+			t.Errorf("%s..%s %q: expected %v, got %v", tc.from, tc.to, tc.tz, tc.want, err)
+		}
+	}
+}
+
+func TestBuildTimeReport(t *testing.T) {
+	d1 := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	d2 := d1.AddDate(0, 0, 1)
+
+	rep := buildTimeReport([]entity.TimeReportRow{
+		{Day: d1, TaskID: 1, Title: "a", Seconds: 600},
+		{Day: d1, TaskID: 2, Title: "b", Seconds: 1800},
+		{Day: d2, TaskID: 1, Title: "a", Seconds: 3600},
+	})
+
+	if rep.Seconds != 6000 {
+		t.Errorf("total: got %d", rep.Seconds)
+	}
+	if len(rep.Days) != 2 || rep.Days[0].Seconds != 2400 || rep.Days[1].Seconds != 3600 {
+		t.Fatalf("days: got %+v", rep.Days)
+	}
+	if len(rep.Days[0].Tasks) != 2 {
+		t.Errorf("first day tasks: got %+v", rep.Days[0].Tasks)
+	}
+	if len(rep.Tasks) != 2 || rep.Tasks[0].TaskID != 1 || rep.Tasks[0].Seconds != 4200 || rep.Tasks[1].Seconds != 1800 {
+		t.Errorf("tasks: got %+v", rep.Tasks)
+	}
+}
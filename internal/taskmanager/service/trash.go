@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"log"
+	"time"
+)
+
+// ListTrash returns the user's deleted tasks, most recently deleted first.
+func (s *TaskService) ListTrash(ctx context.Context, uid int64) ([]entity.Task, error) {
+	return s.repo.ListTrash(ctx, uid)
+}
+
+// RestoreTask brings a task back from the trash with the subtasks that were
+// deleted together with it.
+func (s *TaskService) RestoreTask(ctx context.Context, uid, tid int64) (entity.Task, error) {
+	t, err := s.repo.Restore(ctx, uid, tid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Task{}, ErrTaskNotFound
+	}
+	if err != nil {
+		return entity.Task{}, err
+	}
+	s.publishChange(ctx, uid, t, t)
+	return t, nil
+}
+
+// RestoreUserByID brings a user back from the trash. It fails with
+// ErrUserTaken when someone else has registered the username or email in
+// the meantime.
+func (s *UserService) RestoreUserByID(ctx context.Context, id int64) (entity.User, error) {
+	u, err := s.repo.Restore(ctx, id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return entity.User{}, ErrUserNotFound
+	case errors.Is(err, storage.ErrDuplicate):
+		return entity.User{}, ErrUserTaken
+	}
+	return u, err
+}
+
+// PurgeService permanently removes tasks and users that have been in the
+// trash for longer than the retention period, then deletes the attachment
+// blobs they leave behind.
+type PurgeService struct {
+	tasks       *storage.TaskRepo
+	users       *storage.UserRepo
+	attachments *AttachmentService
+	retention   time.Duration
+}
+
+func NewPurgeService(tasks *storage.TaskRepo, users *storage.UserRepo, attachments *AttachmentService, retention time.Duration) *PurgeService {
+	return &PurgeService{tasks: tasks, users: users, attachments: attachments, retention: retention}
+}
+
+func (s *PurgeService) RunOnce(ctx context.Context) (tasks, users int64, err error) {
+	before := time.Now().Add(-s.retention)
+	if tasks, err = s.tasks.PurgeDeleted(ctx, before); err != nil {
+		return 0, 0, err
+	}
+	if users, err = s.users.PurgeDeleted(ctx, before); err != nil {
+		return tasks, 0, err
+	}
+	if s.attachments != nil {
+		if n, err := s.attachments.SweepBlobs(ctx); err != nil {
+			return tasks, users, err
+		} else if n > 0 {
+			log.Printf("trash purge: removed %d attachment blobs", n)
+		}
+	}
+	return tasks, users, nil
+}
+
+// Run purges every interval until ctx is cancelled.
+func (s *PurgeService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if tasks, users, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("trash purge: %v", err)
+		} else if tasks > 0 || users > 0 {
+			log.Printf("trash purge: removed %d tasks, %d users", tasks, users)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -10,13 +10,14 @@ import (
 var (
 	ErrEmptyName  = errors.New("empty name")
 	ErrEmptyEmail = errors.New("empty email")
+	ErrUserTaken  = errors.New("username or email is taken")
 )
 
 type UserService struct {
-	repo *storage.UserRepo
+	repo storage.UserRepository
 }
 
-func NewUserService(repo *storage.UserRepo) *UserService {
+func NewUserService(repo storage.UserRepository) *UserService {
 	return &UserService{repo: repo}
 }
 
@@ -46,22 +47,31 @@ func (s *UserService) GetUserByID(ctx context.Context, id int64) (entity.User, e
 	return s.repo.GetByID(ctx, id)
 }
 
-func (s *UserService) UpdateUserByID(ctx context.Context, id int64, name, email string) (entity.User, error) {
+func (s *UserService) UpdateUserByID(ctx context.Context, id int64, name, email string, ifVersion *int64) (entity.User, error) {
 	if name == "" {
 		return entity.User{}, ErrEmptyName
 	}
 	if email == "" {
 		return entity.User{}, ErrEmptyEmail
 	}
-	return s.repo.Update(ctx, id, name, email)
+	u, err := s.repo.Update(ctx, id, name, email, ifVersion)
+	return u, userVersionErr(err)
 }
 
-func (s *UserService) PatchUserByID(ctx context.Context, id int64, name, email *string) (entity.User, error) {
-	return s.repo.Patch(ctx, id, name, email)
+func (s *UserService) PatchUserByID(ctx context.Context, id int64, name, email *string, ifVersion *int64) (entity.User, error) {
+	u, err := s.repo.Patch(ctx, id, name, email, ifVersion)
+	return u, userVersionErr(err)
 }
 
-func (s *UserService) DeleteUserByID(ctx context.Context, id int64) error {
-	return s.repo.Delete(ctx, id)
+func (s *UserService) DeleteUserByID(ctx context.Context, id int64, ifVersion *int64) error {
+	return userVersionErr(s.repo.Delete(ctx, id, ifVersion))
+}
+
+func userVersionErr(err error) error {
+	if errors.Is(err, storage.ErrVersionMismatch) {
+		return ErrVersionMismatch
+	}
+	return err
 }
 
 func (s *UserService) GetByEmail(ctx context.Context, email string) (entity.User, error) {
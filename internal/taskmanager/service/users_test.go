@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/mocks"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"github.com/golang/mock/gomock"
+	"testing"
+)
+
+func TestUserService_RestoreUserByID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := NewUserService(mockRepo)
+
+	tests := []struct {
+		name    string
+		repoErr error
+		wantErr error
+	}{
+		{name: "success"},
+		{name: "not in the trash", repoErr: sql.ErrNoRows, wantErr: ErrUserNotFound},
+		{name: "username taken meanwhile", repoErr: storage.ErrDuplicate, wantErr: ErrUserTaken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo.EXPECT().
+				Restore(gomock.Any(), int64(1)).
+				Return(entity.User{ID: 1, Username: "bob"}, tt.repoErr)
+
+			_, err := svc.RestoreUserByID(context.Background(), 1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxViewNameLen = 100
+
+	// ViewFilterVersion is the format new view filters are stored in.
+	ViewFilterVersion = 1
+)
+
+var (
+	ErrEmptyViewName = errors.New("empty view name")
+	ErrBadViewName   = errors.New("bad view name")
+	ErrBadViewFilter = errors.New("bad view filter")
+	ErrViewNotFound  = errors.New("view not found")
+	ErrViewExists    = errors.New("view already exists")
+	ErrNotViewOwner  = errors.New("not the view owner")
+	ErrBadShare      = errors.New("cannot share a view with its owner")
+	ErrNotShared     = errors.New("view is not shared with the user")
+	ErrNoDefaultView = errors.New("no default view")
+)
+
+// ViewFilter is the filter definition of a saved view. Its fields mirror
+// the query parameters of the task list.
+type ViewFilter struct {
+	Statuses    []string   `json:"status,omitempty"`
+	MinPriority *int64     `json:"priority_min,omitempty"`
+	MaxPriority *int64     `json:"priority_max,omitempty"`
+	MinEstimate *int64     `json:"estimate_min,omitempty"`
+	MaxEstimate *int64     `json:"estimate_max,omitempty"`
+	MinPoints   *int64     `json:"points_min,omitempty"`
+	MaxPoints   *int64     `json:"points_max,omitempty"`
+	DueAfter    *time.Time `json:"due_after,omitempty"`
+	DueBefore   *time.Time `json:"due_before,omitempty"`
+	Overdue     bool       `json:"overdue,omitempty"`
+	Labels      []string   `json:"label,omitempty"`
+	LabelMatch  string     `json:"label_match,omitempty"`
+	Sort        string     `json:"sort,omitempty"`
+	Order       string     `json:"order,omitempty"`
+}
+
+// normalize drops blank statuses and labels and lower-cases the keywords,
+// so equal filters are stored alike.
+func (f *ViewFilter) normalize() {
+	f.Statuses = trimAll(f.Statuses)
+	f.Labels = trimAll(f.Labels)
+	f.LabelMatch = strings.ToLower(strings.TrimSpace(f.LabelMatch))
+	f.Order = strings.ToLower(strings.TrimSpace(f.Order))
+	f.Sort = strings.TrimSpace(f.Sort)
+}
+
+func trimAll(list []string) []string {
+	var out []string
+	for _, s := range list {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// query turns the filter into a task list query, validated like the query
+// parameters it mirrors.
+func (f ViewFilter) query() (TaskListQuery, error) {
+	q := TaskListQuery{
+		Statuses:    f.Statuses,
+		MinPriority: f.MinPriority,
+		MaxPriority: f.MaxPriority,
+		MinEstimate: f.MinEstimate,
+		MaxEstimate: f.MaxEstimate,
+		MinPoints:   f.MinPoints,
+		MaxPoints:   f.MaxPoints,
+		DueAfter:    f.DueAfter,
+		DueBefore:   f.DueBefore,
+		OverdueOnly: f.Overdue,
+		Labels:      f.Labels,
+		SortBy:      f.Sort,
+	}
+	switch f.LabelMatch {
+	case "", "all":
+	case "any":
+		q.AnyLabel = true
+	default:
+		return TaskListQuery{}, fmt.Errorf("%w: label_match must be all or any", ErrBadViewFilter)
+	}
+	switch f.Order {
+	case "":
+		q.Desc = f.Sort == ""
+	case "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return TaskListQuery{}, fmt.Errorf("%w: order must be asc or desc", ErrBadViewFilter)
+	}
+	if err := checkListQuery(q); err != nil {
+		return TaskListQuery{}, err
+	}
+	return q, nil
+}
+
+// decodeViewFilter reads a stored filter. New filter fields are always
+// optional and unknown ones are ignored, so adding a field leaves old rows
+// meaning what they meant. A change that alters the meaning of a stored
+// filter bumps ViewFilterVersion and adds a case here that upgrades the
+// older format.
+func decodeViewFilter(version int, raw []byte) (ViewFilter, error) {
+	switch version {
+	case 1:
+		var f ViewFilter
+		if err := json.Unmarshal(raw, &f); err != nil {
+			return ViewFilter{}, fmt.Errorf("%w: %v", ErrBadViewFilter, err)
+		}
+		return f, nil
+	}
+	return ViewFilter{}, fmt.Errorf("%w: unsupported version %d", ErrBadViewFilter, version)
+}
+
+// View is a saved view with its filter decoded. Default tells whether it
+// is the default view of the user who asked for it.
+type View struct {
+	entity.SavedView
+	Filter  ViewFilter
+	Default bool
+}
+
+func toView(v entity.SavedView, defaultID int64) (View, error) {
+	f, err := decodeViewFilter(v.FilterVersion, v.FilterJSON)
+	if err != nil {
+		return View{}, err
+	}
+	return View{SavedView: v, Filter: f, Default: v.ID == defaultID}, nil
+}
+
+func canSeeView(v entity.SavedView, uid int64) bool {
+	if v.UserID == uid {
+		return true
+	}
+	for _, id := range v.SharedWith {
+		if id == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// ViewService manages saved views. A view belongs to the user who created
+// it; users it is shared with may list tasks through it, always their own
+// tasks, but only the owner may change it.
+type ViewService struct {
+	repo  *storage.ViewRepo
+	tasks *TaskService
+}
+
+func NewViewService(repo *storage.ViewRepo, tasks *TaskService) *ViewService {
+	return &ViewService{repo: repo, tasks: tasks}
+}
+
+// prepareView validates a view and encodes its filter in the current format.
+func prepareView(v *entity.SavedView, f ViewFilter) error {
+	v.Name = strings.TrimSpace(v.Name)
+	if v.Name == "" {
+		return ErrEmptyViewName
+	}
+	if utf8.RuneCountInString(v.Name) > MaxViewNameLen {
+		return ErrBadViewName
+	}
+	f.normalize()
+	if _, err := f.query(); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	v.FilterJSON = raw
+	v.FilterVersion = ViewFilterVersion
+	return nil
+}
+
+func (s *ViewService) defaultID(ctx context.Context, uid int64) (int64, error) {
+	id, err := s.repo.GetDefault(ctx, uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+func (s *ViewService) CreateView(ctx context.Context, uid int64, name string, f ViewFilter) (View, error) {
+	v := entity.SavedView{UserID: uid, Name: name}
+	if err := prepareView(&v, f); err != nil {
+		return View{}, err
+	}
+	if err := s.repo.Create(ctx, &v); err != nil {
+		if errors.Is(err, storage.ErrDuplicate) {
+			return View{}, ErrViewExists
+		}
+		return View{}, err
+	}
+	return toView(v, 0)
+}
+
+// ListViews returns the user's own views and the views shared with them.
+func (s *ViewService) ListViews(ctx context.Context, uid int64) ([]View, error) {
+	list, err := s.repo.GetVisible(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	def, err := s.defaultID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]View, 0, len(list))
+	for _, v := range list {
+		view, err := toView(v, def)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, view)
+	}
+	return out, nil
+}
+
+// visibleView returns the view when uid owns it or it is shared with uid.
+func (s *ViewService) visibleView(ctx context.Context, uid, id int64) (entity.SavedView, error) {
+	v, err := s.repo.GetByID(ctx, id)
+	if err != nil || !canSeeView(v, uid) {
+		return entity.SavedView{}, ErrViewNotFound
+	}
+	return v, nil
+}
+
+func (s *ViewService) ownedView(ctx context.Context, uid, id int64) (entity.SavedView, error) {
+	v, err := s.visibleView(ctx, uid, id)
+	if err != nil {
+		return entity.SavedView{}, err
+	}
+	if v.UserID != uid {
+		return entity.SavedView{}, ErrNotViewOwner
+	}
+	return v, nil
+}
+
+func (s *ViewService) GetView(ctx context.Context, uid, id int64) (View, error) {
+	v, err := s.visibleView(ctx, uid, id)
+	if err != nil {
+		return View{}, err
+	}
+	def, err := s.defaultID(ctx, uid)
+	if err != nil {
+		return View{}, err
+	}
+	return toView(v, def)
+}
+
+// UpdateView renames a view and replaces its filter.
+func (s *ViewService) UpdateView(ctx context.Context, uid, id int64, name string, f ViewFilter) (View, error) {
+	v, err := s.ownedView(ctx, uid, id)
+	if err != nil {
+		return View{}, err
+	}
+	v.Name = name
+	if err := prepareView(&v, f); err != nil {
+		return View{}, err
+	}
+
+	err = s.repo.Update(ctx, &v)
+	switch {
+	case errors.Is(err, storage.ErrDuplicate):
+		return View{}, ErrViewExists
+	case errors.Is(err, sql.ErrNoRows):
+		return View{}, ErrViewNotFound
+	case err != nil:
+		return View{}, err
+	}
+	def, err := s.defaultID(ctx, uid)
+	if err != nil {
+		return View{}, err
+	}
+	return toView(v, def)
+}
+
+func (s *ViewService) DeleteView(ctx context.Context, uid, id int64) error {
+	if _, err := s.ownedView(ctx, uid, id); err != nil {
+		return err
+	}
+	err := s.repo.Delete(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrViewNotFound
+	}
+	return err
+}
+
+func (s *ViewService) ShareView(ctx context.Context, uid, id, with int64) error {
+	if _, err := s.ownedView(ctx, uid, id); err != nil {
+		return err
+	}
+	if with == uid {
+		return ErrBadShare
+	}
+	err := s.repo.Share(ctx, id, with)
+	if errors.Is(err, storage.ErrUnknownUser) {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// UnshareView stops sharing a view with a user. The owner may take the
+// view away from anyone, and a user may drop a view shared with them.
+func (s *ViewService) UnshareView(ctx context.Context, uid, id, with int64) error {
+	if with == uid {
+		if _, err := s.visibleView(ctx, uid, id); err != nil {
+			return err
+		}
+	} else if _, err := s.ownedView(ctx, uid, id); err != nil {
+		return err
+	}
+	err := s.repo.Unshare(ctx, id, with)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotShared
+	}
+	return err
+}
+
+// ViewTasks pages through the user's tasks that match a view.
+func (s *ViewService) ViewTasks(ctx context.Context, uid, id int64, limit int, cursor string) (TaskPage, error) {
+	v, err := s.visibleView(ctx, uid, id)
+	if err != nil {
+		return TaskPage{}, err
+	}
+	view, err := toView(v, 0)
+	if err != nil {
+		return TaskPage{}, err
+	}
+	q, err := view.Filter.query()
+	if err != nil {
+		return TaskPage{}, err
+	}
+	q.Limit, q.Cursor = limit, cursor
+	return s.tasks.ListTasks(ctx, uid, q)
+}
+
+func (s *ViewService) DefaultView(ctx context.Context, uid int64) (View, error) {
+	id, err := s.defaultID(ctx, uid)
+	if err != nil {
+		return View{}, err
+	}
+	if id == 0 {
+		return View{}, ErrNoDefaultView
+	}
+	return s.GetView(ctx, uid, id)
+}
+
+// SetDefaultView makes one of the views the user can see their default.
+func (s *ViewService) SetDefaultView(ctx context.Context, uid, id int64) (View, error) {
+	v, err := s.visibleView(ctx, uid, id)
+	if err != nil {
+		return View{}, err
+	}
+	if err := s.repo.SetDefault(ctx, uid, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return View{}, ErrViewNotFound
+		}
+		return View{}, err
+	}
+	return toView(v, id)
+}
+
+func (s *ViewService) ClearDefaultView(ctx context.Context, uid int64) error {
+	err := s.repo.ClearDefault(ctx, uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoDefaultView
+	}
+	return err
+}
+
+// DefaultQuery returns the task list query of the user's default view;
+// ok is false when they have none.
+func (s *ViewService) DefaultQuery(ctx context.Context, uid int64) (q TaskListQuery, ok bool, err error) {
+	view, err := s.DefaultView(ctx, uid)
+	if errors.Is(err, ErrNoDefaultView) || errors.Is(err, ErrViewNotFound) {
+		return TaskListQuery{}, false, nil
+	}
+	if err != nil {
+		return TaskListQuery{}, false, err
+	}
+	q, err = view.Filter.query()
+	if err != nil {
+		return TaskListQuery{}, false, err
+	}
+	return q, true, nil
+}
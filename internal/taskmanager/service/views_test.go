@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"reflect"
+	"testing"
+)
+
+func TestViewFilter_Query(t *testing.T) {
+	p := func(v int64) *int64 { return &v }
+	tests := []struct {
+		name     string
+		filter   ViewFilter
+		wantErr  error
+		wantDesc bool
+		wantAny  bool
+	}{
+		{name: "empty", filter: ViewFilter{}, wantDesc: true},
+		{name: "sort asc by default", filter: ViewFilter{Sort: SortPriority}},
+		{name: "explicit desc", filter: ViewFilter{Sort: SortPriority, Order: "desc"}, wantDesc: true},
+		{name: "any label", filter: ViewFilter{Labels: []string{"bug"}, LabelMatch: "any"}, wantDesc: true, wantAny: true},
+		{name: "bad label match", filter: ViewFilter{LabelMatch: "some"}, wantErr: ErrBadViewFilter},
+		{name: "bad order", filter: ViewFilter{Order: "up"}, wantErr: ErrBadViewFilter},
+		{name: "bad sort", filter: ViewFilter{Sort: "color"}, wantErr: ErrBadSort},
+		{name: "bad status", filter: ViewFilter{Statuses: []string{"to do!"}}, wantErr: ErrBadStatus},
+		{name: "priority range reversed", filter: ViewFilter{MinPriority: p(4), MaxPriority: p(2)}, wantErr: ErrBadFilter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := tt.filter.query()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if q.Desc != tt.wantDesc || q.AnyLabel != tt.wantAny {
+				t.Errorf("got desc=%v any=%v, want desc=%v any=%v", q.Desc, q.AnyLabel, tt.wantDesc, tt.wantAny)
+			}
+		})
+	}
+}
+
+func TestDecodeViewFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		raw     string
+		want    ViewFilter
+		wantErr bool
+	}{
+		{name: "current", version: 1, raw: `{"status":["todo"],"sort":"due_at","order":"asc"}`, want: ViewFilter{Statuses: []string{"todo"}, Sort: "due_at", Order: "asc"}},
+		{name: "field from a newer build", version: 1, raw: `{"label":["bug"],"assignee":5}`, want: ViewFilter{Labels: []string{"bug"}}},
+		{name: "unknown version", version: 2, raw: `{}`, wantErr: true},
+		{name: "broken json", version: 1, raw: `{"status":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeViewFilter(tt.version, []byte(tt.raw))
+			if tt.wantErr {
+				if !errors.Is(err, ErrBadViewFilter) {
+					t.Fatalf("got %v, want ErrBadViewFilter", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareView(t *testing.T) {
+	v := entity.SavedView{Name: "  my bugs "}
+	f := ViewFilter{Statuses: []string{" todo", ""}, Labels: []string{"bug"}, LabelMatch: "ANY", Sort: SortPriority, Order: " Desc"}
+	if err := prepareView(&v, f); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.Name != "my bugs" || v.FilterVersion != ViewFilterVersion {
+		t.Errorf("got name %q, version %d", v.Name, v.FilterVersion)
+	}
+
+	got, err := decodeViewFilter(v.FilterVersion, v.FilterJSON)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := ViewFilter{Statuses: []string{"todo"}, Labels: []string{"bug"}, LabelMatch: "any", Sort: SortPriority, Order: "desc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip: got %+v, want %+v", got, want)
+	}
+
+	if err := prepareView(&entity.SavedView{Name: " "}, ViewFilter{}); !errors.Is(err, ErrEmptyViewName) {
+		t.Errorf("empty name: got %v", err)
+	}
+	if err := prepareView(&entity.SavedView{Name: "x"}, ViewFilter{Order: "sideways"}); !errors.Is(err, ErrBadViewFilter) {
+		t.Errorf("bad order: got %v", err)
+	}
+}
+
+func TestCanSeeView(t *testing.T) {
+	v := entity.SavedView{UserID: 1, SharedWith: []int64{2, 3}}
+	for uid, want := range map[int64]bool{1: true, 2: true, 3: true, 4: false} {
+		if got := canSeeView(v, uid); got != want {
+			t.Errorf("user %d: got %v, want %v", uid, got, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+)
+
+var ErrNotWatching = errors.New("not watching the task")
+
+// WatcherService manages who follows a task. Anyone who can see a task may
+// watch it: its owner and assignee, who watch it implicitly, and the
+// members of its project. Everybody else is told the task does not exist.
+type WatcherService struct {
+	repo     *storage.WatcherRepo
+	tasks    storage.TaskRepository
+	projects storage.ProjectRepository
+}
+
+func NewWatcherService(repo *storage.WatcherRepo, tasks storage.TaskRepository, projects storage.ProjectRepository) *WatcherService {
+	return &WatcherService{repo: repo, tasks: tasks, projects: projects}
+}
+
+// visibleTask returns the task when uid may see it.
+func (s *WatcherService) visibleTask(ctx context.Context, uid, tid int64) (entity.Task, error) {
+	t, err := s.tasks.GetByID(ctx, tid)
+	if err != nil || !canSeeTask(ctx, s.projects, t, uid) {
+		return entity.Task{}, ErrTaskNotFound
+	}
+	return t, nil
+}
+
+func (s *WatcherService) ListWatchers(ctx context.Context, uid, tid int64) ([]entity.Watcher, error) {
+	if _, err := s.visibleTask(ctx, uid, tid); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, tid)
+}
+
+// Watch makes uid watch the task, or mutes or unmutes it for uid.
+func (s *WatcherService) Watch(ctx context.Context, uid, tid int64, muted bool) (entity.Watcher, error) {
+	t, err := s.visibleTask(ctx, uid, tid)
+	if err != nil {
+		return entity.Watcher{}, err
+	}
+	if err := s.repo.Set(ctx, tid, uid, muted); err != nil {
+		if errors.Is(err, storage.ErrUnknownUser) {
+			return entity.Watcher{}, ErrUserNotFound
+		}
+		return entity.Watcher{}, err
+	}
+	return entity.Watcher{
+		TaskID:   tid,
+		UserID:   uid,
+		Implicit: t.UserID == uid || isAssignee(t, uid),
+		Muted:    muted,
+	}, nil
+}
+
+// Unwatch stops uid from watching the task. The owner and the assignee
+// keep watching it implicitly, so for them it only lifts a mute.
+func (s *WatcherService) Unwatch(ctx context.Context, uid, tid int64) error {
+	t, err := s.visibleTask(ctx, uid, tid)
+	if err != nil {
+		return err
+	}
+	err = s.repo.Delete(ctx, tid, uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		if t.UserID == uid || isAssignee(t, uid) {
+			return nil
+		}
+		return ErrNotWatching
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/mocks"
+	"github.com/golang/mock/gomock"
+	"testing"
+)
+
+func TestWatcherService_CannotSeeTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTasks := mocks.NewMockTaskRepository(ctrl)
+	mockProjects := mocks.NewMockProjectRepository(ctrl)
+	// The watcher repository must not be touched for a refused user.
+	svc := NewWatcherService(nil, mockTasks, mockProjects)
+	ctx := context.Background()
+
+	project := int64(5)
+	mockTasks.EXPECT().GetByID(gomock.Any(), int64(10)).Return(entity.Task{ID: 10, UserID: 1}, nil).AnyTimes()
+	mockTasks.EXPECT().GetByID(gomock.Any(), int64(11)).Return(entity.Task{ID: 11, UserID: 1, ProjectID: &project}, nil).AnyTimes()
+	mockTasks.EXPECT().GetByID(gomock.Any(), int64(404)).Return(entity.Task{}, sql.ErrNoRows).AnyTimes()
+	mockProjects.EXPECT().GetMember(gomock.Any(), int64(5), int64(2)).Return(entity.ProjectMember{}, sql.ErrNoRows).AnyTimes()
+
+	tests := []struct {
+		name string
+		tid  int64
+	}{
+		{name: "private task", tid: 10},
+		{name: "project task, not a member", tid: 11},
+		{name: "missing task", tid: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.ListWatchers(ctx, 2, tt.tid); !errors.Is(err, ErrTaskNotFound) {
+				t.Errorf("list: expected %v, got %v", ErrTaskNotFound, err)
+			}
+			if _, err := svc.Watch(ctx, 2, tt.tid, false); !errors.Is(err, ErrTaskNotFound) {
+				t.Errorf("watch: expected %v, got %v", ErrTaskNotFound, err)
+			}
+			if err := svc.Unwatch(ctx, 2, tt.tid); !errors.Is(err, ErrTaskNotFound) {
+				t.Errorf("unwatch: expected %v, got %v", ErrTaskNotFound, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,500 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/events"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// MaxWebhookAttempts is how many times a delivery is tried before it
+	// is given up as failed.
+	MaxWebhookAttempts = 8
+
+	MinWebhookSecretLen = 16
+	MaxWebhookSecretLen = 200
+	MaxDeliveryList     = 50
+
+	webhookBatch     = 50
+	webhookWorkers   = 4
+	webhookLease     = 2 * time.Minute
+	webhookTimeout   = 10 * time.Second
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = 6 * time.Hour
+)
+
+var (
+	ErrBadWebhookURL    = errors.New("bad webhook url")
+	ErrBadWebhookEvents = errors.New("bad webhook events")
+	ErrBadWebhookSecret = errors.New("bad webhook secret")
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+
+	// errPrivateAddress is what a delivery fails with when the webhook host
+	// resolves to an address inside our network.
+	errPrivateAddress = errors.New("webhook address is not public")
+)
+
+// WebhookEvents are the event types a webhook can subscribe to.
+var WebhookEvents = []string{
+	events.TypeTaskCreated,
+	events.TypeTaskUpdated,
+	events.TypeTaskStatusChanged,
+	events.TypeTaskDeleted,
+	events.TypeTaskReminder,
+}
+
+// checkWebhookURL rejects the hosts that are internal on their face. Names
+// that resolve to internal addresses are stopped by the dispatcher, see
+// dialPublic.
+func checkWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return fmt.Errorf("%w: use an absolute https:// URL without credentials", ErrBadWebhookURL)
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: the host must be public", ErrBadWebhookURL)
+	}
+	if ip := net.ParseIP(host); ip != nil && !publicIP(ip) {
+		return fmt.Errorf("%w: the host must be public", ErrBadWebhookURL)
+	}
+	return nil
+}
+
+// publicIP reports whether ip is routable on the internet: not loopback,
+// private, link-local (cloud metadata lives there), multicast or
+// unspecified.
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// dialPublic is a net.Dialer Control hook. It runs after DNS resolution,
+// right before connecting, so a name that resolves to an internal address
+// cannot reach it, whatever it resolved to when the webhook was saved.
+func dialPublic(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("%w: %s", errPrivateAddress, host)
+	}
+	return nil
+}
+
+// normalizeWebhookEvents checks the event types and returns them sorted,
+// without repeats.
+func normalizeWebhookEvents(list []string) ([]string, error) {
+	if len(list) == 0 {
+		return nil, fmt.Errorf("%w: subscribe to at least one event", ErrBadWebhookEvents)
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, typ := range list {
+		known := false
+		for _, e := range WebhookEvents {
+			known = known || e == typ
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrBadWebhookEvents, typ)
+		}
+		if !seen[typ] {
+			seen[typ] = true
+			out = append(out, typ)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SignWebhook returns the X-Webhook-Signature of a request: the hex
+// HMAC-SHA256, keyed with the webhook secret, of the X-Webhook-Timestamp
+// value, a dot and the body.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff is the wait after the given failed attempt: 30s, doubling
+// with every attempt up to 6h.
+func webhookBackoff(attempt int) time.Duration {
+	d := webhookRetryBase
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= webhookRetryMax {
+			return webhookRetryMax
+		}
+	}
+	return d
+}
+
+type WebhookService struct {
+	repo *storage.WebhookRepo
+}
+
+func NewWebhookService(repo *storage.WebhookRepo) *WebhookService {
+	return &WebhookService{repo: repo}
+}
+
+// CreateWebhook registers a webhook. Without a secret one is generated; it
+// is returned here and never shown again.
+func (s *WebhookService) CreateWebhook(ctx context.Context, uid int64, rawURL string, types []string, secret string) (entity.Webhook, error) {
+	w := entity.Webhook{UserID: uid, URL: rawURL, Secret: secret, Active: true}
+	if err := prepareWebhook(&w, types); err != nil {
+		return entity.Webhook{}, err
+	}
+	if err := s.repo.Create(ctx, &w); err != nil {
+		return entity.Webhook{}, err
+	}
+	return w, nil
+}
+
+// prepareWebhook validates a webhook and fills in a secret when it has none.
+func prepareWebhook(w *entity.Webhook, types []string) error {
+	if err := checkWebhookURL(w.URL); err != nil {
+		return err
+	}
+	list, err := normalizeWebhookEvents(types)
+	if err != nil {
+		return err
+	}
+	w.Events = list
+
+	if w.Secret == "" {
+		if w.Secret, err = randomHex(32); err != nil {
+			return err
+		}
+	}
+	if len(w.Secret) < MinWebhookSecretLen || len(w.Secret) > MaxWebhookSecretLen {
+		return fmt.Errorf("%w: use %d..%d characters", ErrBadWebhookSecret, MinWebhookSecretLen, MaxWebhookSecretLen)
+	}
+	return nil
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context, uid int64) ([]entity.Webhook, error) {
+	return s.repo.GetByUserID(ctx, uid)
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, uid, id int64) (entity.Webhook, error) {
+	w, err := s.repo.GetByID(ctx, id)
+	if err != nil || w.UserID != uid {
+		return entity.Webhook{}, ErrWebhookNotFound
+	}
+	return w, nil
+}
+
+// UpdateWebhook replaces the address, events and state of a webhook. An
+// empty secret keeps the current one.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, uid, id int64, rawURL string, types []string, secret string, active bool) (entity.Webhook, error) {
+	w, err := s.GetWebhook(ctx, uid, id)
+	if err != nil {
+		return entity.Webhook{}, err
+	}
+	w.URL, w.Active = rawURL, active
+	if secret != "" {
+		w.Secret = secret
+	}
+	if err := prepareWebhook(&w, types); err != nil {
+		return entity.Webhook{}, err
+	}
+	err = s.repo.Update(ctx, &w)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Webhook{}, ErrWebhookNotFound
+	}
+	if err != nil {
+		return entity.Webhook{}, err
+	}
+	return w, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, uid, id int64) error {
+	if _, err := s.GetWebhook(ctx, uid, id); err != nil {
+		return err
+	}
+	err := s.repo.Delete(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+// ListDeliveries returns the latest deliveries of a webhook, newest first.
+func (s *WebhookService) ListDeliveries(ctx context.Context, uid, id int64) ([]entity.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, uid, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeliveries(ctx, id, MaxDeliveryList)
+}
+
+func (s *WebhookService) delivery(ctx context.Context, uid, id, did int64) (entity.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, uid, id); err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+	d, err := s.repo.GetDelivery(ctx, did)
+	if err != nil || d.WebhookID != id {
+		return entity.WebhookDelivery{}, ErrDeliveryNotFound
+	}
+	return d, nil
+}
+
+// GetDelivery returns a delivery with the log of its attempts.
+func (s *WebhookService) GetDelivery(ctx context.Context, uid, id, did int64) (entity.WebhookDelivery, []entity.WebhookAttempt, error) {
+	d, err := s.delivery(ctx, uid, id, did)
+	if err != nil {
+		return entity.WebhookDelivery{}, nil, err
+	}
+	attempts, err := s.repo.ListAttempts(ctx, did)
+	if err != nil {
+		return entity.WebhookDelivery{}, nil, err
+	}
+	return d, attempts, nil
+}
+
+// Redeliver replays a past delivery as a new one, sent as soon as the
+// dispatcher gets to it.
+func (s *WebhookService) Redeliver(ctx context.Context, uid, id, did int64) (entity.WebhookDelivery, error) {
+	if _, err := s.delivery(ctx, uid, id, did); err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+	d, err := s.repo.Redeliver(ctx, did)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.WebhookDelivery{}, ErrDeliveryNotFound
+	}
+	return d, err
+}
+
+// webhookPayload is the body of a webhook request. ID identifies the event
+// and stays the same across retries and redeliveries.
+type webhookPayload struct {
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	TaskID     int64          `json:"task_id"`
+	UserID     int64          `json:"user_id"`
+	Data       map[string]any `json:"data,omitempty"`
+}
+
+// WebhookOutbox stores deliveries for the webhooks of a user.
+type WebhookOutbox interface {
+	Enqueue(ctx context.Context, userID int64, eventID, eventType string, payload []byte) (int, error)
+}
+
+// WebhookPublisher queues every event for the webhooks of the task owner
+// that subscribed to its type. The requests are made by WebhookDispatcher.
+type WebhookPublisher struct {
+	outbox WebhookOutbox
+}
+
+func NewWebhookPublisher(outbox WebhookOutbox) *WebhookPublisher {
+	return &WebhookPublisher{outbox: outbox}
+}
+
+func (p *WebhookPublisher) Publish(ctx context.Context, e events.Event) error {
+	id, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(webhookPayload{
+		ID:         id,
+		Type:       e.Type,
+		OccurredAt: e.OccurredAt,
+		TaskID:     e.TaskID,
+		UserID:     e.UserID,
+		Data:       e.Data,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = p.outbox.Enqueue(ctx, e.UserID, id, e.Type, body)
+	return err
+}
+
+// WebhookQueue hands out the deliveries that are due and takes back their
+// outcome.
+type WebhookQueue interface {
+	Due(ctx context.Context, limit int, lease time.Duration) ([]storage.DueDelivery, error)
+	Finish(ctx context.Context, d entity.WebhookDelivery, a entity.WebhookAttempt) error
+}
+
+// WebhookDispatcher sends queued deliveries. A delivery succeeds on a 2xx
+// response; anything else, redirects included, is retried with exponential
+// backoff until MaxWebhookAttempts.
+type WebhookDispatcher struct {
+	queue  WebhookQueue
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWebhookDispatcher sends with client, or, when it is nil, with a
+// client with a 10s timeout that only connects to public addresses and
+// ignores proxy settings, so the check applies to the receiver itself.
+// Redirects are never followed.
+func NewWebhookDispatcher(queue WebhookQueue, client *http.Client) *WebhookDispatcher {
+	c := http.Client{Timeout: webhookTimeout, Transport: publicTransport()}
+	if client != nil {
+		c = *client
+	}
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return &WebhookDispatcher{queue: queue, client: &c, now: time.Now}
+}
+
+func publicTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = (&net.Dialer{
+		Timeout:   webhookTimeout,
+		KeepAlive: 30 * time.Second,
+		Control:   dialPublic,
+	}).DialContext
+	return t
+}
+
+// RunOnce sends every delivery that is due and returns how many it tried.
+func (s *WebhookDispatcher) RunOnce(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		due, err := s.queue.Due(ctx, webhookBatch, webhookLease)
+		if err != nil {
+			return total, err
+		}
+
+		work := make(chan storage.DueDelivery)
+		var wg sync.WaitGroup
+		for i := 0; i < webhookWorkers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for d := range work {
+					s.deliver(ctx, d)
+				}
+			}()
+		}
+		for _, d := range due {
+			work <- d
+		}
+		close(work)
+		wg.Wait()
+
+		total += len(due)
+		if len(due) < webhookBatch || ctx.Err() != nil {
+			return total, nil
+		}
+	}
+}
+
+// Run sends due deliveries every interval until ctx is cancelled.
+func (s *WebhookDispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("webhooks: %v", err)
+		} else if n > 0 {
+			log.Printf("webhooks: sent %d", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *WebhookDispatcher) deliver(ctx context.Context, d storage.DueDelivery) {
+	a := s.attempt(ctx, d)
+	if err := s.queue.Finish(ctx, settleDelivery(d.WebhookDelivery, a), a); err != nil {
+		log.Printf("webhooks: delivery %d: %v", d.ID, err)
+	}
+}
+
+// attempt makes one signed request for a delivery.
+func (s *WebhookDispatcher) attempt(ctx context.Context, d storage.DueDelivery) entity.WebhookAttempt {
+	start := s.now()
+	a := entity.WebhookAttempt{DeliveryID: d.ID, Attempt: d.Attempts + 1, AttemptedAt: start}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	ts := start.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TaskManager-Webhooks")
+	req.Header.Set("X-Webhook-Event", d.EventType)
+	req.Header.Set("X-Webhook-Event-ID", d.EventID)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(d.ID, 10))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(ts, 10))
+	req.Header.Set("X-Webhook-Signature", SignWebhook(d.Secret, ts, d.Payload))
+
+	resp, err := s.client.Do(req)
+	a.Duration = s.now().Sub(start)
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	code := resp.StatusCode
+	a.ResponseCode = &code
+	if code < 200 || code > 299 {
+		a.Error = fmt.Sprintf("unexpected status %d", code)
+	}
+	return a
+}
+
+// settleDelivery applies the outcome of an attempt to its delivery.
+func settleDelivery(d entity.WebhookDelivery, a entity.WebhookAttempt) entity.WebhookDelivery {
+	d.Attempts = a.Attempt
+	d.ResponseCode = a.ResponseCode
+	d.LastError = a.Error
+	d.NextAttemptAt = nil
+	switch {
+	case a.Error == "":
+		d.Status = entity.DeliverySucceeded
+	case a.Attempt >= MaxWebhookAttempts:
+		d.Status = entity.DeliveryFailed
+	default:
+		d.Status = entity.DeliveryPending
+		next := a.AttemptedAt.Add(webhookBackoff(a.Attempt))
+		d.NextAttemptAt = &next
+	}
+	return d
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/events"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWebhookQueue keeps deliveries in memory and hands out the pending
+// ones whose time has come on its own clock.
+type fakeWebhookQueue struct {
+	mu         sync.Mutex
+	now        time.Time
+	url        string
+	secret     string
+	deliveries []entity.WebhookDelivery
+	attempts   []entity.WebhookAttempt
+}
+
+func (q *fakeWebhookQueue) Due(_ context.Context, limit int, _ time.Duration) ([]storage.DueDelivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var due []storage.DueDelivery
+	for _, d := range q.deliveries {
+		if d.Status == entity.DeliveryPending && !d.NextAttemptAt.After(q.now) && len(due) < limit {
+			due = append(due, storage.DueDelivery{WebhookDelivery: d, URL: q.url, Secret: q.secret})
+		}
+	}
+	return due, nil
+}
+
+func (q *fakeWebhookQueue) Finish(_ context.Context, d entity.WebhookDelivery, a entity.WebhookAttempt) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range q.deliveries {
+		if q.deliveries[i].ID == d.ID {
+			q.deliveries[i] = d
+		}
+	}
+	q.attempts = append(q.attempts, a)
+	return nil
+}
+
+func (q *fakeWebhookQueue) Enqueue(_ context.Context, _ int64, eventID, eventType string, payload []byte) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now
+	q.deliveries = append(q.deliveries, entity.WebhookDelivery{
+		ID:            int64(len(q.deliveries) + 1),
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       payload,
+		Status:        entity.DeliveryPending,
+		NextAttemptAt: &now,
+	})
+	return 1, nil
+}
+
+// receiver is a local webhook endpoint that checks signatures and answers
+// with the queued status codes, then with 200.
+type receiver struct {
+	t      *testing.T
+	secret string
+	mu     sync.Mutex
+	codes  []int
+	got    []*http.Request
+	bodies [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	ts, err := strconv.ParseInt(r.Header.Get("X-Webhook-Timestamp"), 10, 64)
+	if err != nil || r.Header.Get("X-Webhook-Signature") != SignWebhook(rc.secret, ts, body) {
+		rc.t.Errorf("bad signature %q", r.Header.Get("X-Webhook-Signature"))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.got = append(rc.got, r)
+	rc.bodies = append(rc.bodies, body)
+	code := http.StatusOK
+	if len(rc.codes) > 0 {
+		code, rc.codes = rc.codes[0], rc.codes[1:]
+	}
+	if code == http.StatusFound {
+		w.Header().Set("Location", "/elsewhere")
+	}
+	w.WriteHeader(code)
+}
+
+func newWebhookTest(t *testing.T, codes ...int) (*receiver, *fakeWebhookQueue, *WebhookDispatcher) {
+	rc := &receiver{t: t, secret: "0123456789abcdef0123", codes: codes}
+	srv := httptest.NewTLSServer(rc)
+	t.Cleanup(srv.Close)
+
+	q := &fakeWebhookQueue{now: time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC), url: srv.URL + "/hook", secret: rc.secret}
+	d := NewWebhookDispatcher(q, srv.Client())
+	d.now = func() time.Time { return q.now }
+	return rc, q, d
+}
+
+func TestWebhooks_PublishAndDeliver(t *testing.T) {
+	rc, q, d := newWebhookTest(t)
+
+	pub := NewWebhookPublisher(q)
+	err := pub.Publish(context.Background(), events.Event{
+		Type:       events.TypeTaskStatusChanged,
+		TaskID:     2,
+		UserID:     1,
+		Watchers:   []int64{7},
+		OccurredAt: q.now,
+		Data:       map[string]any{"status": StatusDone, "from_status": StatusTodo},
+	})
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	n, err := d.RunOnce(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("RunOnce = %d, %v; want 1 delivery", n, err)
+	}
+	if len(rc.got) != 1 {
+		t.Fatalf("receiver got %d requests", len(rc.got))
+	}
+	r := rc.got[0]
+	if r.Header.Get("X-Webhook-Event") != events.TypeTaskStatusChanged || r.Header.Get("X-Webhook-Delivery") != "1" {
+		t.Errorf("unexpected headers: %v", r.Header)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(rc.bodies[0], &body); err != nil {
+		t.Fatalf("body: %v", err)
+	}
+	if body["id"] != r.Header.Get("X-Webhook-Event-ID") || body["type"] != events.TypeTaskStatusChanged || body["task_id"] != float64(2) {
+		t.Errorf("unexpected body: %s", rc.bodies[0])
+	}
+	if _, ok := body["watchers"]; ok {
+		t.Errorf("watchers leaked into the payload: %s", rc.bodies[0])
+	}
+
+	got := q.deliveries[0]
+	if got.Status != entity.DeliverySucceeded || got.Attempts != 1 || got.ResponseCode == nil || *got.ResponseCode != 200 {
+		t.Errorf("delivery: got %+v", got)
+	}
+	if len(q.attempts) != 1 || q.attempts[0].Error != "" {
+		t.Errorf("attempts: got %+v", q.attempts)
+	}
+}
+
+func TestWebhookDispatcher_RetriesWithBackoff(t *testing.T) {
+	rc, q, d := newWebhookTest(t, http.StatusInternalServerError, http.StatusFound)
+	q.Enqueue(context.Background(), 1, "ev1", events.TypeTaskCreated, []byte(`{"id":"ev1"}`))
+
+	wantCodes := []int{500, 302, 200}
+	wantWaits := []time.Duration{30 * time.Second, time.Minute}
+	for i, code := range wantCodes {
+		if n, err := d.RunOnce(context.Background()); err != nil || n != 1 {
+			t.Fatalf("run %d: RunOnce = %d, %v", i, n, err)
+		}
+		del := q.deliveries[0]
+		if del.ResponseCode == nil || *del.ResponseCode != code {
+			t.Fatalf("run %d: response code %v, want %d", i, del.ResponseCode, code)
+		}
+		if i < len(wantWaits) {
+			if del.Status != entity.DeliveryPending || !del.NextAttemptAt.Equal(q.now.Add(wantWaits[i])) {
+				t.Fatalf("run %d: got %+v, want a retry in %v", i, del, wantWaits[i])
+			}
+			if n, _ := d.RunOnce(context.Background()); n != 0 {
+				t.Fatalf("run %d: retried before the backoff ran out", i)
+			}
+			q.now = *del.NextAttemptAt
+		}
+	}
+
+	if del := q.deliveries[0]; del.Status != entity.DeliverySucceeded || del.Attempts != 3 {
+		t.Errorf("final delivery: %+v", del)
+	}
+	if len(rc.got) != 3 || len(q.attempts) != 3 {
+		t.Errorf("got %d requests and %d logged attempts, want 3", len(rc.got), len(q.attempts))
+	}
+}
+
+func TestWebhookDispatcher_GivesUp(t *testing.T) {
+	codes := make([]int, MaxWebhookAttempts)
+	for i := range codes {
+		codes[i] = http.StatusServiceUnavailable
+	}
+	_, q, d := newWebhookTest(t, codes...)
+	q.Enqueue(context.Background(), 1, "ev1", events.TypeTaskDeleted, []byte(`{}`))
+
+	for i := 0; i < MaxWebhookAttempts; i++ {
+		d.RunOnce(context.Background())
+		if next := q.deliveries[0].NextAttemptAt; next != nil {
+			q.now = *next
+		}
+	}
+	del := q.deliveries[0]
+	if del.Status != entity.DeliveryFailed || del.Attempts != MaxWebhookAttempts || del.NextAttemptAt != nil {
+		t.Errorf("got %+v, want failed after %d attempts", del, MaxWebhookAttempts)
+	}
+	if n, _ := d.RunOnce(context.Background()); n != 0 {
+		t.Errorf("a failed delivery was sent again")
+	}
+}
+
+func TestWebhookDispatcher_UnreachableReceiver(t *testing.T) {
+	q := &fakeWebhookQueue{now: time.Now(), url: "https://127.0.0.1:1/hook", secret: "0123456789abcdef"}
+	q.Enqueue(context.Background(), 1, "ev1", events.TypeTaskCreated, []byte(`{}`))
+
+	NewWebhookDispatcher(q, nil).RunOnce(context.Background())
+	del := q.deliveries[0]
+	if del.Status != entity.DeliveryPending || del.ResponseCode != nil || del.LastError == "" {
+		t.Errorf("got %+v, want a pending retry with the error logged", del)
+	}
+}
+
+func TestWebhookDispatcher_PrivateAddress(t *testing.T) {
+	rc := &receiver{t: t, secret: "0123456789abcdef0123"}
+	srv := httptest.NewTLSServer(rc)
+	t.Cleanup(srv.Close)
+
+	// The default client refuses to connect to the loopback receiver.
+	q := &fakeWebhookQueue{now: time.Now(), url: srv.URL + "/hook", secret: rc.secret}
+	q.Enqueue(context.Background(), 1, "ev1", events.TypeTaskCreated, []byte(`{}`))
+
+	NewWebhookDispatcher(q, nil).RunOnce(context.Background())
+	del := q.deliveries[0]
+	if del.ResponseCode != nil || !strings.Contains(del.LastError, errPrivateAddress.Error()) {
+		t.Errorf("got %+v, want the delivery blocked", del)
+	}
+	if len(rc.got) != 0 {
+		t.Errorf("the receiver got %d requests", len(rc.got))
+	}
+}
+
+func TestDialPublic(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:443", false},
+		{"[::1]:443", false},
+		{"10.1.2.3:443", false},
+		{"172.16.0.1:443", false},
+		{"192.168.1.1:443", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:443", false},
+		{"[fd00::1]:443", false},
+		{"0.0.0.0:443", false},
+		{"[::ffff:127.0.0.1]:443", false},
+		{"224.0.0.1:443", false},
+	}
+	for _, tt := range tests {
+		err := dialPublic("tcp", tt.address, nil)
+		if tt.allowed && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.address, err)
+		}
+		if !tt.allowed && !errors.Is(err, errPrivateAddress) {
+			t.Errorf("%s: got %v, want %v", tt.address, err, errPrivateAddress)
+		}
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute}
+	for i, w := range want {
+		if got := webhookBackoff(i + 1); got != w {
+			t.Errorf("attempt %d: got %v, want %v", i+1, got, w)
+		}
+	}
+	if got := webhookBackoff(40); got != webhookRetryMax {
+		t.Errorf("attempt 40: got %v, want %v", got, webhookRetryMax)
+	}
+}
+
+func TestPrepareWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		events  []string
+		secret  string
+		wantErr error
+	}{
+		{name: "valid", url: "https://example.com/hook", events: []string{events.TypeTaskUpdated, events.TypeTaskCreated, events.TypeTaskUpdated}},
+		{name: "plain http", url: "http://example.com/hook", events: []string{events.TypeTaskCreated}, wantErr: ErrBadWebhookURL},
+		{name: "relative", url: "/hook", events: []string{events.TypeTaskCreated}, wantErr: ErrBadWebhookURL},
+		{name: "credentials", url: "https://user:pw@example.com/", events: []string{events.TypeTaskCreated}, wantErr: ErrBadWebhookURL},
+		{name: "loopback", url: "https://127.0.0.1/hook", events: []string{events.TypeTaskCreated}, wantErr: ErrBadWebhookURL},
+		{name: "metadata", url: "https://169.254.169.254/latest", events: []string{events.TypeTaskCreated}, wantErr: ErrBadWebhookURL},
+		{name: "private v6", url: "https://[fd00::1]/hook", events: []string{events.TypeTaskCreated}, wantErr: ErrBadWebhookURL},
+		{name: "localhost", url: "https://localhost:8443/hook", events: []string{events.TypeTaskCreated}, wantErr: ErrBadWebhookURL},
+		{name: "no events", url: "https://example.com/hook", wantErr: ErrBadWebhookEvents},
+		{name: "unknown event", url: "https://example.com/hook", events: []string{"task.exploded"}, wantErr: ErrBadWebhookEvents},
+		{name: "short secret", url: "https://example.com/hook", events: []string{events.TypeTaskCreated}, secret: "abc", wantErr: ErrBadWebhookSecret},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := entity.Webhook{URL: tt.url, Secret: tt.secret}
+			err := prepareWebhook(&w, tt.events)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(w.Events, []string{events.TypeTaskCreated, events.TypeTaskUpdated}) {
+				t.Errorf("events: got %v", w.Events)
+			}
+			if len(w.Secret) != 64 {
+				t.Errorf("generated secret %q", w.Secret)
+			}
+		})
+	}
+}
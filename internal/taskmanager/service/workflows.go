@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/storage"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const MaxWorkflowNameLen = 50
+
+var (
+	ErrEmptyWorkflowName = errors.New("empty workflow name")
+	ErrBadWorkflowName   = errors.New("bad workflow name")
+	ErrBadWorkflowDef    = errors.New("bad workflow definition")
+	ErrWorkflowNotFound  = errors.New("workflow not found")
+	ErrWorkflowExists    = errors.New("workflow already exists")
+	ErrWorkflowInUse     = errors.New("workflow is used by tasks")
+	ErrStateInUse        = errors.New("workflow state is used by tasks")
+	ErrBadWorkflow       = errors.New("bad workflow")
+
+	ErrBadTransition   = errors.New("transition not allowed")
+	ErrTransitionGuard = errors.New("transition guard failed")
+)
+
+var stateNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// DefaultWorkflow is followed by tasks that have no workflow_id: todo,
+// doing and done, with every move between them allowed.
+var DefaultWorkflow = entity.Workflow{
+	Name:         "default",
+	InitialState: StatusTodo,
+	States:       []string{StatusTodo, StatusInProgress, StatusDone},
+	Transitions: []entity.WorkflowTransition{
+		{From: StatusTodo, To: StatusInProgress},
+		{From: StatusTodo, To: StatusDone},
+		{From: StatusInProgress, To: StatusTodo},
+		{From: StatusInProgress, To: StatusDone},
+		{From: StatusDone, To: StatusTodo},
+		{From: StatusDone, To: StatusInProgress},
+	},
+}
+
+// TransitionError explains why a status change was refused: either the
+// workflow has no such move, and Allowed lists the ones it has, or the move
+// exists but the task does not satisfy its Guard.
+type TransitionError struct {
+	From    string
+	To      string
+	Allowed []string
+	Guard   string
+}
+
+func (e *TransitionError) Error() string {
+	if e.Guard != "" {
+		return fmt.Sprintf("moving from %s to %s requires %s", e.From, e.To, e.Guard)
+	}
+	if len(e.Allowed) == 0 {
+		return fmt.Sprintf("cannot move from %s to %s, %s is final", e.From, e.To, e.From)
+	}
+	return fmt.Sprintf("cannot move from %s to %s, allowed next states: %s", e.From, e.To, strings.Join(e.Allowed, ", "))
+}
+
+func (e *TransitionError) Unwrap() error {
+	if e.Guard != "" {
+		return ErrTransitionGuard
+	}
+	return ErrBadTransition
+}
+
+func hasState(wf entity.Workflow, state string) bool {
+	for _, s := range wf.States {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func nextStates(wf entity.Workflow, from string) []string {
+	var next []string
+	for _, tr := range wf.Transitions {
+		if tr.From == from {
+			next = append(next, tr.To)
+		}
+	}
+	return next
+}
+
+func findTransition(wf entity.Workflow, from, to string) (entity.WorkflowTransition, bool) {
+	for _, tr := range wf.Transitions {
+		if tr.From == from && tr.To == to {
+			return tr, true
+		}
+	}
+	return entity.WorkflowTransition{}, false
+}
+
+// validateWorkflow normalizes a workflow definition in place. Every
+// workflow must have a done state: progress, dependencies, reminders and
+// recurrence all treat it as the finished state.
+func validateWorkflow(wf *entity.Workflow) error {
+	wf.Name = strings.TrimSpace(wf.Name)
+	if wf.Name == "" {
+		return ErrEmptyWorkflowName
+	}
+	if utf8.RuneCountInString(wf.Name) > MaxWorkflowNameLen {
+		return ErrBadWorkflowName
+	}
+
+	if len(wf.States) == 0 {
+		return fmt.Errorf("%w: no states", ErrBadWorkflowDef)
+	}
+	seen := make(map[string]bool, len(wf.States))
+	for _, s := range wf.States {
+		if !stateNameRe.MatchString(s) {
+			return fmt.Errorf("%w: invalid state name %q", ErrBadWorkflowDef, s)
+		}
+		if seen[s] {
+			return fmt.Errorf("%w: duplicate state %q", ErrBadWorkflowDef, s)
+		}
+		seen[s] = true
+	}
+	if !seen[StatusDone] {
+		return fmt.Errorf("%w: state %q is required", ErrBadWorkflowDef, StatusDone)
+	}
+	if wf.InitialState == "" {
+		wf.InitialState = wf.States[0]
+	}
+	if !seen[wf.InitialState] {
+		return fmt.Errorf("%w: unknown initial state %q", ErrBadWorkflowDef, wf.InitialState)
+	}
+
+	moves := make(map[[2]string]bool, len(wf.Transitions))
+	for _, tr := range wf.Transitions {
+		if !seen[tr.From] || !seen[tr.To] {
+			return fmt.Errorf("%w: transition %s -> %s uses an unknown state", ErrBadWorkflowDef, tr.From, tr.To)
+		}
+		if tr.From == tr.To {
+			return fmt.Errorf("%w: transition %s -> %s goes nowhere", ErrBadWorkflowDef, tr.From, tr.To)
+		}
+		key := [2]string{tr.From, tr.To}
+		if moves[key] {
+			return fmt.Errorf("%w: duplicate transition %s -> %s", ErrBadWorkflowDef, tr.From, tr.To)
+		}
+		moves[key] = true
+	}
+	return nil
+}
+
+// checkTransition decides whether cur may move to status under wf.
+// description is the one the task will have after the update, so a PATCH
+// that fills it in and changes the status at once passes the guard.
+func checkTransition(wf entity.Workflow, cur entity.Task, status, description string) error {
+	if status == cur.Status {
+		return nil
+	}
+	if !hasState(wf, status) {
+		return ErrBadStatus
+	}
+	tr, ok := findTransition(wf, cur.Status, status)
+	if !ok {
+		return &TransitionError{From: cur.Status, To: status, Allowed: nextStates(wf, cur.Status)}
+	}
+	if err := checkReady(wf, cur, status); err != nil {
+		return err
+	}
+	if tr.RequiresDescription && strings.TrimSpace(description) == "" {
+		return &TransitionError{From: cur.Status, To: status, Guard: "a description"}
+	}
+	if tr.RequiresAssignee && cur.AssigneeID == nil {
+		return &TransitionError{From: cur.Status, To: status, Guard: "an assignee"}
+	}
+	return nil
+}
+
+// checkReady holds the rules that apply whatever the workflow says: a
+// blocked task stays in the initial state and a parent is done only after
+// its subtasks.
+func checkReady(wf entity.Workflow, cur entity.Task, status string) error {
+	if status == cur.Status {
+		return nil
+	}
+	if status != wf.InitialState && cur.OpenBlockers > 0 {
+		return ErrBlocked
+	}
+	if status == StatusDone && hasOpenSubtasks(cur) {
+		return ErrOpenSubtasks
+	}
+	return nil
+}
+
+// workflowOf returns the workflow a task follows.
+func (s *TaskService) workflowOf(ctx context.Context, t entity.Task) (entity.Workflow, error) {
+	if t.WorkflowID == nil {
+		return DefaultWorkflow, nil
+	}
+	return s.repo.GetWorkflow(ctx, *t.WorkflowID)
+}
+
+// userWorkflow resolves a workflow a user wants to put a task on; nil means
+// the default one.
+func (s *TaskService) userWorkflow(ctx context.Context, uid int64, id *int64) (entity.Workflow, error) {
+	if id == nil {
+		return DefaultWorkflow, nil
+	}
+	wf, err := s.repo.GetWorkflow(ctx, *id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && wf.UserID != uid) {
+		return entity.Workflow{}, ErrBadWorkflow
+	}
+	return wf, err
+}
+
+type WorkflowService struct {
+	repo *storage.WorkflowRepo
+}
+
+func NewWorkflowService(repo *storage.WorkflowRepo) *WorkflowService {
+	return &WorkflowService{repo: repo}
+}
+
+func (s *WorkflowService) CreateWorkflow(ctx context.Context, uid int64, wf entity.Workflow) (entity.Workflow, error) {
+	if err := validateWorkflow(&wf); err != nil {
+		return entity.Workflow{}, err
+	}
+	wf.UserID = uid
+	if err := s.repo.Create(ctx, &wf); err != nil {
+		if errors.Is(err, storage.ErrDuplicate) {
+			return entity.Workflow{}, ErrWorkflowExists
+		}
+		return entity.Workflow{}, err
+	}
+	return wf, nil
+}
+
+func (s *WorkflowService) ListWorkflows(ctx context.Context, uid int64) ([]entity.Workflow, error) {
+	return s.repo.GetByUserID(ctx, uid)
+}
+
+func (s *WorkflowService) GetWorkflow(ctx context.Context, uid, id int64) (entity.Workflow, error) {
+	wf, err := s.repo.GetByID(ctx, id)
+	if err != nil || wf.UserID != uid {
+		return entity.Workflow{}, ErrWorkflowNotFound
+	}
+	return wf, nil
+}
+
+// UpdateWorkflow replaces a workflow definition. States that tasks are in
+// cannot be removed.
+func (s *WorkflowService) UpdateWorkflow(ctx context.Context, uid, id int64, wf entity.Workflow) (entity.Workflow, error) {
+	if _, err := s.GetWorkflow(ctx, uid, id); err != nil {
+		return entity.Workflow{}, err
+	}
+	if err := validateWorkflow(&wf); err != nil {
+		return entity.Workflow{}, err
+	}
+	wf.ID, wf.UserID = id, uid
+
+	err := s.repo.Update(ctx, &wf)
+	switch {
+	case errors.Is(err, storage.ErrDuplicate):
+		return entity.Workflow{}, ErrWorkflowExists
+	case errors.Is(err, storage.ErrStateInUse):
+		return entity.Workflow{}, ErrStateInUse
+	case errors.Is(err, sql.ErrNoRows):
+		return entity.Workflow{}, ErrWorkflowNotFound
+	case err != nil:
+		return entity.Workflow{}, err
+	}
+	return wf, nil
+}
+
+func (s *WorkflowService) DeleteWorkflow(ctx context.Context, uid, id int64) error {
+	if _, err := s.GetWorkflow(ctx, uid, id); err != nil {
+		return err
+	}
+	err := s.repo.Delete(ctx, id)
+	switch {
+	case errors.Is(err, storage.ErrWorkflowInUse):
+		return ErrWorkflowInUse
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrWorkflowNotFound
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+)
+
+type AttachmentRepo struct {
+	db *sql.DB
+}
+
+func NewAttachmentRepo(db *sql.DB) *AttachmentRepo {
+	return &AttachmentRepo{db: db}
+}
+
+const attachmentColumns = "id, task_id, user_id, name, size, content_type, checksum, blob_key, created_at"
+
+func scanAttachment(row rowScanner, a *entity.Attachment) error {
+	return row.Scan(&a.ID, &a.TaskID, &a.UserID, &a.Name, &a.Size, &a.ContentType, &a.Checksum, &a.BlobKey, &a.CreatedAt)
+}
+
+func (r *AttachmentRepo) Create(ctx context.Context, a *entity.Attachment) error {
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO attachments (task_id, user_id, name, size, content_type, checksum, blob_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at;
+	`, a.TaskID, a.UserID, a.Name, a.Size, a.ContentType, a.Checksum, a.BlobKey)
+	return row.Scan(&a.ID, &a.CreatedAt)
+}
+
+func (r *AttachmentRepo) GetByID(ctx context.Context, id int64) (entity.Attachment, error) {
+	var a entity.Attachment
+	err := scanAttachment(r.db.QueryRowContext(ctx, "SELECT "+attachmentColumns+" FROM attachments WHERE id = $1", id), &a)
+	return a, err
+}
+
+func (r *AttachmentRepo) ListByTask(ctx context.Context, taskID int64) ([]entity.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+attachmentColumns+" FROM attachments WHERE task_id = $1 ORDER BY id", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []entity.Attachment
+	for rows.Next() {
+		var a entity.Attachment
+		if err := scanAttachment(rows, &a); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+// Delete removes the metadata; the trigger queues the blob key in
+// blob_garbage.
+func (r *AttachmentRepo) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM attachments WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Garbage returns up to limit blob keys that no attachment refers to any
+// more.
+func (r *AttachmentRepo) Garbage(ctx context.Context, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT blob_key FROM blob_garbage ORDER BY created_at LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// DropGarbage forgets a key once its blob is gone.
+func (r *AttachmentRepo) DropGarbage(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM blob_garbage WHERE blob_key = $1", key)
+	return err
+}
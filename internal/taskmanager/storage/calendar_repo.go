@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+)
+
+// CalendarRepo keeps the secret token of each user's calendar feed. Only
+// a hash of the token is stored.
+type CalendarRepo struct {
+	db *sql.DB
+}
+
+func NewCalendarRepo(db *sql.DB) *CalendarRepo {
+	return &CalendarRepo{db: db}
+}
+
+// SetToken replaces the token of a user, which revokes the previous one.
+func (r *CalendarRepo) SetToken(ctx context.Context, userID int64, hash string) error {
+	query := `
+		INSERT INTO calendar_tokens (user_id, token_hash)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = now();
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, hash)
+	return err
+}
+
+func (r *CalendarRepo) TokenHash(ctx context.Context, userID int64) (string, error) {
+	var hash string
+	err := r.db.QueryRowContext(ctx, `SELECT token_hash FROM calendar_tokens WHERE user_id = $1;`, userID).Scan(&hash)
+	return hash, err
+}
+
+func (r *CalendarRepo) DeleteToken(ctx context.Context, userID int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM calendar_tokens WHERE user_id = $1;`, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+//go:generate mockgen -source=comments_repo.go -destination=../mocks/mock_comment_repo.go -package=mocks
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"time"
+)
+
+type CommentRepository interface {
+	Create(ctx context.Context, c *entity.Comment) error
+	GetByID(ctx context.Context, id int64) (entity.Comment, error)
+	ListTopLevel(ctx context.Context, taskID int64, after *time.Time, afterID int64, limit int) ([]entity.Comment, error)
+	ListReplies(ctx context.Context, parentIDs []int64) ([]entity.Comment, error)
+	UpdateBody(ctx context.Context, id int64, body string) (entity.Comment, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type CommentRepo struct {
+	db *sql.DB
+}
+
+func NewCommentRepo(db *sql.DB) *CommentRepo {
+	return &CommentRepo{db: db}
+}
+
+const commentColumns = "id, task_id, user_id, parent_id, body, edited, created_at, updated_at"
+
+func scanComment(row rowScanner, c *entity.Comment) error {
+	return row.Scan(&c.ID, &c.TaskID, &c.UserID, &c.ParentID, &c.Body, &c.Edited, &c.CreatedAt, &c.UpdatedAt)
+}
+
+func scanComments(rows *sql.Rows) ([]entity.Comment, error) {
+	var comments []entity.Comment
+	for rows.Next() {
+		var c entity.Comment
+		if err := scanComment(rows, &c); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (r *CommentRepo) Create(ctx context.Context, c *entity.Comment) error {
+	row := r.db.QueryRowContext(ctx,
+		"INSERT INTO comments (task_id, user_id, parent_id, body) VALUES ($1, $2, $3, $4) RETURNING id, edited, created_at, updated_at",
+		c.TaskID, c.UserID, c.ParentID, c.Body,
+	)
+	return row.Scan(&c.ID, &c.Edited, &c.CreatedAt, &c.UpdatedAt)
+}
+
+func (r *CommentRepo) GetByID(ctx context.Context, id int64) (entity.Comment, error) {
+	var c entity.Comment
+	err := scanComment(r.db.QueryRowContext(ctx, "SELECT "+commentColumns+" FROM comments WHERE id = $1", id), &c)
+	if err != nil {
+		return entity.Comment{}, err
+	}
+	return c, nil
+}
+
+func (r *CommentRepo) ListTopLevel(ctx context.Context, taskID int64, after *time.Time, afterID int64, limit int) ([]entity.Comment, error) {
+	query := "SELECT " + commentColumns + " FROM comments WHERE task_id = $1 AND parent_id IS NULL"
+	args := []interface{}{taskID}
+	if after != nil {
+		query += " AND (created_at, id) > ($2, $3)"
+		args = append(args, *after, afterID)
+	}
+	query += fmt.Sprintf(" ORDER BY created_at, id LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanComments(rows)
+}
+
+func (r *CommentRepo) ListReplies(ctx context.Context, parentIDs []int64) ([]entity.Comment, error) {
+	if len(parentIDs) == 0 {
+		return nil, nil
+	}
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE parent_id = ANY($1) ORDER BY created_at, id",
+		parentIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanComments(rows)
+}
+
+func (r *CommentRepo) UpdateBody(ctx context.Context, id int64, body string) (entity.Comment, error) {
+	var c entity.Comment
+	err := scanComment(r.db.QueryRowContext(ctx,
+		"UPDATE comments SET body = $1, edited = true, updated_at = now() WHERE id = $2 RETURNING "+commentColumns,
+		body, id,
+	), &c)
+	if err != nil {
+		return entity.Comment{}, err
+	}
+	return c, nil
+}
+
+func (r *CommentRepo) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM comments WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
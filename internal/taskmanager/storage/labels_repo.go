@@ -0,0 +1,157 @@
+//go:generate mockgen -source=labels_repo.go -destination=../mocks/mock_label_repo.go -package=mocks
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrDuplicate = errors.New("duplicate key")
+
+type LabelRepository interface {
+	Create(ctx context.Context, label *entity.Label) error
+	GetByID(ctx context.Context, id int64) (entity.Label, error)
+	GetByUserID(ctx context.Context, userID int64) ([]entity.Label, error)
+	Patch(ctx context.Context, id int64, name, color *string) (entity.Label, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type LabelRepo struct {
+	db *sql.DB
+}
+
+func NewLabelRepo(db *sql.DB) *LabelRepo {
+	return &LabelRepo{db: db}
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+// violatesForeignKey reports whether err is a foreign key violation of the
+// named constraint, for tables that reference more than one other table.
+func violatesForeignKey(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503" && pgErr.ConstraintName == constraint
+}
+
+func (r *LabelRepo) Create(ctx context.Context, label *entity.Label) error {
+	row := r.db.QueryRowContext(ctx,
+		"INSERT INTO labels (user_id, name, color) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at",
+		label.UserID, label.Name, label.Color,
+	)
+	err := row.Scan(&label.ID, &label.CreatedAt, &label.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *LabelRepo) GetByID(ctx context.Context, id int64) (entity.Label, error) {
+	row := r.db.QueryRowContext(ctx,
+		"SELECT id, user_id, name, color, created_at, updated_at FROM labels WHERE id = $1",
+		id,
+	)
+
+	var l entity.Label
+	err := row.Scan(&l.ID, &l.UserID, &l.Name, &l.Color, &l.CreatedAt, &l.UpdatedAt)
+	if err != nil {
+		return entity.Label{}, err
+	}
+	return l, nil
+}
+
+func (r *LabelRepo) GetByUserID(ctx context.Context, userID int64) ([]entity.Label, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, user_id, name, color, created_at, updated_at FROM labels WHERE user_id = $1 ORDER BY name",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var labels []entity.Label
+	for rows.Next() {
+		var l entity.Label
+		if err := rows.Scan(&l.ID, &l.UserID, &l.Name, &l.Color, &l.CreatedAt, &l.UpdatedAt); err != nil {
+			return nil, err
+		}
+		labels = append(labels, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// touchLabelTasks follows a data-modifying CTE named l that returns the id
+// of a changed label and bumps the version of the tasks that show it.
+const touchLabelTasks = `, t AS (
+	UPDATE tasks SET version = version + 1
+	WHERE id IN (SELECT tl.task_id FROM task_labels tl JOIN l ON l.id = tl.label_id)
+)`
+
+func (r *LabelRepo) Patch(ctx context.Context, id int64, name, color *string) (entity.Label, error) {
+	if name == nil && color == nil {
+		return entity.Label{}, errors.New("nothing to update")
+	}
+
+	query := "WITH l AS (UPDATE labels SET "
+	params := []interface{}{}
+	idx := 1
+
+	if name != nil {
+		query += fmt.Sprintf("name = $%d", idx)
+		params = append(params, *name)
+		idx++
+	}
+	if color != nil {
+		if len(params) > 0 {
+			query += ", "
+		}
+		query += fmt.Sprintf("color = $%d", idx)
+		params = append(params, *color)
+		idx++
+	}
+
+	query += fmt.Sprintf(", updated_at = now() WHERE id = $%d RETURNING id, user_id, name, color, created_at, updated_at)", idx)
+	query += touchLabelTasks + " SELECT id, user_id, name, color, created_at, updated_at FROM l"
+	params = append(params, id)
+
+	var l entity.Label
+	err := r.db.QueryRowContext(ctx, query, params...).
+		Scan(&l.ID, &l.UserID, &l.Name, &l.Color, &l.CreatedAt, &l.UpdatedAt)
+	if isUniqueViolation(err) {
+		return entity.Label{}, ErrDuplicate
+	}
+	if err != nil {
+		return entity.Label{}, err
+	}
+	return l, nil
+}
+
+func (r *LabelRepo) Delete(ctx context.Context, id int64) error {
+	var n int64
+	err := r.db.QueryRowContext(ctx,
+		"WITH l AS (DELETE FROM labels WHERE id = $1 RETURNING id)"+touchLabelTasks+" SELECT count(*) FROM l",
+		id,
+	).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
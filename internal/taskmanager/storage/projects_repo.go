@@ -0,0 +1,213 @@
+//go:generate mockgen -source=projects_repo.go -destination=../mocks/mock_project_repo.go -package=mocks
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+)
+
+type ProjectRepository interface {
+	Create(ctx context.Context, p *entity.Project) error
+	GetByID(ctx context.Context, id int64) (entity.Project, error)
+	GetByMember(ctx context.Context, userID int64) ([]entity.Project, error)
+	Patch(ctx context.Context, id int64, name, description *string) (entity.Project, error)
+	Delete(ctx context.Context, id int64) error
+	GetMember(ctx context.Context, projectID, userID int64) (entity.ProjectMember, error)
+	ListMembers(ctx context.Context, projectID int64) ([]entity.ProjectMember, error)
+	AddMember(ctx context.Context, m *entity.ProjectMember) error
+	RemoveMember(ctx context.Context, projectID, userID int64) error
+}
+
+const projectColumns = "id, owner_id, name, description, created_at, updated_at"
+
+func scanProject(row rowScanner, p *entity.Project) error {
+	return row.Scan(&p.ID, &p.OwnerID, &p.Name, &p.Description, &p.CreatedAt, &p.UpdatedAt)
+}
+
+type ProjectRepo struct {
+	db *sql.DB
+}
+
+func NewProjectRepo(db *sql.DB) *ProjectRepo {
+	return &ProjectRepo{db: db}
+}
+
+// Create inserts the project and registers its owner as the first member.
+func (r *ProjectRepo) Create(ctx context.Context, p *entity.Project) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO projects (owner_id, name, description) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at",
+		p.OwnerID, p.Name, p.Description,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if isForeignKeyViolation(err) {
+		return ErrUnknownUser
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO project_members (project_id, user_id, role) VALUES ($1, $2, 'owner')",
+		p.ID, p.OwnerID,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *ProjectRepo) GetByID(ctx context.Context, id int64) (entity.Project, error) {
+	var p entity.Project
+	err := scanProject(r.db.QueryRowContext(ctx, "SELECT "+projectColumns+" FROM projects WHERE id = $1", id), &p)
+	if err != nil {
+		return entity.Project{}, err
+	}
+	return p, nil
+}
+
+func (r *ProjectRepo) GetByMember(ctx context.Context, userID int64) ([]entity.Project, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+projectColumns+`
+		FROM projects
+		WHERE id IN (SELECT project_id FROM project_members WHERE user_id = $1)
+		ORDER BY name, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []entity.Project
+	for rows.Next() {
+		var p entity.Project
+		if err := scanProject(rows, &p); err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (r *ProjectRepo) Patch(ctx context.Context, id int64, name, description *string) (entity.Project, error) {
+	if name == nil && description == nil {
+		return entity.Project{}, errors.New("nothing to update")
+	}
+
+	query := "UPDATE projects SET updated_at = now()"
+	params := []interface{}{}
+	idx := 1
+
+	if name != nil {
+		query += fmt.Sprintf(", name = $%d", idx)
+		params = append(params, *name)
+		idx++
+	}
+	if description != nil {
+		query += fmt.Sprintf(", description = $%d", idx)
+		params = append(params, *description)
+		idx++
+	}
+
+	query += fmt.Sprintf(" WHERE id = $%d RETURNING %s", idx, projectColumns)
+	params = append(params, id)
+
+	var p entity.Project
+	if err := scanProject(r.db.QueryRowContext(ctx, query, params...), &p); err != nil {
+		return entity.Project{}, err
+	}
+	return p, nil
+}
+
+func (r *ProjectRepo) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM projects WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *ProjectRepo) GetMember(ctx context.Context, projectID, userID int64) (entity.ProjectMember, error) {
+	var m entity.ProjectMember
+	err := r.db.QueryRowContext(ctx,
+		"SELECT project_id, user_id, role, added_at FROM project_members WHERE project_id = $1 AND user_id = $2",
+		projectID, userID,
+	).Scan(&m.ProjectID, &m.UserID, &m.Role, &m.AddedAt)
+	if err != nil {
+		return entity.ProjectMember{}, err
+	}
+	return m, nil
+}
+
+func (r *ProjectRepo) ListMembers(ctx context.Context, projectID int64) ([]entity.ProjectMember, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT project_id, user_id, role, added_at FROM project_members WHERE project_id = $1 ORDER BY added_at, user_id",
+		projectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []entity.ProjectMember
+	for rows.Next() {
+		var m entity.ProjectMember
+		if err := rows.Scan(&m.ProjectID, &m.UserID, &m.Role, &m.AddedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *ProjectRepo) AddMember(ctx context.Context, m *entity.ProjectMember) error {
+	err := r.db.QueryRowContext(ctx,
+		"INSERT INTO project_members (project_id, user_id, role) VALUES ($1, $2, $3) RETURNING added_at",
+		m.ProjectID, m.UserID, m.Role,
+	).Scan(&m.AddedAt)
+	switch {
+	case isUniqueViolation(err):
+		return ErrDuplicate
+	case isForeignKeyViolation(err):
+		return ErrUnknownUser
+	}
+	return err
+}
+
+func (r *ProjectRepo) RemoveMember(ctx context.Context, projectID, userID int64) error {
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM project_members WHERE project_id = $1 AND user_id = $2",
+		projectID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"time"
+)
+
+// ReminderWindow selects the open tasks whose due date lies in
+// (now + Until, now + Lead]. A nil Until leaves the window unbounded
+// towards the past, which is how overdue tasks are picked up.
+type ReminderWindow struct {
+	Threshold string
+	Lead      time.Duration
+	Until     *time.Duration
+}
+
+type ReminderRepo struct {
+	db *sql.DB
+}
+
+func NewReminderRepo(db *sql.DB) *ReminderRepo {
+	return &ReminderRepo{db: db}
+}
+
+// Claim records up to limit not yet sent reminders of window w and returns
+// them once that is committed. The transaction holds an advisory lock, so
+// when several replicas scan at once only one of them does the work; the
+// others get ok == false. Sending is left to the caller: a reminder is
+// never claimed twice, but one whose sending fails is not retried.
+func (r *ReminderRepo) Claim(ctx context.Context, w ReminderWindow, limit int) (claimed []entity.TaskReminder, ok bool, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock(hashtext('task_reminders'));`).Scan(&ok); err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		return nil, false, nil
+	}
+
+	query := `
+		INSERT INTO task_reminders (task_id, threshold, due_date)
+		SELECT t.id, $1, t.due_date
+		FROM tasks t
+		WHERE t.status <> 'done'
+		  AND t.deleted_at IS NULL
+		  AND t.due_date IS NOT NULL
+		  AND t.due_date <= now() + $2 * interval '1 second'
+		  AND ($3::float8 IS NULL OR t.due_date > now() + $3 * interval '1 second')
+		  AND NOT EXISTS (
+		      SELECT 1 FROM task_reminders r
+		      WHERE r.task_id = t.id AND r.threshold = $1 AND r.due_date = t.due_date
+		  )
+		ORDER BY t.due_date, t.id
+		LIMIT $4
+		ON CONFLICT DO NOTHING
+		RETURNING task_id, due_date, sent_at;
+	`
+
+	var until *float64
+	if w.Until != nil {
+		secs := w.Until.Seconds()
+		until = &secs
+	}
+
+	rows, err := tx.QueryContext(ctx, query, w.Threshold, w.Lead.Seconds(), until, limit)
+	if err != nil {
+		return nil, true, err
+	}
+	for rows.Next() {
+		rem := entity.TaskReminder{Threshold: w.Threshold}
+		if err := rows.Scan(&rem.TaskID, &rem.DueAt, &rem.SentAt); err != nil {
+			rows.Close()
+			return nil, true, err
+		}
+		claimed = append(claimed, rem)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, true, err
+	}
+
+	for i := range claimed {
+		rem := &claimed[i]
+		err := tx.QueryRowContext(ctx,
+			`SELECT user_id, assignee_id, title FROM tasks WHERE id = $1;`,
+			rem.TaskID,
+		).Scan(&rem.UserID, &rem.AssigneeID, &rem.Title)
+		if err != nil {
+			return nil, true, err
+		}
+		if rem.Watchers, err = taskRecipients(ctx, tx, rem.TaskID); err != nil {
+			return nil, true, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, true, err
+	}
+	return claimed, true, nil
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of the fields recorded in task_events.
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldStatus      = "status"
+	FieldPriority    = "priority"
+	FieldDueAt       = "due_at"
+	FieldParent      = "parent_id"
+	FieldProject     = "project_id"
+	FieldWorkflow    = "workflow_id"
+	FieldEstimate    = "estimate_minutes"
+	FieldStoryPoints = "story_points"
+	FieldLabels      = "labels"
+)
+
+func textValue(s string) *string { return &s }
+
+func timeValue(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	return textValue(t.UTC().Format(time.RFC3339Nano))
+}
+
+func idValue(id *int64) *string {
+	if id == nil {
+		return nil
+	}
+	return textValue(strconv.FormatInt(*id, 10))
+}
+
+func labelsValue(labels []entity.Label) *string {
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		names = append(names, l.Name)
+	}
+	return textValue(strings.Join(names, ","))
+}
+
+func taskFieldValue(t entity.Task, field string) *string {
+	switch field {
+	case FieldTitle:
+		return textValue(t.Title)
+	case FieldDescription:
+		return textValue(t.Description)
+	case FieldStatus:
+		return textValue(t.Status)
+	case FieldPriority:
+		return textValue(strconv.FormatInt(t.Priority, 10))
+	case FieldDueAt:
+		return timeValue(t.DueAt)
+	case FieldParent:
+		return idValue(t.ParentID)
+	case FieldProject:
+		return idValue(t.ProjectID)
+	case FieldWorkflow:
+		return idValue(t.WorkflowID)
+	case FieldEstimate:
+		return idValue(t.EstimateMinutes)
+	case FieldStoryPoints:
+		return idValue(t.StoryPoints)
+	case FieldLabels:
+		return labelsValue(t.Labels)
+	}
+	return nil
+}
+
+func sameValue(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// recordChanges writes one task_events row per listed field whose value
+// differs between before and after.
+func recordChanges(ctx context.Context, q dbtx, actor int64, before, after entity.Task, fields []string) error {
+	for _, f := range fields {
+		oldValue, newValue := taskFieldValue(before, f), taskFieldValue(after, f)
+		if sameValue(oldValue, newValue) {
+			continue
+		}
+		_, err := q.ExecContext(ctx, `
+			INSERT INTO task_events (task_id, actor_id, field, old_value, new_value)
+			VALUES ($1, $2, $3, $4, $5);
+		`, after.ID, actor, f, oldValue, newValue)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockTask locks the row of a task for the rest of the transaction and
+// returns its current state.
+func lockTask(ctx context.Context, q dbtx, id int64) (entity.Task, error) {
+	var locked int64
+	if err := q.QueryRowContext(ctx, `SELECT id FROM tasks WHERE id = $1 AND deleted_at IS NULL FOR UPDATE;`, id).Scan(&locked); err != nil {
+		return entity.Task{}, err
+	}
+	var task entity.Task
+	err := scanTask(q.QueryRowContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = $1;`, id), &task)
+	return task, err
+}
+
+func (r *TaskRepo) GetHistory(ctx context.Context, taskID, beforeID int64, limit int) ([]entity.TaskEvent, error) {
+	query := `
+		SELECT id, task_id, actor_id, field, old_value, new_value, created_at
+		FROM task_events
+		WHERE task_id = $1 AND ($2 = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3;
+	`
+
+	rows, err := r.q().QueryContext(ctx, query, taskID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []entity.TaskEvent
+	for rows.Next() {
+		var e entity.TaskEvent
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.ActorID, &e.Field, &e.OldValue, &e.NewValue, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
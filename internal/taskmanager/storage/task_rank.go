@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/rank"
+)
+
+var ErrBadNeighbour = errors.New("neighbour is not in the same column")
+
+// errRankTie means two neighbours share a rank, so nothing fits between
+// them until the column is rebalanced.
+var errRankTie = errors.New("rank tie")
+
+// TaskOrder picks the order GetByUserID returns tasks in.
+type TaskOrder int
+
+const (
+	// OrderNewest lists the newest tasks first.
+	OrderNewest TaskOrder = iota
+	// OrderRank groups tasks by status and follows the rank within each.
+	OrderRank
+)
+
+// Move places a task between two tasks of its status column: right after
+// afterID and right before beforeID. With only one of them set the task
+// lands next to that neighbour. Only the moved task gets a new rank, unless
+// the new key would grow past rank.MaxLen or the neighbours share a rank,
+// in which case the whole column is rebalanced first.
+func (r *TaskRepo) Move(ctx context.Context, uid, tid int64, afterID, beforeID *int64) (entity.Task, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	defer tx.Rollback()
+
+	task, err := lockTask(ctx, tx, tid)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	if task.UserID != uid {
+		return entity.Task{}, sql.ErrNoRows
+	}
+
+	key, err := moveRank(ctx, tx, task, afterID, beforeID)
+	if errors.Is(err, errRankTie) || (err == nil && len(key) > rank.MaxLen) {
+		if err := rebalanceRanks(ctx, tx, task.UserID, task.Status); err != nil {
+			return entity.Task{}, err
+		}
+		key, err = moveRank(ctx, tx, task, afterID, beforeID)
+	}
+	if err != nil {
+		return entity.Task{}, err
+	}
+
+	var out entity.Task
+	err = scanTask(tx.QueryRowContext(ctx, `
+		UPDATE tasks
+		SET rank = $1, updated_at = now(), version = version + 1
+		WHERE id = $2
+		RETURNING `+taskColumns+`;
+	`, key, tid), &out)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return entity.Task{}, err
+	}
+	return out, nil
+}
+
+// moveRank picks the key between the requested neighbours of task. Ties
+// between tasks of equal rank are broken by id, like in every listing.
+func moveRank(ctx context.Context, q dbtx, task entity.Task, afterID, beforeID *int64) (string, error) {
+	var lo, hi string
+	var err error
+	if afterID != nil {
+		if lo, err = neighbourRank(ctx, q, task, *afterID); err != nil {
+			return "", err
+		}
+	}
+	if beforeID != nil {
+		if hi, err = neighbourRank(ctx, q, task, *beforeID); err != nil {
+			return "", err
+		}
+	}
+
+	switch {
+	case afterID != nil && beforeID == nil:
+		err = q.QueryRowContext(ctx, `
+			SELECT coalesce((
+				SELECT rank FROM tasks
+				WHERE user_id = $1 AND status = $2 AND deleted_at IS NULL AND id <> $3
+				  AND (rank, id) > ($4, $5)
+				ORDER BY rank, id
+				LIMIT 1
+			), '');
+		`, task.UserID, task.Status, task.ID, lo, *afterID).Scan(&hi)
+	case beforeID != nil && afterID == nil:
+		err = q.QueryRowContext(ctx, `
+			SELECT coalesce((
+				SELECT rank FROM tasks
+				WHERE user_id = $1 AND status = $2 AND deleted_at IS NULL AND id <> $3
+				  AND (rank, id) < ($4, $5)
+				ORDER BY rank DESC, id DESC
+				LIMIT 1
+			), '');
+		`, task.UserID, task.Status, task.ID, hi, *beforeID).Scan(&lo)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case lo != "" && lo == hi:
+		return "", errRankTie
+	case lo != "" && hi != "" && lo > hi:
+		return "", ErrBadNeighbour
+	}
+	return rank.Between(lo, hi)
+}
+
+// neighbourRank returns the rank of a live task from the same column as
+// task.
+func neighbourRank(ctx context.Context, q dbtx, task entity.Task, id int64) (string, error) {
+	if id == task.ID {
+		return "", ErrBadNeighbour
+	}
+	var key string
+	err := q.QueryRowContext(ctx, `
+		SELECT rank FROM tasks
+		WHERE id = $1 AND user_id = $2 AND status = $3 AND deleted_at IS NULL;
+	`, id, task.UserID, task.Status).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrBadNeighbour
+	}
+	return key, err
+}
+
+// lastRank returns a key after every task in a status column but the
+// excluded one, rebalancing the column when the key gets too long.
+func lastRank(ctx context.Context, q dbtx, userID int64, status string, exclude int64) (string, error) {
+	query := `
+		SELECT coalesce(max(rank), '') FROM tasks
+		WHERE user_id = $1 AND status = $2 AND deleted_at IS NULL AND id <> $3;
+	`
+	var last string
+	if err := q.QueryRowContext(ctx, query, userID, status, exclude).Scan(&last); err != nil {
+		return "", err
+	}
+	if len(last) >= rank.MaxLen {
+		if err := rebalanceRanks(ctx, q, userID, status); err != nil {
+			return "", err
+		}
+		if err := q.QueryRowContext(ctx, query, userID, status, exclude).Scan(&last); err != nil {
+			return "", err
+		}
+	}
+	return rank.Between(last, "")
+}
+
+// rerankOnStatusChange moves a task that changed status to the end of its
+// new column. It runs inside the update that changed the status.
+func rerankOnStatusChange(ctx context.Context, q dbtx, before entity.Task, after *entity.Task) error {
+	if before.Status == after.Status {
+		return nil
+	}
+	key, err := lastRank(ctx, q, after.UserID, after.Status, after.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx, `UPDATE tasks SET rank = $1 WHERE id = $2;`, key, after.ID); err != nil {
+		return err
+	}
+	after.Rank = key
+	return nil
+}
+
+// rebalanceRanks gives the live tasks of a status column short, evenly
+// spaced keys in their current order. The keys are part of what a task
+// shows, so the versions are bumped as well.
+func rebalanceRanks(ctx context.Context, q dbtx, userID int64, status string) error {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id FROM tasks
+		WHERE user_id = $1 AND status = $2 AND deleted_at IS NULL
+		ORDER BY rank, id
+		FOR UPDATE;
+	`, userID, status)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, `
+		UPDATE tasks SET rank = k.rank, version = version + 1
+		FROM unnest($1::bigint[], $2::text[]) AS k(id, rank)
+		WHERE tasks.id = k.id;
+	`, ids, rank.Spread(len(ids)))
+	return err
+}
@@ -4,73 +4,444 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// TaskRepository stores tasks. Update, Patch and UpdateStatus record every
+// changed field in task_events; Update and Patch are owner operations and
+// record the task owner as the actor. Delete only moves a task to the
+// trash; every other read skips deleted rows. Writes bump the version of
+// each task whose representation they change, see Update. InTx runs fn
+// against a repository bound to a single transaction; calls nested inside
+// it become savepoints.
 type TaskRepository interface {
 	Create(ctx context.Context, task *entity.Task) error
 	GetByID(ctx context.Context, id int64) (entity.Task, error)
-	GetByUserID(ctx context.Context, userID int64) ([]entity.Task, error)
+	GetByUserID(ctx context.Context, userID int64, order TaskOrder) ([]entity.Task, error)
+	EachByUserID(ctx context.Context, userID int64, fn func(entity.Task) error) error
+	List(ctx context.Context, f TaskListFilter) ([]entity.Task, error)
+	Search(ctx context.Context, userID *int64, q string, limit, offset int) ([]entity.TaskSearchHit, error)
 	Update(ctx context.Context, task *entity.Task) (entity.Task, error)
-	Patch(ctx context.Context, uid, tid int64, title, desc, status *string, priority *int, dueAtProvided bool, dueAt *time.Time) (entity.Task, error)
-	Delete(ctx context.Context, id int64) error
+	Patch(ctx context.Context, uid, tid int64, p entity.TaskPatch) (entity.Task, error)
+	UpdateStatus(ctx context.Context, actor, id int64, status string, ifVersion *int64) error
+	Assign(ctx context.Context, id int64, assigneeID *int64) (entity.Task, error)
+	Delete(ctx context.Context, id int64, ifVersion *int64) error
+	Move(ctx context.Context, uid, tid int64, afterID, beforeID *int64) (entity.Task, error)
+	ListTrash(ctx context.Context, userID int64) ([]entity.Task, error)
+	Restore(ctx context.Context, userID, id int64) (entity.Task, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	GetChildren(ctx context.Context, parentID int64) ([]entity.Task, error)
+	GetSubtree(ctx context.Context, rootID int64) ([]entity.Task, error)
+	OrphanChildren(ctx context.Context, parentID int64, ifVersion *int64) error
+	AddDependency(ctx context.Context, taskID, blockedByID int64) error
+	RemoveDependency(ctx context.Context, taskID, blockedByID int64) error
+	GetBlockers(ctx context.Context, taskID int64) ([]entity.Task, error)
+	GetBlocked(ctx context.Context, taskID int64) ([]entity.Task, error)
+	GetSeries(ctx context.Context, id int64) (entity.TaskSeries, error)
+	StartSeries(ctx context.Context, taskID int64, s *entity.TaskSeries) error
+	UpdateSeries(ctx context.Context, s *entity.TaskSeries) error
+	DeleteSeries(ctx context.Context, id int64) error
+	GetSeriesTasks(ctx context.Context, seriesID int64) ([]entity.Task, error)
+	GetHistory(ctx context.Context, taskID, beforeID int64, limit int) ([]entity.TaskEvent, error)
+	GetWorkflow(ctx context.Context, id int64) (entity.Workflow, error)
+	ListEffort(ctx context.Context, userID int64, from, to *time.Time) ([]entity.TaskEffort, error)
+	InTx(ctx context.Context, fn func(TaskRepository) error) error
 }
 
+var (
+	ErrDependencyCycle  = errors.New("dependency cycle")
+	ErrDependencyExists = errors.New("dependency already exists")
+	ErrForeignLabel     = errors.New("label does not belong to task owner")
+	ErrUnknownUser      = errors.New("referenced user does not exist")
+	ErrNotProjectMember = errors.New("task owner is not a member of the project")
+	ErrVersionMismatch  = errors.New("version mismatch")
+)
+
+// tasksAssigneeFK is the name Postgres gave the assignee_id foreign key
+// (0010_task_assignee.sql). Tasks reference users, parents, projects and
+// workflows, so only a violation of this one means a bad assignee.
+const tasksAssigneeFK = "tasks_assignee_id_fkey"
+
+// Search wraps matches in its snippets with these control characters
+// instead of HTML, so the raw task text can be escaped before highlighting.
+const (
+	SnippetStart = "\x02"
+	SnippetStop  = "\x03"
+)
+
+var (
+	titleHeadline       = `StartSel="` + SnippetStart + `", StopSel="` + SnippetStop + `", HighlightAll=true`
+	descriptionHeadline = `StartSel="` + SnippetStart + `", StopSel="` + SnippetStop + `", MaxFragments=2`
+)
+
+// TaskListFilter describes a single page of a user's tasks.
+// AfterValue/AfterID carry the keyset position of the last row of the
+// previous page; AfterValue is the text form of the sort column.
+// When AssigneeID or ProjectID is set the page holds the tasks assigned
+// to that user or filed under that project instead of the ones owned by
+// UserID.
+type TaskListFilter struct {
+	UserID      int64
+	AssigneeID  *int64
+	ProjectID   *int64
+	Statuses    []string
+	MinPriority *int64
+	MaxPriority *int64
+	MinEstimate *int64
+	MaxEstimate *int64
+	MinPoints   *int64
+	MaxPoints   *int64
+	DueAfter    *time.Time
+	DueBefore   *time.Time
+	OverdueOnly bool
+	Labels      []string
+	AnyLabel    bool
+	SortBy      string
+	Desc        bool
+	AfterValue  *string
+	AfterID     int64
+	Limit       int
+}
+
+type taskSortColumn struct {
+	expr string
+	cast string
+}
+
+var taskSortColumns = map[string]taskSortColumn{
+	"created_at": {expr: "created_at", cast: "timestamptz"},
+	"updated_at": {expr: "updated_at", cast: "timestamptz"},
+	"due_at":     {expr: "COALESCE(due_date, 'infinity'::timestamptz)", cast: "timestamptz"},
+	"priority":   {expr: "priority", cast: "int"},
+	"title":      {expr: "title", cast: "text"},
+	// Tasks without an estimate sort after every estimated one.
+	"estimate_minutes": {expr: "COALESCE(estimate_minutes, 2147483647)", cast: "int"},
+	"story_points":     {expr: "COALESCE(story_points, 2147483647)", cast: "int"},
+	// Ranks only order tasks within one status column.
+	"rank": {expr: "rank", cast: "text"},
+}
+
+const labelsJSON = `coalesce(json_agg(json_build_object('id', l.id, 'user_id', l.user_id, 'name', l.name, 'color', l.color) ORDER BY l.name), '[]')::text`
+
+const taskColumns = `id, user_id, assignee_id, project_id, workflow_id, parent_id, title, description, status, due_date, priority, estimate_minutes, story_points,
+	(SELECT count(*) FROM tasks c WHERE c.parent_id = tasks.id AND c.deleted_at IS NULL),
+	(SELECT count(*) FROM tasks c WHERE c.parent_id = tasks.id AND c.deleted_at IS NULL AND c.status = 'done'),
+	(SELECT coalesce(string_agg(d.blocked_by_id::text, ',' ORDER BY d.blocked_by_id), '') FROM task_dependencies d JOIN tasks b ON b.id = d.blocked_by_id WHERE d.task_id = tasks.id AND b.deleted_at IS NULL),
+	(SELECT coalesce(string_agg(d.task_id::text, ',' ORDER BY d.task_id), '') FROM task_dependencies d JOIN tasks b ON b.id = d.task_id WHERE d.blocked_by_id = tasks.id AND b.deleted_at IS NULL),
+	(SELECT count(*) FROM task_dependencies d JOIN tasks b ON b.id = d.blocked_by_id WHERE d.task_id = tasks.id AND b.deleted_at IS NULL AND b.status <> 'done'),
+	(SELECT ` + labelsJSON + ` FROM task_labels tl JOIN labels l ON l.id = tl.label_id WHERE tl.task_id = tasks.id),
+	series_id, occurrence_at,
+	coalesce((SELECT s.rrule FROM task_series s WHERE s.id = tasks.series_id), ''),
+	(SELECT coalesce(sum(extract(epoch FROM coalesce(e.ended_at, now()) - e.started_at)), 0)::bigint FROM time_entries e WHERE e.task_id = tasks.id),
+	rank, created_at, updated_at, deleted_at, version`
+
+// int64List scans a comma separated list of ids produced by string_agg.
+type int64List []int64
+
+func (l *int64List) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("int64List: unsupported type %T", src)
+	}
+	*l = nil
+	if s == "" {
+		return nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, id)
+	}
+	return nil
+}
+
+// labelList scans the json_agg of a task's labels.
+type labelList []entity.Label
+
+func (l *labelList) Scan(src any) error {
+	var raw []byte
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return fmt.Errorf("labelList: unsupported type %T", src)
+	}
+
+	var items []struct {
+		ID     int64  `json:"id"`
+		UserID int64  `json:"user_id"`
+		Name   string `json:"name"`
+		Color  string `json:"color"`
+	}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return err
+	}
+	*l = make([]entity.Label, 0, len(items))
+	for _, it := range items {
+		*l = append(*l, entity.Label{ID: it.ID, UserID: it.UserID, Name: it.Name, Color: it.Color})
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(row rowScanner, task *entity.Task, extra ...any) error {
+	dest := []any{
+		&task.ID,
+		&task.UserID,
+		&task.AssigneeID,
+		&task.ProjectID,
+		&task.WorkflowID,
+		&task.ParentID,
+		&task.Title,
+		&task.Description,
+		&task.Status,
+		&task.DueAt,
+		&task.Priority,
+		&task.EstimateMinutes,
+		&task.StoryPoints,
+		&task.SubtasksTotal,
+		&task.SubtasksDone,
+		(*int64List)(&task.BlockedBy),
+		(*int64List)(&task.Blocking),
+		&task.OpenBlockers,
+		(*labelList)(&task.Labels),
+		&task.SeriesID,
+		&task.OccurrenceAt,
+		&task.RRule,
+		&task.TimeSpent,
+		&task.Rank,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+		&task.DeletedAt,
+		&task.Version,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+func scanTasks(rows *sql.Rows) ([]entity.Task, error) {
+	var tasks []entity.Task
+	for rows.Next() {
+		var task entity.Task
+		if err := scanTask(rows, &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// TaskRepo talks to the database directly, or through tx when it was handed
+// out by InTx. sp numbers the savepoints taken inside tx.
 type TaskRepo struct {
 	db *sql.DB
+	tx *sql.Tx
+	sp int
 }
 
 func NewTaskRepo(db *sql.DB) *TaskRepo {
 	return &TaskRepo{db: db}
 }
 
+// txn is a unit of work that can be committed or rolled back: a real
+// transaction or a savepoint inside one.
+type txn interface {
+	dbtx
+	Commit() error
+	Rollback() error
+}
+
+type savepoint struct {
+	*sql.Tx
+	ctx  context.Context
+	name string
+	done bool
+}
+
+func (s *savepoint) Commit() error {
+	s.done = true
+	_, err := s.Tx.ExecContext(s.ctx, "RELEASE SAVEPOINT "+s.name)
+	return err
+}
+
+func (s *savepoint) Rollback() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	_, err := s.Tx.ExecContext(s.ctx, "ROLLBACK TO SAVEPOINT "+s.name)
+	return err
+}
+
+func (r *TaskRepo) q() dbtx {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.db
+}
+
+// begin starts a transaction, or a savepoint when r is already inside one,
+// so that methods keep their all-or-nothing behaviour inside a batch.
+func (r *TaskRepo) begin(ctx context.Context) (txn, error) {
+	if r.tx == nil {
+		return r.db.BeginTx(ctx, nil)
+	}
+	r.sp++
+	name := "sp_" + strconv.Itoa(r.sp)
+	if _, err := r.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, err
+	}
+	return &savepoint{Tx: r.tx, ctx: ctx, name: name}, nil
+}
+
+func (r *TaskRepo) InTx(ctx context.Context, fn func(TaskRepository) error) error {
+	if r.tx != nil {
+		sp, err := r.begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer sp.Rollback()
+
+		if err := fn(r); err != nil {
+			return err
+		}
+		return sp.Commit()
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&TaskRepo{db: r.db, tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Create adds a task at the end of its status column and bumps the
+// version of its parent, whose progress changes.
 func (r *TaskRepo) Create(ctx context.Context, task *entity.Task) error {
 	query := `
-		INSERT INTO tasks (user_id, title, description, status, due_date, priority)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO tasks (user_id, assignee_id, project_id, workflow_id, parent_id, title, description, status, due_date, priority, estimate_minutes, story_points, series_id, occurrence_at, rank)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 		RETURNING id, created_at, updated_at;
 	`
 
-	row := r.db.QueryRowContext(ctx, query,
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if task.ProjectID != nil {
+		if err := checkProjectMember(ctx, tx, *task.ProjectID, task.UserID); err != nil {
+			return err
+		}
+	}
+	if task.SeriesID == nil && task.RRule != "" && task.DueAt != nil {
+		var seriesID int64
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO task_series (user_id, rrule, dtstart) VALUES ($1, $2, $3) RETURNING id;`,
+			task.UserID, task.RRule, *task.DueAt,
+		).Scan(&seriesID)
+		if err != nil {
+			return err
+		}
+		task.SeriesID = &seriesID
+		task.OccurrenceAt = task.DueAt
+	}
+	if task.Rank, err = lastRank(ctx, tx, task.UserID, task.Status, 0); err != nil {
+		return err
+	}
+
+	row := tx.QueryRowContext(ctx, query,
 		task.UserID,
+		task.AssigneeID,
+		task.ProjectID,
+		task.WorkflowID,
+		task.ParentID,
 		task.Title,
 		task.Description,
 		task.Status,
 		task.DueAt,
 		task.Priority,
+		task.EstimateMinutes,
+		task.StoryPoints,
+		task.SeriesID,
+		task.OccurrenceAt,
+		task.Rank,
 	)
 
-	err := row.Scan(&task.ID, &task.CreatedAt, &task.UpdatedAt)
+	err = row.Scan(&task.ID, &task.CreatedAt, &task.UpdatedAt)
+	if violatesForeignKey(err, tasksAssigneeFK) {
+		return ErrUnknownUser
+	}
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if len(task.Labels) > 0 {
+		if err := attachLabels(ctx, tx, task.ID, labelIDs(task.Labels)); err != nil {
+			return err
+		}
+		var labels labelList
+		err := tx.QueryRowContext(ctx,
+			`SELECT `+labelsJSON+` FROM labels l WHERE l.id = ANY($1);`,
+			labelIDs(task.Labels),
+		).Scan(&labels)
+		if err != nil {
+			return err
+		}
+		task.Labels = labels
+	}
+	if task.ParentID != nil {
+		if err := touchTasks(ctx, tx, *task.ParentID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 func (r *TaskRepo) GetByID(ctx context.Context, id int64) (entity.Task, error) {
 	query := `
-		SELECT id, user_id, title, description, status, due_date, priority, created_at, updated_at
+		SELECT ` + taskColumns + `
 		FROM tasks
-		WHERE id = $1;
+		WHERE id = $1 AND deleted_at IS NULL;
 	`
 
 	var task entity.Task
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&task.ID,
-		&task.UserID,
-		&task.Title,
-		&task.Description,
-		&task.Status,
-		&task.DueAt,
-		&task.Priority,
-		&task.CreatedAt,
-		&task.UpdatedAt,
-	)
+	err := scanTask(r.q().QueryRowContext(ctx, query, id), &task)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return entity.Task{}, err
@@ -79,205 +450,1068 @@ func (r *TaskRepo) GetByID(ctx context.Context, id int64) (entity.Task, error) {
 	return task, err
 }
 
-func (r *TaskRepo) GetByUserID(ctx context.Context, userID int64) ([]entity.Task, error) {
+func (r *TaskRepo) GetByUserID(ctx context.Context, userID int64, order TaskOrder) ([]entity.Task, error) {
+	orderBy := "created_at DESC"
+	if order == OrderRank {
+		orderBy = "status, rank, id"
+	}
 	query := `
-		SELECT id, user_id, title, description, status, due_date, priority, created_at, updated_at
+		SELECT ` + taskColumns + `
 		FROM tasks
-		WHERE user_id = $1
-		ORDER BY created_at DESC;
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY ` + orderBy + `;
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.q().QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var tasks []entity.Task
+	return scanTasks(rows)
+}
+
+// EachByUserID streams a user's tasks in id order without loading them all;
+// an error from fn stops the scan and is returned as is.
+func (r *TaskRepo) EachByUserID(ctx context.Context, userID int64, fn func(entity.Task) error) error {
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY id;
+	`
+
+	rows, err := r.q().QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var task entity.Task
-		err := rows.Scan(
-			&task.ID,
-			&task.UserID,
-			&task.Title,
-			&task.Description,
-			&task.Status,
-			&task.DueAt,
-			&task.Priority,
-			&task.CreatedAt,
-			&task.UpdatedAt,
+		if err := scanTask(rows, &task); err != nil {
+			return err
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *TaskRepo) List(ctx context.Context, f TaskListFilter) ([]entity.Task, error) {
+	sortBy := f.SortBy
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
+	col, ok := taskSortColumns[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort column %q", sortBy)
+	}
+
+	conds := []string{"user_id = $1"}
+	args := []interface{}{f.UserID}
+	switch {
+	case f.AssigneeID != nil:
+		conds = []string{"assignee_id = $1"}
+		args = []interface{}{*f.AssigneeID}
+	case f.ProjectID != nil:
+		conds = []string{"project_id = $1"}
+		args = []interface{}{*f.ProjectID}
+	}
+	conds = append(conds, "deleted_at IS NULL")
+	idx := 2
+
+	if len(f.Statuses) > 0 {
+		placeholders := make([]string, 0, len(f.Statuses))
+		for _, st := range f.Statuses {
+			placeholders = append(placeholders, fmt.Sprintf("$%d", idx))
+			args = append(args, st)
+			idx++
+		}
+		conds = append(conds, "status IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if f.MinPriority != nil {
+		conds = append(conds, fmt.Sprintf("priority >= $%d", idx))
+		args = append(args, *f.MinPriority)
+		idx++
+	}
+	if f.MaxPriority != nil {
+		conds = append(conds, fmt.Sprintf("priority <= $%d", idx))
+		args = append(args, *f.MaxPriority)
+		idx++
+	}
+	if f.MinEstimate != nil {
+		conds = append(conds, fmt.Sprintf("estimate_minutes >= $%d", idx))
+		args = append(args, *f.MinEstimate)
+		idx++
+	}
+	if f.MaxEstimate != nil {
+		conds = append(conds, fmt.Sprintf("estimate_minutes <= $%d", idx))
+		args = append(args, *f.MaxEstimate)
+		idx++
+	}
+	if f.MinPoints != nil {
+		conds = append(conds, fmt.Sprintf("story_points >= $%d", idx))
+		args = append(args, *f.MinPoints)
+		idx++
+	}
+	if f.MaxPoints != nil {
+		conds = append(conds, fmt.Sprintf("story_points <= $%d", idx))
+		args = append(args, *f.MaxPoints)
+		idx++
+	}
+	if f.DueAfter != nil {
+		conds = append(conds, fmt.Sprintf("due_date >= $%d", idx))
+		args = append(args, *f.DueAfter)
+		idx++
+	}
+	if f.DueBefore != nil {
+		conds = append(conds, fmt.Sprintf("due_date < $%d", idx))
+		args = append(args, *f.DueBefore)
+		idx++
+	}
+	if f.OverdueOnly {
+		conds = append(conds, "status != 'done' AND due_date < now()")
+	}
+	if len(f.Labels) > 0 {
+		labelQuery := fmt.Sprintf(
+			"SELECT count(DISTINCT l.name) FROM task_labels tl JOIN labels l ON l.id = tl.label_id WHERE tl.task_id = tasks.id AND l.name = ANY($%d)",
+			idx,
 		)
-		if err != nil {
-			return nil, err
+		if f.AnyLabel {
+			conds = append(conds, fmt.Sprintf("(%s) > 0", labelQuery))
+		} else {
+			conds = append(conds, fmt.Sprintf("(%s) = %d", labelQuery, len(f.Labels)))
 		}
-		tasks = append(tasks, task)
+		args = append(args, f.Labels)
+		idx++
 	}
 
-	if err := rows.Err(); err != nil {
+	dir, cmp := "ASC", ">"
+	if f.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	if f.AfterValue != nil {
+		conds = append(conds, fmt.Sprintf("(%s, id) %s ($%d::%s, $%d)", col.expr, cmp, idx, col.cast, idx+1))
+		args = append(args, *f.AfterValue, f.AfterID)
+		idx += 2
+	}
+
+	query := "SELECT " + taskColumns + " FROM tasks WHERE " + strings.Join(conds, " AND ") +
+		fmt.Sprintf(" ORDER BY %s %s, id %s", col.expr, dir, dir)
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", idx)
+		args = append(args, f.Limit)
+	}
+
+	rows, err := r.q().QueryContext(ctx, query, args...)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return tasks, nil
+	return scanTasks(rows)
 }
 
-func (r *TaskRepo) UpdateStatus(ctx context.Context, id int64, status string) error {
+func (r *TaskRepo) Search(ctx context.Context, userID *int64, q string, limit, offset int) ([]entity.TaskSearchHit, error) {
+	scope := ""
+	args := []interface{}{q, limit, offset, titleHeadline, descriptionHeadline}
+	if userID != nil {
+		scope = "user_id = $6 AND "
+		args = append(args, *userID)
+	}
+
 	query := `
-		UPDATE tasks
-		SET status = $1, updated_at = now()
-		WHERE id = $2;
+		WITH q AS (SELECT websearch_to_tsquery('simple', $1) AS tsq)
+		SELECT ` + taskColumns + `,
+		       ts_rank(search_vector, q.tsq) + greatest(similarity(title, $1), similarity(coalesce(description, ''), $1)) AS rank,
+		       ts_headline('simple', title, q.tsq, $4),
+		       ts_headline('simple', coalesce(description, ''), q.tsq, $5)
+		FROM tasks, q
+		WHERE deleted_at IS NULL AND ` + scope + `(search_vector @@ q.tsq OR title % $1 OR description % $1)
+		ORDER BY rank DESC, id DESC
+		LIMIT $2 OFFSET $3;
 	`
-	res, err := r.db.ExecContext(ctx, query, status, id)
+
+	rows, err := r.q().QueryContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	n, err := res.RowsAffected()
-	if err != nil {
-		return err
+	defer rows.Close()
+
+	var hits []entity.TaskSearchHit
+	for rows.Next() {
+		var h entity.TaskSearchHit
+		if err := scanTask(rows, &h.Task, &h.Rank, &h.TitleSnippet, &h.DescriptionSnippet); err != nil {
+			return nil, err
+		}
+		hits = append(hits, h)
 	}
-	if n == 0 {
-		return sql.ErrNoRows
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	return nil
+	return hits, nil
 }
 
-func (r *TaskRepo) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM tasks WHERE id = $1;`
+// UpdateStatus moves a task to the end of the column of its new status. A
+// non-nil ifVersion must match the stored version, or it fails with
+// ErrVersionMismatch.
+func (r *TaskRepo) UpdateStatus(ctx context.Context, actor, id int64, status string, ifVersion *int64) error {
+	query := `
+		UPDATE tasks
+		SET status = $1, updated_at = now(), version = version + 1
+		WHERE id = $2 AND ($3::bigint IS NULL OR version = $3);
+	`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	tx, err := r.begin(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	before, err := lockTask(ctx, tx, id)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	res, err := tx.ExecContext(ctx, query, status, id, ifVersion)
+	if err != nil {
+		return err
 	}
-	return nil
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrVersionMismatch
+	}
+
+	after := before
+	after.Status = status
+	if err := recordChanges(ctx, tx, actor, before, after, []string{FieldStatus}); err != nil {
+		return err
+	}
+	if err := stopTimersOnDone(ctx, tx, before, after); err != nil {
+		return err
+	}
+	if err := rerankOnStatusChange(ctx, tx, before, &after); err != nil {
+		return err
+	}
+	if err := touchNeighbours(ctx, tx, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (r *TaskRepo) Update(ctx context.Context, t *entity.Task) (entity.Task, error) {
+// Assign sets or clears the assignee of a task.
+func (r *TaskRepo) Assign(ctx context.Context, id int64, assigneeID *int64) (entity.Task, error) {
 	query := `
 		UPDATE tasks
-		SET title = $1,
-		    description = $2,
-		    status = $3,
-		    due_date = $4,
-		    priority = $5,
-		    updated_at = now()
-		WHERE id = $6 AND user_id = $7
-		RETURNING id, user_id, title, description, status, due_date, priority, created_at, updated_at;
+		SET assignee_id = $1, updated_at = now(), version = version + 1
+		WHERE id = $2 AND deleted_at IS NULL
+		RETURNING ` + taskColumns + `;
 	`
 
 	var out entity.Task
-	err := r.db.QueryRowContext(ctx, query,
-		t.Title,
-		t.Description,
-		t.Status,
-		t.DueAt,
-		t.Priority,
-		t.ID,
-		t.UserID,
-	).Scan(
-		&out.ID,
-		&out.UserID,
-		&out.Title,
-		&out.Description,
-		&out.Status,
-		&out.DueAt,
-		&out.Priority,
-		&out.CreatedAt,
-		&out.UpdatedAt,
-	)
+	err := scanTask(r.q().QueryRowContext(ctx, query, assigneeID, id), &out)
+	if violatesForeignKey(err, tasksAssigneeFK) {
+		return entity.Task{}, ErrUnknownUser
+	}
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return entity.Task{}, sql.ErrNoRows
-		}
 		return entity.Task{}, err
 	}
 	return out, nil
 }
 
-func (r *TaskRepo) Patch(
-	ctx context.Context,
-	uid, tid int64,
-	title, desc, status *string,
-	priority *int,
-	dueAtProvided bool,
-	dueAt *time.Time,
-) (entity.Task, error) {
+// Delete moves a task and its live subtree to the trash. All of them get
+// the same deleted_at so that Restore can bring them back together. A
+// non-nil ifVersion must match the stored version of the task, or it fails
+// with ErrVersionMismatch.
+func (r *TaskRepo) Delete(ctx context.Context, id int64, ifVersion *int64) error {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id, 0 AS depth FROM tasks WHERE id = $1 AND deleted_at IS NULL AND ($2::bigint IS NULL OR version = $2)
+			UNION ALL
+			SELECT t.id, s.depth + 1
+			FROM tasks t
+			JOIN subtree s ON t.parent_id = s.id
+			WHERE s.depth < 64 AND t.deleted_at IS NULL
+		)
+		UPDATE tasks
+		SET deleted_at = now(), version = version + 1
+		WHERE id IN (SELECT id FROM subtree)
+		RETURNING id;
+	`
 
-	if title == nil && desc == nil && status == nil && priority == nil && !dueAtProvided {
-		return entity.Task{}, errors.New("nothing to update")
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	query := "UPDATE tasks SET "
-	args := []interface{}{}
-	idx := 1
-
-	if title != nil {
-		query += fmt.Sprintf("title = $%d", idx)
-		args = append(args, *title)
-		idx++
-	}
-	if desc != nil {
-		if len(args) > 0 {
-			query += ", "
-		}
-		query += fmt.Sprintf("description = $%d", idx)
-		args = append(args, *desc)
-		idx++
+	ids, err := queryIDs(ctx, tx, query, id, ifVersion)
+	if err != nil {
+		return err
 	}
-	if status != nil {
-		if len(args) > 0 {
-			query += ", "
+	if len(ids) == 0 {
+		var exists bool
+		err := tx.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM tasks WHERE id = $1 AND deleted_at IS NULL);`, id,
+		).Scan(&exists)
+		if err != nil {
+			return err
 		}
-		query += fmt.Sprintf("status = $%d", idx)
-		args = append(args, *status)
-		idx++
-	}
-	if priority != nil {
-		if len(args) > 0 {
-			query += ", "
+		if exists && ifVersion != nil {
+			return ErrVersionMismatch
 		}
-		query += fmt.Sprintf("priority = $%d", idx)
-		args = append(args, *priority)
-		idx++
+		return sql.ErrNoRows
 	}
-	if dueAtProvided {
-		if len(args) > 0 {
-			query += ", "
-		}
-		query += fmt.Sprintf("due_date = $%d", idx)
-		args = append(args, dueAt)
-		idx++
+	if err := touchLinked(ctx, tx, ids); err != nil {
+		return err
 	}
+	return tx.Commit()
+}
+
+func (r *TaskRepo) ListTrash(ctx context.Context, userID int64) ([]entity.Task, error) {
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC;
+	`
+
+	rows, err := r.q().QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTasks(rows)
+}
 
-	query += fmt.Sprintf(", updated_at = now() WHERE id = $%d AND user_id = $%d ", idx, idx+1)
-	args = append(args, tid, uid)
+// Restore takes a task out of the trash together with the subtasks that
+// were deleted along with it. If the parent is still in the trash the task
+// comes back as a top-level one. Tasks of a deleted user are restored with
+// the user, not one by one.
+func (r *TaskRepo) Restore(ctx context.Context, userID, id int64) (entity.Task, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT t.deleted_at
+		FROM tasks t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.id = $1 AND t.user_id = $2 AND t.deleted_at IS NOT NULL AND u.deleted_at IS NULL
+		FOR UPDATE OF t;
+	`, id, userID).Scan(&deletedAt)
+	if err != nil {
+		return entity.Task{}, err
+	}
+
+	ids, err := queryIDs(ctx, tx, `
+		WITH RECURSIVE subtree AS (
+			SELECT id, 0 AS depth FROM tasks WHERE id = $1
+			UNION ALL
+			SELECT t.id, s.depth + 1
+			FROM tasks t
+			JOIN subtree s ON t.parent_id = s.id
+			WHERE s.depth < 64 AND t.deleted_at = $2
+		)
+		UPDATE tasks
+		SET deleted_at = NULL, version = version + 1
+		WHERE id IN (SELECT id FROM subtree)
+		RETURNING id;
+	`, id, deletedAt)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	if err := touchLinked(ctx, tx, ids); err != nil {
+		return entity.Task{}, err
+	}
 
-	query += "RETURNING id, user_id, title, description, status, due_date, priority, created_at, updated_at;"
+	_, err = tx.ExecContext(ctx, `
+		UPDATE tasks t
+		SET parent_id = NULL, updated_at = now(), version = t.version + 1
+		FROM tasks p
+		WHERE t.id = $1 AND p.id = t.parent_id AND p.deleted_at IS NOT NULL;
+	`, id)
+	if err != nil {
+		return entity.Task{}, err
+	}
 
 	var out entity.Task
-	err := r.db.QueryRowContext(ctx, query, args...).Scan(
-		&out.ID,
-		&out.UserID,
-		&out.Title,
-		&out.Description,
-		&out.Status,
-		&out.DueAt,
-		&out.Priority,
-		&out.CreatedAt,
-		&out.UpdatedAt,
-	)
+	err = scanTask(tx.QueryRowContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = $1;`, id), &out)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return entity.Task{}, err
+	}
+	return out, nil
+}
+
+// PurgeDeleted permanently removes tasks that were moved to the trash
+// before the given time.
+func (r *TaskRepo) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.q().ExecContext(ctx, `DELETE FROM tasks WHERE deleted_at < $1;`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Update replaces the fields of a task. A non-zero t.Version must match the
+// stored version, or it fails with ErrVersionMismatch.
+//
+// Like every write, it bumps the version of the task and of the tasks whose
+// derived fields it changes: the parent's progress, the open blockers of
+// the tasks it blocks. Writes to labels, dependencies, recurrence, time
+// entries and ranks do the same, so the version, and the ETag built from
+// it, always changes with the representation.
+func (r *TaskRepo) Update(ctx context.Context, t *entity.Task) (entity.Task, error) {
+	query := `
+		UPDATE tasks
+		SET title = $1,
+		    description = $2,
+		    status = $3,
+		    due_date = $4,
+		    priority = $5,
+		    updated_at = now(),
+		    version = version + 1
+		WHERE id = $6 AND user_id = $7 AND ($8::bigint = 0 OR version = $8)
+		RETURNING ` + taskColumns + `;
+	`
+
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	defer tx.Rollback()
+
+	before, err := lockTask(ctx, tx, t.ID)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	if before.UserID != t.UserID {
+		return entity.Task{}, sql.ErrNoRows
+	}
+
+	var out entity.Task
+	err = scanTask(tx.QueryRowContext(ctx, query,
+		t.Title,
+		t.Description,
+		t.Status,
+		t.DueAt,
+		t.Priority,
+		t.ID,
+		t.UserID,
+		t.Version,
+	), &out)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return entity.Task{}, sql.ErrNoRows
+			return entity.Task{}, ErrVersionMismatch
 		}
 		return entity.Task{}, err
 	}
+
+	fields := []string{FieldTitle, FieldDescription, FieldStatus, FieldPriority, FieldDueAt}
+	if err := recordChanges(ctx, tx, t.UserID, before, out, fields); err != nil {
+		return entity.Task{}, err
+	}
+	if err := stopTimersOnDone(ctx, tx, before, out); err != nil {
+		return entity.Task{}, err
+	}
+	if err := rerankOnStatusChange(ctx, tx, before, &out); err != nil {
+		return entity.Task{}, err
+	}
+	if err := touchNeighbours(ctx, tx, before, out); err != nil {
+		return entity.Task{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return entity.Task{}, err
+	}
 	return out, nil
 }
+
+// Patch applies p to a task of uid. A non-nil p.IfVersion must match the
+// stored version, or it fails with ErrVersionMismatch.
+func (r *TaskRepo) Patch(ctx context.Context, uid, tid int64, p entity.TaskPatch) (entity.Task, error) {
+	if p.IsEmpty() {
+		return entity.Task{}, errors.New("nothing to update")
+	}
+
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	defer tx.Rollback()
+
+	before, err := lockTask(ctx, tx, tid)
+	if err != nil {
+		return entity.Task{}, err
+	}
+	if before.UserID != uid {
+		return entity.Task{}, sql.ErrNoRows
+	}
+
+	if len(p.AddLabels) > 0 {
+		if err := attachLabels(ctx, tx, tid, p.AddLabels); err != nil {
+			return entity.Task{}, err
+		}
+	}
+	if len(p.RemoveLabels) > 0 {
+		if err := detachLabels(ctx, tx, tid, p.RemoveLabels); err != nil {
+			return entity.Task{}, err
+		}
+	}
+	if p.ProjectSet && p.ProjectID != nil {
+		if err := checkProjectMember(ctx, tx, *p.ProjectID, uid); err != nil {
+			return entity.Task{}, err
+		}
+	}
+
+	query := "UPDATE tasks SET updated_at = now(), version = version + 1"
+	args := []interface{}{}
+	idx := 1
+	var fields []string
+
+	set := func(field, column string, value interface{}) {
+		query += fmt.Sprintf(", %s = $%d", column, idx)
+		args = append(args, value)
+		idx++
+		fields = append(fields, field)
+	}
+
+	if p.Title != nil {
+		set(FieldTitle, "title", *p.Title)
+	}
+	if p.Description != nil {
+		set(FieldDescription, "description", *p.Description)
+	}
+	if p.Status != nil {
+		set(FieldStatus, "status", *p.Status)
+	}
+	if p.Priority != nil {
+		set(FieldPriority, "priority", *p.Priority)
+	}
+	if p.DueAtSet {
+		set(FieldDueAt, "due_date", p.DueAt)
+	}
+	if p.ParentSet {
+		set(FieldParent, "parent_id", p.ParentID)
+	}
+	if p.ProjectSet {
+		set(FieldProject, "project_id", p.ProjectID)
+	}
+	if p.WorkflowSet {
+		set(FieldWorkflow, "workflow_id", p.WorkflowID)
+	}
+	if p.EstimateSet {
+		set(FieldEstimate, "estimate_minutes", p.Estimate)
+	}
+	if p.PointsSet {
+		set(FieldStoryPoints, "story_points", p.Points)
+	}
+	if len(p.AddLabels) > 0 || len(p.RemoveLabels) > 0 {
+		fields = append(fields, FieldLabels)
+	}
+
+	query += fmt.Sprintf(" WHERE id = $%d AND user_id = $%d AND ($%d::bigint IS NULL OR version = $%d) ", idx, idx+1, idx+2, idx+2)
+	args = append(args, tid, uid, p.IfVersion)
+
+	query += "RETURNING " + taskColumns + ";"
+
+	var out entity.Task
+	err = scanTask(tx.QueryRowContext(ctx, query, args...), &out)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.Task{}, ErrVersionMismatch
+		}
+		return entity.Task{}, err
+	}
+	if err := recordChanges(ctx, tx, uid, before, out, fields); err != nil {
+		return entity.Task{}, err
+	}
+	if err := stopTimersOnDone(ctx, tx, before, out); err != nil {
+		return entity.Task{}, err
+	}
+	if err := rerankOnStatusChange(ctx, tx, before, &out); err != nil {
+		return entity.Task{}, err
+	}
+	if err := touchNeighbours(ctx, tx, before, out); err != nil {
+		return entity.Task{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return entity.Task{}, err
+	}
+	return out, nil
+}
+
+// ListEffort returns the done tasks of a user that carry an estimate or
+// story points, completed within [from, to) when the bounds are set. The
+// completion time is the last move to done in the history, or the last
+// update for tasks that predate it.
+func (r *TaskRepo) ListEffort(ctx context.Context, userID int64, from, to *time.Time) ([]entity.TaskEffort, error) {
+	query := `
+		SELECT id, estimate_minutes, story_points, spent, completed_at
+		FROM (
+			SELECT t.id, t.estimate_minutes, t.story_points,
+			       (SELECT coalesce(sum(extract(epoch FROM coalesce(e.ended_at, now()) - e.started_at)), 0)::bigint
+			        FROM time_entries e WHERE e.task_id = t.id) AS spent,
+			       coalesce((SELECT max(ev.created_at) FROM task_events ev
+			                 WHERE ev.task_id = t.id AND ev.field = 'status' AND ev.new_value = 'done'), t.updated_at) AS completed_at
+			FROM tasks t
+			WHERE t.user_id = $1 AND t.deleted_at IS NULL AND t.status = 'done'
+			  AND (t.estimate_minutes IS NOT NULL OR t.story_points IS NOT NULL)
+		) done
+		WHERE ($2::timestamptz IS NULL OR completed_at >= $2) AND ($3::timestamptz IS NULL OR completed_at < $3)
+		ORDER BY completed_at, id;
+	`
+
+	rows, err := r.q().QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []entity.TaskEffort
+	for rows.Next() {
+		var e entity.TaskEffort
+		if err := rows.Scan(&e.TaskID, &e.EstimateMinutes, &e.StoryPoints, &e.SpentSeconds, &e.CompletedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+func (r *TaskRepo) GetChildren(ctx context.Context, parentID int64) ([]entity.Task, error) {
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE parent_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at, id;
+	`
+
+	rows, err := r.q().QueryContext(ctx, query, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTasks(rows)
+}
+
+func (r *TaskRepo) GetSubtree(ctx context.Context, rootID int64) ([]entity.Task, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id, 0 AS depth FROM tasks WHERE id = $1 AND deleted_at IS NULL
+			UNION ALL
+			SELECT t.id, s.depth + 1
+			FROM tasks t
+			JOIN subtree s ON t.parent_id = s.id
+			WHERE s.depth < 64 AND t.deleted_at IS NULL
+		)
+		SELECT ` + taskColumns + `
+		FROM tasks
+		JOIN subtree USING (id)
+		WHERE depth > 0
+		ORDER BY depth, id;
+	`
+
+	rows, err := r.q().QueryContext(ctx, query, rootID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTasks(rows)
+}
+
+// OrphanChildren detaches the live direct children of a task that is about
+// to be deleted. The task itself is only locked, not bumped: a non-nil
+// ifVersion is checked against it before anything changes, and the caller
+// deletes it next, in the same transaction.
+func (r *TaskRepo) OrphanChildren(ctx context.Context, parentID int64, ifVersion *int64) error {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	parent, err := lockTask(ctx, tx, parentID)
+	if err != nil {
+		return err
+	}
+	if ifVersion != nil && parent.Version != *ifVersion {
+		return ErrVersionMismatch
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE tasks SET parent_id = NULL, updated_at = now(), version = version + 1
+		WHERE parent_id = $1 AND deleted_at IS NULL;
+	`, parentID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AddDependency records that taskID cannot start until blockedByID is done.
+// The cycle check and the insert run under one transaction-scoped advisory
+// lock so concurrent inserts cannot close a loop between them.
+func (r *TaskRepo) AddDependency(ctx context.Context, taskID, blockedByID int64) error {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('task_dependencies'));`); err != nil {
+		return err
+	}
+
+	var cycle bool
+	err = tx.QueryRowContext(ctx, `
+		WITH RECURSIVE blockers AS (
+			SELECT blocked_by_id AS id FROM task_dependencies WHERE task_id = $1
+			UNION
+			SELECT d.blocked_by_id
+			FROM task_dependencies d
+			JOIN blockers b ON d.task_id = b.id
+		)
+		SELECT $1 = $2 OR EXISTS (SELECT 1 FROM blockers WHERE id = $2);
+	`, blockedByID, taskID).Scan(&cycle)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return ErrDependencyCycle
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO task_dependencies (task_id, blocked_by_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;
+	`, taskID, blockedByID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrDependencyExists
+	}
+	if err := touchTasks(ctx, tx, taskID, blockedByID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *TaskRepo) RemoveDependency(ctx context.Context, taskID, blockedByID int64) error {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`DELETE FROM task_dependencies WHERE task_id = $1 AND blocked_by_id = $2;`,
+		taskID, blockedByID,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	if err := touchTasks(ctx, tx, taskID, blockedByID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *TaskRepo) GetBlockers(ctx context.Context, taskID int64) ([]entity.Task, error) {
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE id IN (SELECT blocked_by_id FROM task_dependencies WHERE task_id = $1) AND deleted_at IS NULL
+		ORDER BY id;
+	`
+
+	rows, err := r.q().QueryContext(ctx, query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTasks(rows)
+}
+
+func (r *TaskRepo) GetBlocked(ctx context.Context, taskID int64) ([]entity.Task, error) {
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE id IN (SELECT task_id FROM task_dependencies WHERE blocked_by_id = $1) AND deleted_at IS NULL
+		ORDER BY id;
+	`
+
+	rows, err := r.q().QueryContext(ctx, query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTasks(rows)
+}
+
+func (r *TaskRepo) GetSeries(ctx context.Context, id int64) (entity.TaskSeries, error) {
+	var s entity.TaskSeries
+	err := r.q().QueryRowContext(ctx,
+		`SELECT id, user_id, rrule, dtstart, created_at, updated_at FROM task_series WHERE id = $1;`,
+		id,
+	).Scan(&s.ID, &s.UserID, &s.RRule, &s.DTStart, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return entity.TaskSeries{}, err
+	}
+	return s, nil
+}
+
+// StartSeries creates a series and makes taskID its first instance,
+// scheduled at the series start.
+func (r *TaskRepo) StartSeries(ctx context.Context, taskID int64, s *entity.TaskSeries) error {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO task_series (user_id, rrule, dtstart)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at;
+	`, s.UserID, s.RRule, s.DTStart).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE tasks SET series_id = $1, occurrence_at = $2, updated_at = now(), version = version + 1 WHERE id = $3;`,
+		s.ID, s.DTStart, taskID,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+func (r *TaskRepo) UpdateSeries(ctx context.Context, s *entity.TaskSeries) error {
+	err := r.q().QueryRowContext(ctx, `
+		WITH s AS (
+			UPDATE task_series
+			SET rrule = $1, dtstart = $2, updated_at = now()
+			WHERE id = $3
+			RETURNING id, updated_at
+		), t AS (
+			UPDATE tasks SET version = version + 1 WHERE series_id IN (SELECT id FROM s)
+		)
+		SELECT updated_at FROM s;
+	`, s.RRule, s.DTStart, s.ID).Scan(&s.UpdatedAt)
+	return err
+}
+
+// DeleteSeries stops a recurrence. Existing instances stay as ordinary
+// tasks; ON DELETE SET NULL detaches them.
+func (r *TaskRepo) DeleteSeries(ctx context.Context, id int64) error {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE tasks SET version = version + 1 WHERE series_id = $1;`, id); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM task_series WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+func (r *TaskRepo) GetSeriesTasks(ctx context.Context, seriesID int64) ([]entity.Task, error) {
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE series_id = $1 AND deleted_at IS NULL
+		ORDER BY occurrence_at, id;
+	`
+
+	rows, err := r.q().QueryContext(ctx, query, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTasks(rows)
+}
+
+// dbtx is satisfied by both *sql.DB and *sql.Tx.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// attachLabels links labels to a task. Every label must belong to the
+// owner of the task, otherwise nothing is attached and ErrForeignLabel is
+// returned.
+func attachLabels(ctx context.Context, q dbtx, taskID int64, labelIDs []int64) error {
+	var owned int
+	err := q.QueryRowContext(ctx, `
+		SELECT count(DISTINCT l.id)
+		FROM labels l
+		JOIN tasks t ON t.user_id = l.user_id
+		WHERE t.id = $1 AND l.id = ANY($2);
+	`, taskID, labelIDs).Scan(&owned)
+	if err != nil {
+		return err
+	}
+	if owned != countDistinct(labelIDs) {
+		return ErrForeignLabel
+	}
+
+	_, err = q.ExecContext(ctx, `
+		INSERT INTO task_labels (task_id, label_id)
+		SELECT $1, unnest($2::bigint[])
+		ON CONFLICT DO NOTHING;
+	`, taskID, labelIDs)
+	return err
+}
+
+func checkProjectMember(ctx context.Context, q dbtx, projectID, userID int64) error {
+	var member bool
+	err := q.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM project_members WHERE project_id = $1 AND user_id = $2);`,
+		projectID, userID,
+	).Scan(&member)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrNotProjectMember
+	}
+	return nil
+}
+
+func detachLabels(ctx context.Context, q dbtx, taskID int64, labelIDs []int64) error {
+	_, err := q.ExecContext(ctx,
+		`DELETE FROM task_labels WHERE task_id = $1 AND label_id = ANY($2);`,
+		taskID, labelIDs,
+	)
+	return err
+}
+
+// touchTasks bumps the version of tasks whose derived columns were changed
+// by a write to some other row, so that their ETags change as well.
+func touchTasks(ctx context.Context, q dbtx, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := q.ExecContext(ctx, `UPDATE tasks SET version = version + 1 WHERE id = ANY($1);`, ids)
+	return err
+}
+
+// touchNeighbours bumps the tasks that a write from before to after shows
+// through: the old and new parent, whose progress counts done subtasks,
+// and the tasks after blocks, whose open_blockers count unfinished ones.
+func touchNeighbours(ctx context.Context, q dbtx, before, after entity.Task) error {
+	var ids []int64
+	if (before.Status == "done") != (after.Status == "done") {
+		ids = append(ids, after.Blocking...)
+		if after.ParentID != nil {
+			ids = append(ids, *after.ParentID)
+		}
+	}
+	if !sameValue(idValue(before.ParentID), idValue(after.ParentID)) {
+		for _, p := range []*int64{before.ParentID, after.ParentID} {
+			if p != nil {
+				ids = append(ids, *p)
+			}
+		}
+	}
+	return touchTasks(ctx, q, ids...)
+}
+
+// touchLinked bumps the parents and the dependencies on either side of
+// tasks that have just entered or left the trash, other than those tasks
+// themselves.
+func touchLinked(ctx context.Context, q dbtx, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := q.ExecContext(ctx, `
+		UPDATE tasks SET version = version + 1
+		WHERE NOT (id = ANY($1)) AND id IN (
+			SELECT parent_id FROM tasks WHERE id = ANY($1)
+			UNION SELECT task_id FROM task_dependencies WHERE blocked_by_id = ANY($1)
+			UNION SELECT blocked_by_id FROM task_dependencies WHERE task_id = ANY($1)
+		);
+	`, ids)
+	return err
+}
+
+func queryIDs(ctx context.Context, q dbtx, query string, args ...any) ([]int64, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func countDistinct(ids []int64) int {
+	seen := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		seen[id] = struct{}{}
+	}
+	return len(seen)
+}
+
+func labelIDs(labels []entity.Label) []int64 {
+	ids := make([]int64, 0, len(labels))
+	for _, l := range labels {
+		ids = append(ids, l.ID)
+	}
+	return ids
+}
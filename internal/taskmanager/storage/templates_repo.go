@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+)
+
+type TemplateRepo struct {
+	db *sql.DB
+}
+
+func NewTemplateRepo(db *sql.DB) *TemplateRepo {
+	return &TemplateRepo{db: db}
+}
+
+func (r *TemplateRepo) Create(ctx context.Context, t *entity.TaskTemplate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO task_templates (user_id, name, title, description, priority, due_offset)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at;
+	`, t.UserID, t.Name, t.Title, t.Description, t.Priority, t.DueOffset).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if err := insertTemplateSubtasks(ctx, tx, t); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *TemplateRepo) GetByID(ctx context.Context, id int64) (entity.TaskTemplate, error) {
+	return getTemplate(ctx, r.db, id)
+}
+
+func (r *TemplateRepo) GetByUserID(ctx context.Context, userID int64) ([]entity.TaskTemplate, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id FROM task_templates WHERE user_id = $1 ORDER BY name",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	list := make([]entity.TaskTemplate, 0, len(ids))
+	for _, id := range ids {
+		t, err := getTemplate(ctx, r.db, id)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	return list, nil
+}
+
+// Update replaces a template together with its subtasks.
+func (r *TemplateRepo) Update(ctx context.Context, t *entity.TaskTemplate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE task_templates
+		SET name = $1, title = $2, description = $3, priority = $4, due_offset = $5, updated_at = now()
+		WHERE id = $6
+		RETURNING created_at, updated_at;
+	`, t.Name, t.Title, t.Description, t.Priority, t.DueOffset, t.ID).Scan(&t.CreatedAt, &t.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM task_template_subtasks WHERE template_id = $1", t.ID); err != nil {
+		return err
+	}
+	if err := insertTemplateSubtasks(ctx, tx, t); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *TemplateRepo) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM task_templates WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func insertTemplateSubtasks(ctx context.Context, q dbtx, t *entity.TaskTemplate) error {
+	for i, st := range t.Subtasks {
+		_, err := q.ExecContext(ctx, `
+			INSERT INTO task_template_subtasks (template_id, position, title, description, priority, due_offset)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, t.ID, i, st.Title, st.Description, st.Priority, st.DueOffset)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getTemplate(ctx context.Context, q dbtx, id int64) (entity.TaskTemplate, error) {
+	var t entity.TaskTemplate
+	err := q.QueryRowContext(ctx, `
+		SELECT id, user_id, name, title, description, priority, due_offset, created_at, updated_at
+		FROM task_templates
+		WHERE id = $1
+	`, id).Scan(&t.ID, &t.UserID, &t.Name, &t.Title, &t.Description, &t.Priority, &t.DueOffset, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return entity.TaskTemplate{}, err
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT title, description, priority, due_offset
+		FROM task_template_subtasks
+		WHERE template_id = $1
+		ORDER BY position
+	`, id)
+	if err != nil {
+		return entity.TaskTemplate{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var st entity.TemplateSubtask
+		if err := rows.Scan(&st.Title, &st.Description, &st.Priority, &st.DueOffset); err != nil {
+			return entity.TaskTemplate{}, err
+		}
+		t.Subtasks = append(t.Subtasks, st)
+	}
+	return t, rows.Err()
+}
@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"time"
+)
+
+// ErrTimerRunning is returned when a user starts a second timer.
+var ErrTimerRunning = errors.New("timer already running")
+
+type TimeEntryRepo struct {
+	db *sql.DB
+}
+
+func NewTimeEntryRepo(db *sql.DB) *TimeEntryRepo {
+	return &TimeEntryRepo{db: db}
+}
+
+const timeEntryColumns = "id, task_id, user_id, started_at, ended_at, note, created_at, updated_at"
+
+// touchEntryTask is appended to a data-modifying CTE named e that returns
+// task_id: it bumps the version of the task, whose time_spent has changed.
+const touchEntryTask = `, t AS (
+	UPDATE tasks SET version = version + 1 WHERE id IN (SELECT task_id FROM e)
+)`
+
+func scanTimeEntry(row rowScanner, e *entity.TimeEntry) error {
+	return row.Scan(&e.ID, &e.TaskID, &e.UserID, &e.StartedAt, &e.EndedAt, &e.Note, &e.CreatedAt, &e.UpdatedAt)
+}
+
+func scanTimeEntries(rows *sql.Rows) ([]entity.TimeEntry, error) {
+	var list []entity.TimeEntry
+	for rows.Next() {
+		var e entity.TimeEntry
+		if err := scanTimeEntry(rows, &e); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// Create stores a manual entry, or starts a timer when EndedAt is nil. The
+// partial unique index on running entries turns a second timer into
+// ErrTimerRunning.
+func (r *TimeEntryRepo) Create(ctx context.Context, e *entity.TimeEntry) error {
+	row := r.db.QueryRowContext(ctx, `
+		WITH e AS (
+			INSERT INTO time_entries (task_id, user_id, started_at, ended_at, note)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING `+timeEntryColumns+`
+		)`+touchEntryTask+`
+		SELECT `+timeEntryColumns+` FROM e;
+	`, e.TaskID, e.UserID, e.StartedAt, e.EndedAt, e.Note)
+	err := scanTimeEntry(row, e)
+	if isUniqueViolation(err) {
+		return ErrTimerRunning
+	}
+	return err
+}
+
+func (r *TimeEntryRepo) GetByID(ctx context.Context, id int64) (entity.TimeEntry, error) {
+	var e entity.TimeEntry
+	err := scanTimeEntry(r.db.QueryRowContext(ctx, "SELECT "+timeEntryColumns+" FROM time_entries WHERE id = $1", id), &e)
+	return e, err
+}
+
+// Running returns the running timer of a user, or sql.ErrNoRows.
+func (r *TimeEntryRepo) Running(ctx context.Context, userID int64) (entity.TimeEntry, error) {
+	var e entity.TimeEntry
+	err := scanTimeEntry(r.db.QueryRowContext(ctx, "SELECT "+timeEntryColumns+" FROM time_entries WHERE user_id = $1 AND ended_at IS NULL", userID), &e)
+	return e, err
+}
+
+// Stop ends the running timer of a user, or returns sql.ErrNoRows.
+func (r *TimeEntryRepo) Stop(ctx context.Context, userID int64) (entity.TimeEntry, error) {
+	var e entity.TimeEntry
+	err := scanTimeEntry(r.db.QueryRowContext(ctx, `
+		WITH e AS (
+			UPDATE time_entries
+			SET ended_at = greatest(now(), started_at), updated_at = now()
+			WHERE user_id = $1 AND ended_at IS NULL
+			RETURNING `+timeEntryColumns+`
+		)`+touchEntryTask+`
+		SELECT `+timeEntryColumns+` FROM e;
+	`, userID), &e)
+	return e, err
+}
+
+func (r *TimeEntryRepo) ListByTask(ctx context.Context, taskID int64) ([]entity.TimeEntry, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+timeEntryColumns+" FROM time_entries WHERE task_id = $1 ORDER BY started_at, id", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanTimeEntries(rows)
+}
+
+// Update changes a finished entry; running timers are left alone.
+func (r *TimeEntryRepo) Update(ctx context.Context, e *entity.TimeEntry) error {
+	row := r.db.QueryRowContext(ctx, `
+		WITH e AS (
+			UPDATE time_entries
+			SET started_at = $1, ended_at = $2, note = $3, updated_at = now()
+			WHERE id = $4 AND ended_at IS NOT NULL
+			RETURNING `+timeEntryColumns+`
+		)`+touchEntryTask+`
+		SELECT `+timeEntryColumns+` FROM e;
+	`, e.StartedAt, e.EndedAt, e.Note, e.ID)
+	return scanTimeEntry(row, e)
+}
+
+func (r *TimeEntryRepo) Delete(ctx context.Context, id int64) error {
+	var n int64
+	err := r.db.QueryRowContext(ctx, `
+		WITH e AS (
+			DELETE FROM time_entries WHERE id = $1 RETURNING task_id
+		)`+touchEntryTask+`
+		SELECT count(*) FROM e;
+	`, id).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Report sums the time a user tracked in [from, to) by day and task. Days
+// are calendar days in the IANA zone tz, so an entry that crosses midnight
+// is split between two days; running timers count up to now.
+func (r *TimeEntryRepo) Report(ctx context.Context, userID int64, from, to time.Time, tz string) ([]entity.TimeReportRow, error) {
+	query := `
+		WITH e AS (
+			SELECT task_id,
+			       greatest(started_at, $2) AS s,
+			       least(coalesce(ended_at, now()), $3) AS f
+			FROM time_entries
+			WHERE user_id = $1 AND started_at < $3 AND coalesce(ended_at, now()) > $2
+		), d AS (
+			SELECT e.task_id,
+			       day,
+			       greatest(e.s, day AT TIME ZONE $4) AS s,
+			       least(e.f, (day + interval '1 day') AT TIME ZONE $4) AS f
+			FROM e,
+			     generate_series(date_trunc('day', e.s AT TIME ZONE $4), date_trunc('day', e.f AT TIME ZONE $4), interval '1 day') AS day
+		)
+		SELECT d.day::date, d.task_id, t.title, sum(extract(epoch FROM d.f - d.s))::bigint
+		FROM d
+		JOIN tasks t ON t.id = d.task_id
+		WHERE d.f > d.s
+		GROUP BY d.day, d.task_id, t.title
+		ORDER BY d.day, d.task_id;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, from, to, tz)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []entity.TimeReportRow
+	for rows.Next() {
+		var row entity.TimeReportRow
+		if err := rows.Scan(&row.Day, &row.TaskID, &row.Title, &row.Seconds); err != nil {
+			return nil, err
+		}
+		list = append(list, row)
+	}
+	return list, rows.Err()
+}
+
+// stopTimersOnDone stops the timers running on a task that has just been
+// moved to done. It runs inside the status update, so both commit or
+// neither does.
+func stopTimersOnDone(ctx context.Context, q dbtx, before, after entity.Task) error {
+	if before.Status == "done" || after.Status != "done" {
+		return nil
+	}
+	_, err := q.ExecContext(ctx, `
+		UPDATE time_entries
+		SET ended_at = greatest(now(), started_at), updated_at = now()
+		WHERE task_id = $1 AND ended_at IS NULL;
+	`, after.ID)
+	return err
+}
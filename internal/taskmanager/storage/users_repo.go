@@ -1,3 +1,4 @@
+//go:generate mockgen -source=users_repo.go -destination=../mocks/mock_user_repo.go -package=mocks
 package storage
 
 import (
@@ -6,15 +7,19 @@ import (
 	"errors"
 	"fmt"
 	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"time"
 )
 
 type UserRepository interface {
 	Create(ctx context.Context, user *entity.User) error
 	GetAll(ctx context.Context) ([]entity.User, error)
 	GetByID(ctx context.Context, id int64) (entity.User, error)
-	Update(ctx context.Context, id int64, name, email string) (entity.User, error)
-	Patch(ctx context.Context, id int64, name, email *string) (entity.User, error)
-	Delete(ctx context.Context, id int64) (entity.User, error)
+	Update(ctx context.Context, id int64, name, email string, ifVersion *int64) (entity.User, error)
+	Patch(ctx context.Context, id int64, name, email *string, ifVersion *int64) (entity.User, error)
+	Delete(ctx context.Context, id int64, ifVersion *int64) error
+	Restore(ctx context.Context, id int64) (entity.User, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	GetByEmail(ctx context.Context, email string) (entity.User, error)
 }
 
 type UserRepo struct {
@@ -27,15 +32,15 @@ func NewUserRepo(db *sql.DB) *UserRepo {
 
 func (r *UserRepo) Create(ctx context.Context, user *entity.User) error {
 	row := r.db.QueryRowContext(ctx,
-		"INSERT INTO users (username, email) VALUES ($1, $2) RETURNING id, created_at, updated_at",
+		"INSERT INTO users (username, email) VALUES ($1, $2) RETURNING id, created_at, updated_at, version",
 		user.Username, user.Email,
 	)
-	return row.Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	return row.Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.Version)
 }
 
 func (r *UserRepo) GetAll(ctx context.Context) ([]entity.User, error) {
 	rows, err := r.db.QueryContext(ctx,
-		"SELECT id, username, email, created_at, updated_at FROM users")
+		"SELECT id, username, email, created_at, updated_at, version FROM users WHERE deleted_at IS NULL")
 	if err != nil {
 		return nil, err
 	}
@@ -44,7 +49,7 @@ func (r *UserRepo) GetAll(ctx context.Context) ([]entity.User, error) {
 	var users []entity.User
 	for rows.Next() {
 		var user entity.User
-		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -58,12 +63,12 @@ func (r *UserRepo) GetAll(ctx context.Context) ([]entity.User, error) {
 
 func (r *UserRepo) GetByID(ctx context.Context, id int64) (entity.User, error) {
 	row := r.db.QueryRowContext(ctx,
-		"SELECT id, username, email, created_at, updated_at FROM users WHERE id = $1",
+		"SELECT id, username, email, created_at, updated_at, version FROM users WHERE id = $1 AND deleted_at IS NULL",
 		id,
 	)
 
 	var user entity.User
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.Version)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return entity.User{}, sql.ErrNoRows
@@ -73,23 +78,43 @@ func (r *UserRepo) GetByID(ctx context.Context, id int64) (entity.User, error) {
 	return user, nil
 }
 
-func (r *UserRepo) Update(ctx context.Context, id int64, name, email string) (entity.User, error) {
+func (r *UserRepo) Update(ctx context.Context, id int64, name, email string, ifVersion *int64) (entity.User, error) {
 	row := r.db.QueryRowContext(ctx,
-		"UPDATE users SET username = $1, email = $2, updated_at = now() WHERE id = $3 RETURNING id, username, email, created_at, updated_at",
-		name, email, id,
+		"UPDATE users SET username = $1, email = $2, updated_at = now(), version = version + 1 WHERE id = $3 AND deleted_at IS NULL AND ($4::bigint IS NULL OR version = $4) RETURNING id, username, email, created_at, updated_at, version",
+		name, email, id, ifVersion,
 	)
 	var user entity.User
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.Version)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return entity.User{}, sql.ErrNoRows
+			return entity.User{}, r.missing(ctx, id, ifVersion)
 		}
 		return entity.User{}, err
 	}
 	return user, nil
 }
 
-func (r *UserRepo) Patch(ctx context.Context, id int64, name, email *string) (entity.User, error) {
+// missing explains why a conditional write matched no row: the user is gone
+// or, when a version was required, it has changed since.
+func (r *UserRepo) missing(ctx context.Context, id int64, ifVersion *int64) error {
+	if ifVersion == nil {
+		return sql.ErrNoRows
+	}
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)",
+		id,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return sql.ErrNoRows
+}
+
+func (r *UserRepo) Patch(ctx context.Context, id int64, name, email *string, ifVersion *int64) (entity.User, error) {
 	if name == nil && email == nil {
 		return entity.User{}, errors.New("nothing to update")
 	}
@@ -113,42 +138,112 @@ func (r *UserRepo) Patch(ctx context.Context, id int64, name, email *string) (en
 		idx++
 	}
 
-	query += fmt.Sprintf(", updated_at = now() WHERE id = $%d RETURNING id, username, email, created_at, updated_at", idx)
-	params = append(params, id)
+	query += fmt.Sprintf(", updated_at = now(), version = version + 1 WHERE id = $%d AND deleted_at IS NULL AND ($%d::bigint IS NULL OR version = $%d) RETURNING id, username, email, created_at, updated_at, version", idx, idx+1, idx+1)
+	params = append(params, id, ifVersion)
 
 	row := r.db.QueryRowContext(ctx, query, params...)
 
 	var user entity.User
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.Version)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.User{}, r.missing(ctx, id, ifVersion)
+		}
 		return entity.User{}, err
 	}
 	return user, nil
 }
 
-func (r *UserRepo) Delete(ctx context.Context, id int64) error {
-	res, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+// Delete moves a user to the trash together with all of their live tasks,
+// stamped with the same deleted_at so that Restore brings back exactly them.
+func (r *UserRepo) Delete(ctx context.Context, id int64, ifVersion *int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	n, err := res.RowsAffected()
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx,
+		"UPDATE users SET deleted_at = now(), version = version + 1 WHERE id = $1 AND deleted_at IS NULL AND ($2::bigint IS NULL OR version = $2) RETURNING deleted_at",
+		id, ifVersion,
+	).Scan(&deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r.missing(ctx, id, ifVersion)
+	}
 	if err != nil {
 		return err
 	}
-	if n == 0 {
-		return sql.ErrNoRows
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE tasks SET deleted_at = $1, version = version + 1 WHERE user_id = $2 AND deleted_at IS NULL",
+		deletedAt, id,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *UserRepo) Restore(ctx context.Context, id int64) (entity.User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return entity.User{}, err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx,
+		"SELECT deleted_at FROM users WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE",
+		id,
+	).Scan(&deletedAt)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	var user entity.User
+	err = tx.QueryRowContext(ctx,
+		"UPDATE users SET deleted_at = NULL, version = version + 1 WHERE id = $1 RETURNING id, username, email, created_at, updated_at, version",
+		id,
+	).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.Version)
+	if isUniqueViolation(err) {
+		return entity.User{}, ErrDuplicate
+	}
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE tasks SET deleted_at = NULL, version = version + 1 WHERE user_id = $1 AND deleted_at = $2",
+		id, deletedAt,
+	)
+	if err != nil {
+		return entity.User{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return entity.User{}, err
+	}
+	return user, nil
+}
+
+// PurgeDeleted permanently removes users that were moved to the trash before
+// the given time; their tasks go with them through ON DELETE CASCADE.
+func (r *UserRepo) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE deleted_at < $1", before)
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	return res.RowsAffected()
 }
 
 func (r *UserRepo) GetByEmail(ctx context.Context, email string) (entity.User, error) {
 	row := r.db.QueryRowContext(ctx,
-		"SELECT id, username, email, created_at, updated_at FROM users WHERE email = $1",
+		"SELECT id, username, email, created_at, updated_at, version FROM users WHERE email = $1 AND deleted_at IS NULL",
 		email,
 	)
 
 	var user entity.User
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.Version)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return entity.User{}, sql.ErrNoRows
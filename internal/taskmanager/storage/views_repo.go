@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+)
+
+type ViewRepo struct {
+	db *sql.DB
+}
+
+func NewViewRepo(db *sql.DB) *ViewRepo {
+	return &ViewRepo{db: db}
+}
+
+const viewColumns = `
+	v.id, v.user_id, v.name, v.filter, v.filter_version,
+	(SELECT coalesce(string_agg(s.user_id::text, ',' ORDER BY s.user_id), '') FROM view_shares s WHERE s.view_id = v.id),
+	v.created_at, v.updated_at`
+
+func scanView(row rowScanner, v *entity.SavedView) error {
+	var shared int64List
+	err := row.Scan(&v.ID, &v.UserID, &v.Name, &v.FilterJSON, &v.FilterVersion, &shared, &v.CreatedAt, &v.UpdatedAt)
+	v.SharedWith = shared
+	return err
+}
+
+func (r *ViewRepo) Create(ctx context.Context, v *entity.SavedView) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO saved_views (user_id, name, filter, filter_version)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at;
+	`, v.UserID, v.Name, v.FilterJSON, v.FilterVersion).Scan(&v.ID, &v.CreatedAt, &v.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *ViewRepo) GetByID(ctx context.Context, id int64) (entity.SavedView, error) {
+	var v entity.SavedView
+	err := scanView(r.db.QueryRowContext(ctx, `SELECT `+viewColumns+` FROM saved_views v WHERE v.id = $1;`, id), &v)
+	if err != nil {
+		return entity.SavedView{}, err
+	}
+	return v, nil
+}
+
+// GetVisible lists the views of a user together with the views shared
+// with them.
+func (r *ViewRepo) GetVisible(ctx context.Context, userID int64) ([]entity.SavedView, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+viewColumns+`
+		FROM saved_views v
+		WHERE v.user_id = $1
+		   OR EXISTS (SELECT 1 FROM view_shares s WHERE s.view_id = v.id AND s.user_id = $1)
+		ORDER BY v.name, v.id;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []entity.SavedView
+	for rows.Next() {
+		var v entity.SavedView
+		if err := scanView(rows, &v); err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, rows.Err()
+}
+
+func (r *ViewRepo) Update(ctx context.Context, v *entity.SavedView) error {
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE saved_views
+		SET name = $1, filter = $2, filter_version = $3, updated_at = now()
+		WHERE id = $4
+		RETURNING created_at, updated_at;
+	`, v.Name, v.FilterJSON, v.FilterVersion, v.ID).Scan(&v.CreatedAt, &v.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *ViewRepo) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM saved_views WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *ViewRepo) Share(ctx context.Context, viewID, userID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO view_shares (view_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;
+	`, viewID, userID)
+	if isForeignKeyViolation(err) {
+		return ErrUnknownUser
+	}
+	return err
+}
+
+// Unshare takes the view away from the user, and stops it being their
+// default view. sql.ErrNoRows means it was not shared with them.
+func (r *ViewRepo) Unshare(ctx context.Context, viewID, userID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM view_shares WHERE view_id = $1 AND user_id = $2", viewID, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM default_views WHERE user_id = $1 AND view_id = $2", userID, viewID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetDefault returns the id of the user's default view, or sql.ErrNoRows
+// when they have none.
+func (r *ViewRepo) GetDefault(ctx context.Context, userID int64) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, "SELECT view_id FROM default_views WHERE user_id = $1", userID).Scan(&id)
+	return id, err
+}
+
+func (r *ViewRepo) SetDefault(ctx context.Context, userID, viewID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO default_views (user_id, view_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET view_id = EXCLUDED.view_id;
+	`, userID, viewID)
+	if isForeignKeyViolation(err) {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// ClearDefault removes the user's default view; sql.ErrNoRows means there
+// was none.
+func (r *ViewRepo) ClearDefault(ctx context.Context, userID int64) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM default_views WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+)
+
+type WatcherRepo struct {
+	db *sql.DB
+}
+
+func NewWatcherRepo(db *sql.DB) *WatcherRepo {
+	return &WatcherRepo{db: db}
+}
+
+// watchersQuery lists the watchers of task $1: its owner and assignee, and
+// the users with a watchers row who can still see the task through its
+// project. Deleted users are left out.
+const watchersQuery = `
+	SELECT s.user_id, bool_or(s.implicit), coalesce(bool_or(w.muted), false)
+	FROM (
+		SELECT t.user_id, true AS implicit FROM tasks t WHERE t.id = $1
+		UNION
+		SELECT t.assignee_id, true FROM tasks t WHERE t.id = $1 AND t.assignee_id IS NOT NULL
+		UNION
+		SELECT w.user_id, false
+		FROM watchers w
+		JOIN tasks t ON t.id = w.task_id
+		JOIN project_members m ON m.project_id = t.project_id AND m.user_id = w.user_id
+		WHERE w.task_id = $1
+	) s
+	JOIN users u ON u.id = s.user_id AND u.deleted_at IS NULL
+	LEFT JOIN watchers w ON w.task_id = $1 AND w.user_id = s.user_id
+	GROUP BY s.user_id
+	ORDER BY s.user_id;
+`
+
+func listWatchers(ctx context.Context, q dbtx, taskID int64) ([]entity.Watcher, error) {
+	rows, err := q.QueryContext(ctx, watchersQuery, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []entity.Watcher
+	for rows.Next() {
+		w := entity.Watcher{TaskID: taskID}
+		if err := rows.Scan(&w.UserID, &w.Implicit, &w.Muted); err != nil {
+			return nil, err
+		}
+		list = append(list, w)
+	}
+	return list, rows.Err()
+}
+
+// taskRecipients returns the ids of the watchers of a task who have not
+// muted it.
+func taskRecipients(ctx context.Context, q dbtx, taskID int64) ([]int64, error) {
+	list, err := listWatchers(ctx, q, taskID)
+	if err != nil {
+		return nil, err
+	}
+	ids := []int64{}
+	for _, w := range list {
+		if !w.Muted {
+			ids = append(ids, w.UserID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *WatcherRepo) List(ctx context.Context, taskID int64) ([]entity.Watcher, error) {
+	return listWatchers(ctx, r.db, taskID)
+}
+
+func (r *WatcherRepo) Recipients(ctx context.Context, taskID int64) ([]int64, error) {
+	return taskRecipients(ctx, r.db, taskID)
+}
+
+// Set adds a watchers row for the user or updates its muted flag.
+func (r *WatcherRepo) Set(ctx context.Context, taskID, userID int64, muted bool) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO watchers (task_id, user_id, muted)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (task_id, user_id) DO UPDATE SET muted = EXCLUDED.muted;
+	`, taskID, userID, muted)
+	if isForeignKeyViolation(err) {
+		return ErrUnknownUser
+	}
+	return err
+}
+
+// Delete removes the user's watchers row; sql.ErrNoRows means there was
+// none.
+func (r *WatcherRepo) Delete(ctx context.Context, taskID, userID int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM watchers WHERE task_id = $1 AND user_id = $2;`, taskID, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+	"strings"
+	"time"
+)
+
+type WebhookRepo struct {
+	db *sql.DB
+}
+
+func NewWebhookRepo(db *sql.DB) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+// DueDelivery is a delivery claimed for sending, with the address and the
+// secret of its webhook.
+type DueDelivery struct {
+	entity.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+const webhookColumns = `id, user_id, url, secret, array_to_string(events, ','), active, created_at, updated_at`
+
+func scanWebhook(row rowScanner, w *entity.Webhook) error {
+	var list string
+	if err := row.Scan(&w.ID, &w.UserID, &w.URL, &w.Secret, &list, &w.Active, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return err
+	}
+	w.Events = nil
+	if list != "" {
+		w.Events = strings.Split(list, ",")
+	}
+	return nil
+}
+
+const deliveryColumns = `
+	d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.response_code, d.last_error, d.redelivery_of, d.created_at, d.updated_at`
+
+func scanDelivery(row rowScanner, d *entity.WebhookDelivery, extra ...any) error {
+	var code sql.NullInt64
+	var next sql.NullTime
+	var of sql.NullInt64
+	dest := []any{
+		&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+		&next, &code, &d.LastError, &of, &d.CreatedAt, &d.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	d.NextAttemptAt, d.ResponseCode, d.RedeliveryOf = nil, nil, nil
+	if next.Valid {
+		d.NextAttemptAt = &next.Time
+	}
+	if code.Valid {
+		c := int(code.Int64)
+		d.ResponseCode = &c
+	}
+	if of.Valid {
+		d.RedeliveryOf = &of.Int64
+	}
+	return nil
+}
+
+func (r *WebhookRepo) Create(ctx context.Context, w *entity.Webhook) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO webhooks (user_id, url, secret, events, active)
+		VALUES ($1, $2, $3, $4::text[], $5)
+		RETURNING id, created_at, updated_at;
+	`, w.UserID, w.URL, w.Secret, w.Events, w.Active).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+}
+
+func (r *WebhookRepo) GetByID(ctx context.Context, id int64) (entity.Webhook, error) {
+	var w entity.Webhook
+	err := scanWebhook(r.db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1;`, id), &w)
+	if err != nil {
+		return entity.Webhook{}, err
+	}
+	return w, nil
+}
+
+func (r *WebhookRepo) GetByUserID(ctx context.Context, userID int64) ([]entity.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE user_id = $1 ORDER BY id;`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []entity.Webhook
+	for rows.Next() {
+		var w entity.Webhook
+		if err := scanWebhook(rows, &w); err != nil {
+			return nil, err
+		}
+		list = append(list, w)
+	}
+	return list, rows.Err()
+}
+
+func (r *WebhookRepo) Update(ctx context.Context, w *entity.Webhook) error {
+	return r.db.QueryRowContext(ctx, `
+		UPDATE webhooks
+		SET url = $1, secret = $2, events = $3::text[], active = $4, updated_at = now()
+		WHERE id = $5
+		RETURNING created_at, updated_at;
+	`, w.URL, w.Secret, w.Events, w.Active, w.ID).Scan(&w.CreatedAt, &w.UpdatedAt)
+}
+
+func (r *WebhookRepo) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Enqueue adds a pending delivery of an event for every active webhook of
+// the user subscribed to its type and returns how many it added.
+func (r *WebhookRepo) Enqueue(ctx context.Context, userID int64, eventID, eventType string, payload []byte) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $2, $3, $4
+		FROM webhooks
+		WHERE user_id = $1 AND active AND $3 = ANY (events);
+	`, userID, eventID, eventType, string(payload))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Due claims up to limit pending deliveries of active webhooks whose time
+// has come. A claimed delivery is not due again for lease, so concurrent
+// senders skip it, and a sender that dies leaves it to be retried.
+func (r *WebhookRepo) Due(ctx context.Context, limit int, lease time.Duration) ([]DueDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = now() + $2 * interval '1 second'
+		FROM webhooks w
+		WHERE w.id = d.webhook_id
+		  AND d.id IN (
+			SELECT q.id
+			FROM webhook_deliveries q
+			JOIN webhooks h ON h.id = q.webhook_id AND h.active
+			WHERE q.status = 'pending' AND q.next_attempt_at <= now()
+			ORDER BY q.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF q SKIP LOCKED
+		  )
+		RETURNING `+deliveryColumns+`, w.url, w.secret;
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []DueDelivery
+	for rows.Next() {
+		var d DueDelivery
+		if err := scanDelivery(rows, &d.WebhookDelivery, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// Finish logs an attempt and stores the outcome of the delivery it was
+// made for: its status, attempt count and next attempt time.
+func (r *WebhookRepo) Finish(ctx context.Context, d entity.WebhookDelivery, a entity.WebhookAttempt) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO webhook_attempts (delivery_id, attempt, response_code, error, duration_ms, attempted_at)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`, d.ID, a.Attempt, a.ResponseCode, a.Error, a.Duration.Milliseconds(), a.AttemptedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, next_attempt_at = $3, response_code = $4, last_error = $5, updated_at = now()
+		WHERE id = $6;
+	`, d.Status, d.Attempts, d.NextAttemptAt, d.ResponseCode, d.LastError, d.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListDeliveries returns the newest deliveries of a webhook first.
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]entity.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries d
+		WHERE d.webhook_id = $1
+		ORDER BY d.id DESC
+		LIMIT $2;
+	`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []entity.WebhookDelivery
+	for rows.Next() {
+		var d entity.WebhookDelivery
+		if err := scanDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+func (r *WebhookRepo) GetDelivery(ctx context.Context, id int64) (entity.WebhookDelivery, error) {
+	var d entity.WebhookDelivery
+	err := scanDelivery(r.db.QueryRowContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries d WHERE d.id = $1;`, id), &d)
+	if err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+	return d, nil
+}
+
+func (r *WebhookRepo) ListAttempts(ctx context.Context, deliveryID int64) ([]entity.WebhookAttempt, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT attempt, response_code, error, duration_ms, attempted_at
+		FROM webhook_attempts
+		WHERE delivery_id = $1
+		ORDER BY attempt;
+	`, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []entity.WebhookAttempt
+	for rows.Next() {
+		a := entity.WebhookAttempt{DeliveryID: deliveryID}
+		var code sql.NullInt64
+		var ms int64
+		if err := rows.Scan(&a.Attempt, &code, &a.Error, &ms, &a.AttemptedAt); err != nil {
+			return nil, err
+		}
+		if code.Valid {
+			c := int(code.Int64)
+			a.ResponseCode = &c
+		}
+		a.Duration = time.Duration(ms) * time.Millisecond
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+// Redeliver queues a fresh copy of a delivery, with the same event id and
+// payload, to be sent right away.
+func (r *WebhookRepo) Redeliver(ctx context.Context, id int64) (entity.WebhookDelivery, error) {
+	var d entity.WebhookDelivery
+	err := scanDelivery(r.db.QueryRowContext(ctx, `
+		INSERT INTO webhook_deliveries AS d (webhook_id, event_id, event_type, payload, redelivery_of)
+		SELECT webhook_id, event_id, event_type, payload, id
+		FROM webhook_deliveries
+		WHERE id = $1
+		RETURNING `+deliveryColumns+`;
+	`, id), &d)
+	if err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+	return d, nil
+}
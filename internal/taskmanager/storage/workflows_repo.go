@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/HDBOOMONE12/TaskManager/internal/taskmanager/entity"
+)
+
+var (
+	ErrWorkflowInUse = errors.New("workflow is used by tasks")
+	ErrStateInUse    = errors.New("workflow state is used by tasks")
+)
+
+type WorkflowRepository interface {
+	Create(ctx context.Context, wf *entity.Workflow) error
+	GetByID(ctx context.Context, id int64) (entity.Workflow, error)
+	GetByUserID(ctx context.Context, userID int64) ([]entity.Workflow, error)
+	Update(ctx context.Context, wf *entity.Workflow) error
+	Delete(ctx context.Context, id int64) error
+}
+
+type WorkflowRepo struct {
+	db *sql.DB
+}
+
+func NewWorkflowRepo(db *sql.DB) *WorkflowRepo {
+	return &WorkflowRepo{db: db}
+}
+
+func (r *WorkflowRepo) Create(ctx context.Context, wf *entity.Workflow) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO workflows (user_id, name, initial_state) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at",
+		wf.UserID, wf.Name, wf.InitialState,
+	).Scan(&wf.ID, &wf.CreatedAt, &wf.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if err := insertWorkflowGraph(ctx, tx, wf); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *WorkflowRepo) GetByID(ctx context.Context, id int64) (entity.Workflow, error) {
+	return getWorkflow(ctx, r.db, id)
+}
+
+func (r *WorkflowRepo) GetByUserID(ctx context.Context, userID int64) ([]entity.Workflow, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id FROM workflows WHERE user_id = $1 ORDER BY name",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	list := make([]entity.Workflow, 0, len(ids))
+	for _, id := range ids {
+		wf, err := getWorkflow(ctx, r.db, id)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, wf)
+	}
+	return list, nil
+}
+
+// Update replaces the name, initial state, states and transitions of a
+// workflow. A state that some task of the workflow is still in cannot be
+// dropped.
+func (r *WorkflowRepo) Update(ctx context.Context, wf *entity.Workflow) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		"UPDATE workflows SET name = $1, initial_state = $2, updated_at = now() WHERE id = $3 RETURNING created_at, updated_at",
+		wf.Name, wf.InitialState, wf.ID,
+	).Scan(&wf.CreatedAt, &wf.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+
+	var inUse bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM tasks WHERE workflow_id = $1 AND NOT (status = ANY($2)))",
+		wf.ID, wf.States,
+	).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrStateInUse
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM workflow_states WHERE workflow_id = $1", wf.ID); err != nil {
+		return err
+	}
+	if err := insertWorkflowGraph(ctx, tx, wf); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *WorkflowRepo) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM workflows WHERE id = $1", id)
+	if isForeignKeyViolation(err) {
+		return ErrWorkflowInUse
+	}
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func insertWorkflowGraph(ctx context.Context, q dbtx, wf *entity.Workflow) error {
+	for i, name := range wf.States {
+		_, err := q.ExecContext(ctx,
+			"INSERT INTO workflow_states (workflow_id, name, position) VALUES ($1, $2, $3)",
+			wf.ID, name, i,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, tr := range wf.Transitions {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO workflow_transitions (workflow_id, from_state, to_state, requires_description, requires_assignee)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			wf.ID, tr.From, tr.To, tr.RequiresDescription, tr.RequiresAssignee,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getWorkflow(ctx context.Context, q dbtx, id int64) (entity.Workflow, error) {
+	var wf entity.Workflow
+	err := q.QueryRowContext(ctx,
+		"SELECT id, user_id, name, initial_state, created_at, updated_at FROM workflows WHERE id = $1",
+		id,
+	).Scan(&wf.ID, &wf.UserID, &wf.Name, &wf.InitialState, &wf.CreatedAt, &wf.UpdatedAt)
+	if err != nil {
+		return entity.Workflow{}, err
+	}
+
+	rows, err := q.QueryContext(ctx,
+		"SELECT name FROM workflow_states WHERE workflow_id = $1 ORDER BY position",
+		id,
+	)
+	if err != nil {
+		return entity.Workflow{}, err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return entity.Workflow{}, err
+		}
+		wf.States = append(wf.States, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return entity.Workflow{}, err
+	}
+
+	rows, err = q.QueryContext(ctx, `
+		SELECT t.from_state, t.to_state, t.requires_description, t.requires_assignee
+		FROM workflow_transitions t
+		JOIN workflow_states f ON f.workflow_id = t.workflow_id AND f.name = t.from_state
+		JOIN workflow_states s ON s.workflow_id = t.workflow_id AND s.name = t.to_state
+		WHERE t.workflow_id = $1
+		ORDER BY f.position, s.position
+	`, id)
+	if err != nil {
+		return entity.Workflow{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tr entity.WorkflowTransition
+		if err := rows.Scan(&tr.From, &tr.To, &tr.RequiresDescription, &tr.RequiresAssignee); err != nil {
+			return entity.Workflow{}, err
+		}
+		wf.Transitions = append(wf.Transitions, tr)
+	}
+	if err := rows.Err(); err != nil {
+		return entity.Workflow{}, err
+	}
+	return wf, nil
+}
+
+// GetWorkflow loads the workflow a task follows.
+func (r *TaskRepo) GetWorkflow(ctx context.Context, id int64) (entity.Workflow, error) {
+	return getWorkflow(ctx, r.q(), id)
+}